@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultProfileName is used when neither --profile nor default_profile is given
+const DefaultProfileName = "default"
+
+// Profile holds the connection settings of a named profile
+type Profile struct {
+	// AWSProfile is the profile name in the aws shared config and credentials files
+	AWSProfile   string `toml:"aws_profile"`
+	Region       string `toml:"region"`
+	Endpoint     string `toml:"endpoint"`
+	RoleARN      string `toml:"role_arn"`
+	ExternalID   string `toml:"external_id"`
+	MFASerial    string `toml:"mfa_serial"`
+	TablePrefix  string `toml:"table_prefix"`
+	OutputFormat string `toml:"output_format"`
+	ReadOnly     bool   `toml:"read_only"`
+}
+
+// REPL holds the default options of the prompt
+type REPL struct {
+	// Prefix is the prompt prefix, ">>> " by default
+	Prefix string `toml:"prefix"`
+	// DefaultLimit is used by SELECT without a LIMIT clause, 1 by default
+	DefaultLimit int64 `toml:"default_limit"`
+	// WriteConcurrency is how many items a multi-item UPDATE or DELETE writes at the same time, 8 by default
+	WriteConcurrency int `toml:"write_concurrency"`
+	// SortLimit is the most items ORDER BY sorts client-side, 10000 by default
+	SortLimit int64 `toml:"sort_limit"`
+	// ScanWorkers is how many segments of a parallel Scan are read at the same time, 8 by default
+	ScanWorkers int `toml:"scan_workers"`
+	// ParallelScanItems is the item count above which a full Scan is split into segments without PARALLEL, 100000 by default
+	ParallelScanItems int64 `toml:"parallel_scan_items"`
+	// SubqueryLimit is the most rows a subquery of WHERE returns, 1000 by default
+	SubqueryLimit int64 `toml:"subquery_limit"`
+	// Journal records the items written by UPDATE, DELETE and INSERT for UNDO, on by default
+	Journal *bool `toml:"journal"`
+}
+
+// Config is the content of the config file
+type Config struct {
+	DefaultProfile string             `toml:"default_profile"`
+	REPL           REPL               `toml:"repl"`
+	Profiles       map[string]Profile `toml:"profiles"`
+}
+
+// DefaultPath returns ~/.config/dynamo.cli/config.toml
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "dynamo.cli", "config.toml")
+}
+
+// DefaultCredentialsCacheDir returns the directory temporary credentials are cached in
+func DefaultCredentialsCacheDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cacheDir, "dynamo.cli", "credentials")
+}
+
+// DefaultJournalPath returns the journal file UNDO restores items from
+func DefaultJournalPath() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cacheDir, "dynamo.cli", "journal.jsonl")
+}
+
+// Load reads the config file at path, a missing file gives an empty config
+func Load(path string) (*Config, error) {
+	conf := &Config{Profiles: map[string]Profile{}}
+	if path == "" {
+		return conf, nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return conf, nil
+	}
+	if _, err := toml.DecodeFile(path, conf); err != nil {
+		return nil, fmt.Errorf("Unable to read config file %s: %s", path, err)
+	}
+	if conf.Profiles == nil {
+		conf.Profiles = map[string]Profile{}
+	}
+	return conf, nil
+}
+
+// ResolveProfileName returns the name of the profile to use,
+// an empty name falls back to default_profile and then to the "default" profile if there is one
+func (c *Config) ResolveProfileName(name string) string {
+	if name != "" {
+		return name
+	} else if c.DefaultProfile != "" {
+		return c.DefaultProfile
+	} else if _, ok := c.Profiles[DefaultProfileName]; ok {
+		return DefaultProfileName
+	}
+	return ""
+}
+
+// GetProfile returns the profile with the given name, see ResolveProfileName for empty names.
+// Without any profile to use an empty profile is returned, a profile asked for by name has to exist.
+func (c *Config) GetProfile(name string) (Profile, error) {
+	resolved := c.ResolveProfileName(name)
+	if resolved == "" {
+		return Profile{}, nil
+	}
+	if profile, ok := c.Profiles[resolved]; ok {
+		return profile, nil
+	}
+	return Profile{}, fmt.Errorf("Profile %s not found in config file", resolved)
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testConfig = `
+default_profile = "staging"
+
+[repl]
+prefix = "dynamo> "
+default_limit = 10
+
+[profiles.staging]
+aws_profile = "company-staging"
+region = "ap-southeast-2"
+table_prefix = "staging_"
+
+[profiles.production]
+aws_profile = "company-production"
+region = "us-east-1"
+output_format = "json"
+read_only = true
+`
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynamo.cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.toml")
+	if err := ioutil.WriteFile(path, []byte(testConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    *Config
+		wantErr bool
+	}{
+		{
+			name: "test Load with config file",
+			path: path,
+			want: &Config{
+				DefaultProfile: "staging",
+				REPL:           REPL{Prefix: "dynamo> ", DefaultLimit: 10},
+				Profiles: map[string]Profile{
+					"staging": Profile{
+						AWSProfile:  "company-staging",
+						Region:      "ap-southeast-2",
+						TablePrefix: "staging_",
+					},
+					"production": Profile{
+						AWSProfile:   "company-production",
+						Region:       "us-east-1",
+						OutputFormat: "json",
+						ReadOnly:     true,
+					},
+				},
+			},
+		},
+		{
+			name: "test Load with missing config file",
+			path: filepath.Join(dir, "missing.toml"),
+			want: &Config{Profiles: map[string]Profile{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_ResolveProfileName(t *testing.T) {
+	tests := []struct {
+		name        string
+		conf        Config
+		profileName string
+		want        string
+	}{
+		{
+			name:        "test ResolveProfileName by name",
+			conf:        Config{DefaultProfile: "staging"},
+			profileName: "production",
+			want:        "production",
+		},
+		{
+			name: "test ResolveProfileName with default_profile",
+			conf: Config{DefaultProfile: "staging"},
+			want: "staging",
+		},
+		{
+			name: "test ResolveProfileName falls back to default",
+			conf: Config{Profiles: map[string]Profile{"default": Profile{}}},
+			want: "default",
+		},
+		{
+			name: "test ResolveProfileName without any profile",
+			conf: Config{Profiles: map[string]Profile{}},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.conf.ResolveProfileName(tt.profileName); got != tt.want {
+				t.Errorf("ResolveProfileName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_GetProfile(t *testing.T) {
+	profiles := map[string]Profile{
+		"default":    Profile{Region: "us-west-2"},
+		"staging":    Profile{Region: "ap-southeast-2"},
+		"production": Profile{Region: "us-east-1", ReadOnly: true},
+	}
+	tests := []struct {
+		name        string
+		conf        Config
+		profileName string
+		want        Profile
+		wantErr     bool
+	}{
+		{
+			name:        "test GetProfile by name",
+			conf:        Config{Profiles: profiles},
+			profileName: "production",
+			want:        Profile{Region: "us-east-1", ReadOnly: true},
+		},
+		{
+			name: "test GetProfile with default_profile",
+			conf: Config{DefaultProfile: "staging", Profiles: profiles},
+			want: Profile{Region: "ap-southeast-2"},
+		},
+		{
+			name: "test GetProfile falls back to default",
+			conf: Config{Profiles: profiles},
+			want: Profile{Region: "us-west-2"},
+		},
+		{
+			name: "test GetProfile without any profile",
+			conf: Config{Profiles: map[string]Profile{}},
+			want: Profile{},
+		},
+		{
+			name:        "test GetProfile with unknown profile",
+			conf:        Config{Profiles: profiles},
+			profileName: "dev",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.conf.GetProfile(tt.profileName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetProfile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetProfile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/briandowns/spinner"
+)
+
+// stdin is shared by all confirmations, the prompt does not read stdin while a command runs
+var stdin = bufio.NewReader(os.Stdin)
+
+// activeSpinner is the spinner of the running command, it's paused while asking for confirmation
+var activeSpinner *spinner.Spinner
+
+// spinnerMutex guards the spinner state below, progress is sent by the workers of a command at the same time.
+// This version of spinner reads Suffix without a lock and a stopped one may keep spinning once started again,
+// so the suffix is never changed on a running spinner and a new spinner is started instead
+var spinnerMutex = sync.Mutex{}
+
+// spinnerSuffix is the progress shown next to the spinner
+var spinnerSuffix = ""
+
+// spinnerPaused counts the questions and warnings printed while the spinner is stopped
+var spinnerPaused = 0
+
+// newSpinner starts a spinner showing suffix
+func newSpinner(suffix string) *spinner.Spinner {
+	spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+	spin.Suffix = suffix
+	spin.Start()
+	return spin
+}
+
+// startSpinner shows a spinner until stopSpinner is called
+func startSpinner() {
+	spinnerMutex.Lock()
+	defer spinnerMutex.Unlock()
+	spinnerSuffix, spinnerPaused = "", 0
+	activeSpinner = newSpinner(spinnerSuffix)
+}
+
+// stopSpinner removes the spinner, progress sent afterwards by a canceled command is dropped
+func stopSpinner() {
+	spinnerMutex.Lock()
+	defer spinnerMutex.Unlock()
+	if activeSpinner != nil {
+		activeSpinner.Stop()
+		activeSpinner = nil
+	}
+}
+
+// pauseSpinner stops the spinner so that something can be printed, it returns the function to resume it
+func pauseSpinner() func() {
+	spinnerMutex.Lock()
+	defer spinnerMutex.Unlock()
+	paused := activeSpinner
+	if paused != nil {
+		spinnerPaused++
+		paused.Stop()
+	}
+	return func() {
+		spinnerMutex.Lock()
+		defer spinnerMutex.Unlock()
+		// the spinner is left stopped if the command ended meanwhile
+		if paused != nil && activeSpinner == paused {
+			spinnerPaused--
+			if spinnerPaused == 0 {
+				activeSpinner = newSpinner(spinnerSuffix)
+			}
+		}
+	}
+}
+
+// progress shows a message next to the spinner
+func progress(message string) {
+	spinnerMutex.Lock()
+	defer spinnerMutex.Unlock()
+	if activeSpinner == nil {
+		return
+	}
+	spinnerSuffix = " " + message
+	if spinnerPaused == 0 {
+		activeSpinner.Stop()
+		activeSpinner = newSpinner(spinnerSuffix)
+	}
+}
+
+// ask prints the question and returns the answer read from stdin
+func ask(question string) string {
+	defer pauseSpinner()()
+	fmt.Print(question)
+	answer, _ := stdin.ReadString('\n')
+	return strings.TrimSpace(answer)
+}
+
+// warn prints a warning above the spinner of the running command
+func warn(message string) {
+	defer pauseSpinner()()
+	fmt.Println("Warning: " + message)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/FrontMage/dynamo.cli/config"
+	"github.com/FrontMage/dynamo.cli/db"
+	"github.com/FrontMage/dynamo.cli/executors"
+	"github.com/FrontMage/dynamo.cli/journal"
+	"github.com/FrontMage/dynamo.cli/tables"
+	"github.com/FrontMage/dynamo.cli/utils"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// connection describes the connected dynamodb, it's shown in the prompt prefix
+type connection struct {
+	profileName string
+	tablePrefix string
+	readOnly    bool
+	opts        db.SessionOptions
+}
+
+// activeConnection is the connection db.DynamoDB is built from
+var activeConnection = connection{}
+
+// forceReadOnly is set by --read-only, every connection is read-only then
+var forceReadOnly = false
+
+// conf is the loaded config file, \connect looks up profiles in it
+var conf = &config.Config{Profiles: map[string]config.Profile{}}
+
+// label returns something like "staging ap-southeast-2 localhost:8000"
+func (c connection) label() string {
+	labels := []string{}
+	if c.profileName != "" {
+		labels = append(labels, c.profileName)
+	}
+	if db.DynamoDB != nil {
+		labels = append(labels, aws.StringValue(db.DynamoDB.Config.Region))
+	}
+	// make it obvious that we are not talking to the real dynamodb
+	if c.opts.Endpoint != "" {
+		labels = append(labels, endpointLabel(c.opts.Endpoint))
+	}
+	if c.readOnly {
+		labels = append(labels, "read-only")
+	}
+	return strings.Join(labels, " ")
+}
+
+// journalName identifies the connection in the journal, it's the label without read-only along with the aws account
+// and the assumed role, UNDO only restores items written with the same credentials
+func (c connection) journalName(account string) string {
+	c.readOnly = false
+	name := c.label()
+	if account != "" {
+		name += " account " + account
+	}
+	if c.opts.RoleARN != "" {
+		name += " role " + c.opts.RoleARN
+	}
+	return name
+}
+
+// journalPath is the journal file of the config, empty when the journal is turned off
+var journalPath = ""
+
+// useJournal journals the writes made through the connection, a custom endpoint is told by its host,
+// writes to aws are not journaled if the account can't be told as UNDO could restore them to another account
+func useJournal(c connection, client *dynamodb.DynamoDB) {
+	journal.Path, journal.Connection = "", ""
+	if journalPath == "" {
+		return
+	}
+	account := ""
+	if c.opts.Endpoint == "" {
+		var err error
+		if account, err = db.CallerAccount(client); err != nil {
+			warn("Unable to tell the aws account, writes are not journaled: " + err.Error())
+			return
+		}
+	}
+	journal.Path, journal.Connection = journalPath, c.journalName(account)
+}
+
+// endpointLabel returns the host part of a custom endpoint for the prompt prefix
+func endpointLabel(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return endpoint
+}
+
+// livePrefix shows the active connection before the prompt prefix, and whether writes are buffered in a transaction
+func livePrefix() (string, bool) {
+	if executors.InTransaction() {
+		return fmt.Sprintf("[%s transaction] %s", activeConnection.label(), promptPrefix), true
+	}
+	return fmt.Sprintf("[%s] %s", activeConnection.label(), promptPrefix), true
+}
+
+// setOutputFormat checks and sets the output format of query results
+func setOutputFormat(format string) error {
+	if utils.FindIndex(utils.OutputFormats, format) == -1 {
+		return fmt.Errorf("Unknown output format %s, must be one of %s", format, strings.Join(utils.OutputFormats, ", "))
+	}
+	utils.OutputFormat = format
+	return nil
+}
+
+// nextConnection is the connection \connect switches to, a profile in the config file or another region of the active
+// connection, read-only only gets stricter, a session made read-only by \readonly on stays so
+func nextConnection(target string) connection {
+	next := activeConnection
+	if profile, isProfile := conf.Profiles[target]; isProfile {
+		next = connection{
+			profileName: target,
+			tablePrefix: profile.TablePrefix,
+			readOnly:    forceReadOnly || activeConnection.readOnly || profile.ReadOnly,
+			opts: db.SessionOptions{
+				Region:     profile.Region,
+				Profile:    profile.AWSProfile,
+				Endpoint:   profile.Endpoint,
+				RoleARN:    profile.RoleARN,
+				ExternalID: profile.ExternalID,
+				MFASerial:  profile.MFASerial,
+			},
+		}
+	} else {
+		next.opts.Region = target
+	}
+	return next
+}
+
+// connect switches the connected dynamodb to a profile in the config file,
+// or to another region of the active connection if target is not a profile name
+func connect(target string) (string, error) {
+	// the buffered writes would be committed to the other connection
+	if executors.InTransaction() {
+		return "", errors.New("In a transaction, COMMIT or ROLLBACK before switching connections")
+	}
+	next := nextConnection(target)
+	profile, isProfile := conf.Profiles[target]
+
+	client, err := db.NewDynamoSession(next.opts)
+	if err != nil {
+		return "", err
+	}
+	// make sure the new connection works before dropping the old one
+	if _, err := client.ListTables(&dynamodb.ListTablesInput{Limit: aws.Int64(1)}); err != nil {
+		return "", err
+	}
+	if isProfile && profile.OutputFormat != "" {
+		if err := setOutputFormat(profile.OutputFormat); err != nil {
+			return "", err
+		}
+	}
+
+	db.DynamoDB = client
+	activeConnection = next
+	useJournal(activeConnection, client)
+	tables.ClearCache()
+	executors.ClearCursor()
+	if err := loadTableNames(activeConnection.tablePrefix); err != nil {
+		warn("Unable to list tables: " + err.Error())
+	}
+	return fmt.Sprintf("Connected to %s", activeConnection.label()), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/config"
+	"github.com/FrontMage/dynamo.cli/db"
+)
+
+func Test_nextConnection(t *testing.T) {
+	defer func(active connection, c *config.Config, force bool) {
+		activeConnection, conf, forceReadOnly = active, c, force
+	}(activeConnection, conf, forceReadOnly)
+	conf = &config.Config{Profiles: map[string]config.Profile{
+		"staging":    {Region: "ap-southeast-2", TablePrefix: "staging_"},
+		"production": {Region: "us-east-1", ReadOnly: true},
+	}}
+	tests := []struct {
+		name         string
+		active       connection
+		force        bool
+		target       string
+		wantProfile  string
+		wantRegion   string
+		wantReadOnly bool
+	}{
+		{
+			name:        "test nextConnection to a profile",
+			active:      connection{profileName: "production", readOnly: false, opts: db.SessionOptions{Region: "us-east-1"}},
+			target:      "staging",
+			wantProfile: "staging",
+			wantRegion:  "ap-southeast-2",
+		},
+		{
+			name:         "test nextConnection to a read-only profile",
+			active:       connection{profileName: "staging", opts: db.SessionOptions{Region: "ap-southeast-2"}},
+			target:       "production",
+			wantProfile:  "production",
+			wantRegion:   "us-east-1",
+			wantReadOnly: true,
+		},
+		{
+			name:         "test nextConnection keeps \\readonly on",
+			active:       connection{profileName: "production", readOnly: true, opts: db.SessionOptions{Region: "us-east-1"}},
+			target:       "staging",
+			wantProfile:  "staging",
+			wantRegion:   "ap-southeast-2",
+			wantReadOnly: true,
+		},
+		{
+			name:         "test nextConnection with --read-only",
+			active:       connection{profileName: "production", opts: db.SessionOptions{Region: "us-east-1"}},
+			force:        true,
+			target:       "staging",
+			wantProfile:  "staging",
+			wantRegion:   "ap-southeast-2",
+			wantReadOnly: true,
+		},
+		{
+			name:         "test nextConnection to another region keeps \\readonly on",
+			active:       connection{profileName: "staging", readOnly: true, opts: db.SessionOptions{Region: "ap-southeast-2"}},
+			target:       "eu-west-1",
+			wantProfile:  "staging",
+			wantRegion:   "eu-west-1",
+			wantReadOnly: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activeConnection, forceReadOnly = tt.active, tt.force
+			got := nextConnection(tt.target)
+			if got.profileName != tt.wantProfile || got.opts.Region != tt.wantRegion || got.readOnly != tt.wantReadOnly {
+				t.Errorf("nextConnection() = %v %v read-only %v, want %v %v read-only %v",
+					got.profileName, got.opts.Region, got.readOnly, tt.wantProfile, tt.wantRegion, tt.wantReadOnly)
+			}
+		})
+	}
+}
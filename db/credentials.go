@@ -0,0 +1,178 @@
+package db
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	// stsProviderName is the ProviderName of credentials retrieved by stsProvider
+	stsProviderName = "DynamoCliSTSProvider"
+	// mfaSessionDuration is how long an MFA session lasts, asking for a token code twice a day is enough
+	mfaSessionDuration = 12 * time.Hour
+	// roleSessionDuration is how long assumed role credentials last, they are refreshed silently
+	roleSessionDuration = time.Hour
+	// expiryWindow refreshes credentials a bit before they actually expire
+	expiryWindow = 5 * time.Minute
+)
+
+// CredentialsCacheDir is where temporary credentials are cached between runs,
+// temporary credentials are not cached on disk if it's empty
+var CredentialsCacheDir = ""
+
+// TokenProvider asks for the current token code of the MFA device,
+// it's asked again when the MFA session expires, possibly while a statement runs
+var TokenProvider = func() (string, error) {
+	var code string
+	fmt.Fprintf(os.Stderr, "MFA token code: ")
+	_, err := fmt.Scanln(&code)
+	return code, err
+}
+
+// cachedCredentials is the content of a credentials cache file
+type cachedCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// stsProvider retrieves temporary credentials from sts and caches them on disk,
+// credentials.Credentials calls Retrieve again once they are expired, so long sessions refresh transparently
+type stsProvider struct {
+	credentials.Expiry
+	cacheKey string
+	retrieve func() (*sts.Credentials, error)
+}
+
+func (p *stsProvider) cacheFile() string {
+	if CredentialsCacheDir == "" {
+		return ""
+	}
+	sum := sha1.Sum([]byte(p.cacheKey))
+	return filepath.Join(CredentialsCacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (p *stsProvider) readCache() (*cachedCredentials, error) {
+	path := p.cacheFile()
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cached := &cachedCredentials{}
+	if err := json.Unmarshal(data, cached); err != nil {
+		return nil, err
+	}
+	return cached, nil
+}
+
+func (p *stsProvider) writeCache(cached *cachedCredentials) error {
+	path := p.cacheFile()
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Retrieve returns cached credentials if they are still valid, otherwise asks sts for new ones
+func (p *stsProvider) Retrieve() (credentials.Value, error) {
+	cached, err := p.readCache()
+	if err != nil || time.Now().Add(expiryWindow).After(cached.Expiration) {
+		creds, err := p.retrieve()
+		if err != nil {
+			return credentials.Value{ProviderName: stsProviderName}, err
+		}
+		cached = &cachedCredentials{
+			AccessKeyID:     aws.StringValue(creds.AccessKeyId),
+			SecretAccessKey: aws.StringValue(creds.SecretAccessKey),
+			SessionToken:    aws.StringValue(creds.SessionToken),
+			Expiration:      aws.TimeValue(creds.Expiration),
+		}
+		if err := p.writeCache(cached); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to cache credentials: %s\n", err)
+		}
+	}
+	p.SetExpiration(cached.Expiration, expiryWindow)
+	return credentials.Value{
+		AccessKeyID:     cached.AccessKeyID,
+		SecretAccessKey: cached.SecretAccessKey,
+		SessionToken:    cached.SessionToken,
+		ProviderName:    stsProviderName,
+	}, nil
+}
+
+// sourceIdentity tells which credentials sts calls are made with, part of the cache key
+func sourceIdentity(opts SessionOptions) string {
+	if opts.AccessKeyID != "" {
+		return opts.AccessKeyID
+	}
+	return "profile:" + opts.Profile
+}
+
+// mfaSessionCredentials returns credentials of an MFA session got by sts GetSessionToken
+func mfaSessionCredentials(sess *session.Session, opts SessionOptions) *credentials.Credentials {
+	stsClient := sts.New(sess)
+	return credentials.NewCredentials(&stsProvider{
+		cacheKey: strings.Join([]string{"session", sourceIdentity(opts), opts.MFASerial}, "|"),
+		retrieve: func() (*sts.Credentials, error) {
+			code, err := TokenProvider()
+			if err != nil {
+				return nil, err
+			}
+			result, err := stsClient.GetSessionToken(&sts.GetSessionTokenInput{
+				DurationSeconds: aws.Int64(int64(mfaSessionDuration / time.Second)),
+				SerialNumber:    aws.String(opts.MFASerial),
+				TokenCode:       aws.String(code),
+			})
+			if err != nil {
+				return nil, err
+			}
+			return result.Credentials, nil
+		},
+	})
+}
+
+// assumeRoleCredentials returns credentials of opts.RoleARN got by sts AssumeRole
+func assumeRoleCredentials(sess *session.Session, opts SessionOptions) *credentials.Credentials {
+	stsClient := sts.New(sess)
+	return credentials.NewCredentials(&stsProvider{
+		cacheKey: strings.Join([]string{"role", sourceIdentity(opts), opts.MFASerial, opts.RoleARN, opts.ExternalID}, "|"),
+		retrieve: func() (*sts.Credentials, error) {
+			input := &sts.AssumeRoleInput{
+				DurationSeconds: aws.Int64(int64(roleSessionDuration / time.Second)),
+				RoleArn:         aws.String(opts.RoleARN),
+				RoleSessionName: aws.String(fmt.Sprintf("dynamo.cli-%d", time.Now().Unix())),
+			}
+			if opts.ExternalID != "" {
+				input.ExternalId = aws.String(opts.ExternalID)
+			}
+			result, err := stsClient.AssumeRole(input)
+			if err != nil {
+				return nil, err
+			}
+			return result.Credentials, nil
+		},
+	})
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+func Test_stsProvider_Retrieve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynamo.cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	CredentialsCacheDir = dir
+	defer func() { CredentialsCacheDir = "" }()
+
+	tests := []struct {
+		name          string
+		cacheKey      string
+		expiration    time.Duration
+		wantRetrieves int
+	}{
+		{
+			name:          "test stsProvider Retrieve with valid credentials",
+			cacheKey:      "valid",
+			expiration:    time.Hour,
+			wantRetrieves: 1,
+		},
+		{
+			name:          "test stsProvider Retrieve with expiring credentials",
+			cacheKey:      "expiring",
+			expiration:    time.Minute,
+			wantRetrieves: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retrieves := 0
+			retrieve := func() (*sts.Credentials, error) {
+				retrieves++
+				return &sts.Credentials{
+					AccessKeyId:     aws.String("ASIAEXAMPLE"),
+					SecretAccessKey: aws.String("secret"),
+					SessionToken:    aws.String("token"),
+					Expiration:      aws.Time(time.Now().Add(tt.expiration)),
+				}, nil
+			}
+			// the second provider acts like a new run of dynamo.cli, it should use the cache file
+			for i := 0; i < 2; i++ {
+				provider := &stsProvider{cacheKey: tt.cacheKey, retrieve: retrieve}
+				value, err := provider.Retrieve()
+				if err != nil {
+					t.Errorf("Retrieve() error = %v", err)
+					return
+				}
+				if value.AccessKeyID != "ASIAEXAMPLE" || value.SessionToken != "token" {
+					t.Errorf("Retrieve() = %+v", value)
+				}
+			}
+			if retrieves != tt.wantRetrieves {
+				t.Errorf("Retrieve() called sts %d times, want %d", retrieves, tt.wantRetrieves)
+			}
+		})
+	}
+}
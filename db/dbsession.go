@@ -5,17 +5,48 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/sts"
 )
 
 // DynamoDB is a connected session of dynamoDB
 var DynamoDB *dynamodb.DynamoDB
 
-// GetDynamoSession returns a new dynamodb session
-func GetDynamoSession(accessKeyID, secretAccessKey, region string) (*dynamodb.DynamoDB, error) {
+// DefaultLocalRegion is used when connecting to a custom endpoint without region,
+// DynamoDB Local and LocalStack accept any region
+const DefaultLocalRegion = "us-east-1"
+
+// SessionOptions holds everything needed to connect to dynamoDB
+type SessionOptions struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	Region          string
+	// Profile is the profile name in the aws shared config and credentials files,
+	// role_arn, mfa_serial, sso and credential_process settings of the profile are honoured
+	Profile  string
+	Endpoint string
+	// RoleARN is assumed with the credentials above, ExternalID is passed along when not empty
+	RoleARN    string
+	ExternalID string
+	// MFASerial asks for a token code and starts an MFA session, the role is assumed within the MFA session
+	MFASerial string
+}
+
+// GetDynamoSession returns a new dynamodb session and makes it the connected one
+func GetDynamoSession(opts SessionOptions) (*dynamodb.DynamoDB, error) {
+	client, err := NewDynamoSession(opts)
+	if err != nil {
+		return nil, err
+	}
+	DynamoDB = client
+	return DynamoDB, nil
+}
+
+// NewDynamoSession returns a new dynamodb session without touching the connected one
+func NewDynamoSession(opts SessionOptions) (*dynamodb.DynamoDB, error) {
 	sessionConfig := session.Options{}
-	if accessKeyID != "" || secretAccessKey != "" {
+	if opts.AccessKeyID != "" || opts.SecretAccessKey != "" {
 		token := ""
-		creds := credentials.NewStaticCredentials(accessKeyID, secretAccessKey, token)
+		creds := credentials.NewStaticCredentials(opts.AccessKeyID, opts.SecretAccessKey, token)
 		_, err := creds.Get()
 		if err != nil {
 			return nil, err
@@ -23,25 +54,68 @@ func GetDynamoSession(accessKeyID, secretAccessKey, region string) (*dynamodb.Dy
 		sessionConfig.Config.Credentials = creds
 	}
 
-	if region != "" {
-		sessionConfig.Config.Region = &region
+	if opts.Profile != "" {
+		sessionConfig.Profile = opts.Profile
+		sessionConfig.SharedConfigState = session.SharedConfigEnable
+	}
+	// used by profiles with role_arn and mfa_serial in the aws shared config
+	sessionConfig.AssumeRoleTokenProvider = TokenProvider
+
+	if opts.Region != "" {
+		sessionConfig.Config.Region = &opts.Region
+	} else if opts.Endpoint != "" {
+		// a local endpoint does not care about region, no need to read it from shared config
+		sessionConfig.Config.Region = aws.String(DefaultLocalRegion)
 	} else {
 		sessionConfig.SharedConfigState = session.SharedConfigEnable
 	}
 
-	session, err := session.NewSessionWithOptions(sessionConfig)
+	sess, err := session.NewSessionWithOptions(sessionConfig)
 	if err != nil {
 		return nil, err
 	}
-	DynamoDB = dynamodb.New(session)
-	return DynamoDB, nil
+	if opts.MFASerial != "" {
+		sess = sess.Copy(&aws.Config{Credentials: mfaSessionCredentials(sess, opts)})
+	}
+	if opts.RoleARN != "" {
+		sess = sess.Copy(&aws.Config{Credentials: assumeRoleCredentials(sess, opts)})
+	}
+	// credentials which may ask for an MFA token code are retrieved now rather than in the middle of the first request
+	if opts.MFASerial != "" || opts.RoleARN != "" || opts.Profile != "" {
+		if _, err := sess.Config.Credentials.Get(); err != nil {
+			return nil, err
+		}
+	}
+
+	// the endpoint only applies to dynamodb, sts requests still go to aws
+	dynamoConfig := &aws.Config{}
+	if opts.Endpoint != "" {
+		dynamoConfig.Endpoint = &opts.Endpoint
+	}
+	return dynamodb.New(sess, dynamoConfig), nil
+}
+
+// CallerAccount returns the aws account the credentials of client belong to, as sts GetCallerIdentity tells
+func CallerAccount(client *dynamodb.DynamoDB) (string, error) {
+	// the endpoint only applies to dynamodb, sts requests still go to aws
+	stsConfig := client.Config.Copy()
+	stsConfig.Endpoint = nil
+	sess, err := session.NewSession(stsConfig)
+	if err != nil {
+		return "", err
+	}
+	result, err := sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(result.Account), nil
 }
 
 // ListTable returns all table names from dynamoDB
 func ListTable(receiver []*string, lastEvaluatedTableName *string) ([]*string, error) {
 	if result, err := DynamoDB.ListTables(&dynamodb.ListTablesInput{
 		ExclusiveStartTableName: lastEvaluatedTableName,
-		Limit: aws.Int64(100),
+		Limit:                   aws.Int64(100),
 	}); err == nil {
 		for _, name := range result.TableNames {
 			receiver = append(receiver, name)
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGetDynamoSession(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         SessionOptions
+		wantRegion   string
+		wantEndpoint string
+	}{
+		{
+			name:         "test GetDynamoSession with endpoint and region",
+			opts:         SessionOptions{Region: "ap-southeast-2", Endpoint: "http://localhost:8000"},
+			wantRegion:   "ap-southeast-2",
+			wantEndpoint: "http://localhost:8000",
+		},
+		{
+			name:         "test GetDynamoSession with endpoint only",
+			opts:         SessionOptions{Endpoint: "http://localhost:4566"},
+			wantRegion:   DefaultLocalRegion,
+			wantEndpoint: "http://localhost:4566",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetDynamoSession(tt.opts)
+			if err != nil {
+				t.Errorf("GetDynamoSession() error = %v", err)
+				return
+			}
+			if *got.Config.Region != tt.wantRegion {
+				t.Errorf("GetDynamoSession() region = %v, want %v", *got.Config.Region, tt.wantRegion)
+			}
+			if got.Endpoint != tt.wantEndpoint {
+				t.Errorf("GetDynamoSession() endpoint = %v, want %v", got.Endpoint, tt.wantEndpoint)
+			}
+		})
+	}
+}
+
+func TestNewDynamoSession_mfa(t *testing.T) {
+	asked := 0
+	defer func(provider func() (string, error)) { TokenProvider = provider }(TokenProvider)
+	TokenProvider = func() (string, error) {
+		asked++
+		return "", errors.New("no code")
+	}
+	// the token code is asked when the session is created, not on the first request
+	_, err := NewDynamoSession(SessionOptions{
+		AccessKeyID:     "AKIAEXAMPLE",
+		SecretAccessKey: "secret",
+		Region:          "ap-southeast-2",
+		MFASerial:       "arn:aws:iam::123456789012:mfa/james",
+	})
+	if err == nil || asked != 1 {
+		t.Errorf("NewDynamoSession() error = %v, asked %d times, want an error after asking once", err, asked)
+	}
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestCallerAccount(t *testing.T) {
+	// a custom CA bundle needs an http.Transport
+	defer func(bundle string) { os.Setenv("AWS_CA_BUNDLE", bundle) }(os.Getenv("AWS_CA_BUNDLE"))
+	os.Unsetenv("AWS_CA_BUNDLE")
+	client, err := NewDynamoSession(SessionOptions{
+		AccessKeyID:     "AKIAEXAMPLE",
+		SecretAccessKey: "secret",
+		Region:          "ap-southeast-2",
+		Endpoint:        "http://localhost:8000",
+	})
+	if err != nil {
+		t.Fatalf("NewDynamoSession() error = %v", err)
+	}
+	host := ""
+	client.Config.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		host = r.URL.Host
+		body := `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult><Account>123456789012</Account></GetCallerIdentityResult>
+</GetCallerIdentityResponse>`
+		return &http.Response{StatusCode: 200, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(body)), Request: r}, nil
+	})}
+	// the endpoint of dynamodb is not where sts is asked
+	account, err := CallerAccount(client)
+	if err != nil || account != "123456789012" || !strings.HasPrefix(host, "sts.") {
+		t.Errorf("CallerAccount() = %v, %v, sent to %v, want 123456789012 from sts", account, err, host)
+	}
+}
@@ -0,0 +1,500 @@
+package executors
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/FrontMage/dynamo.cli/db"
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/FrontMage/dynamo.cli/utils"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// aggregation is what a select statement with aggregates computes,
+// the attributes of rows are the GROUP BY attributes and the aggregates written like COUNT(*)
+// aggregation columns are the attributes of SELECT, in the same form, names are how they are selected, by alias if any
+// aggregation distinct is true if selected rows already seen are dropped
+type aggregation struct {
+	columns    []string
+	names      []string
+	distinct   bool
+	groupBy    []string
+	aggregates []sqlparser.Aggregate
+	having     []sqlparser.Condition
+	orderBy    []sqlparser.OrderBy
+}
+
+// havingOperators are the comparisons HAVING takes
+var havingOperators = []string{"=", "!=", "<", "<=", ">", ">=", sqlparser.OpBetween}
+
+// planAggregation checks that every attribute of SELECT, HAVING and ORDER BY is either in GROUP BY or an aggregate,
+// HAVING and ORDER BY may use the aliases of SELECT
+func planAggregation(stmt sqlparser.SelectStatement) (aggregation, error) {
+	agg := aggregation{groupBy: stmt.GroupBy, distinct: stmt.Distinct}
+	aliases := map[string]string{}
+	if stmt.After != "" {
+		return agg, errors.New("AFTER continues a SELECT of items, aggregates are read at once, use OFFSET to skip groups")
+	} else if stmt.Offset < 0 {
+		return agg, errors.New("OFFSET takes the number of groups to skip, like LIMIT 10 OFFSET 20")
+	}
+	for _, g := range agg.groupBy {
+		if _, err := sqlparser.ParsePath(g); err != nil {
+			return agg, fmt.Errorf("Invalid GROUP BY %s, %v", g, err)
+		}
+	}
+	// column returns how an attribute is named in the rows, adding the aggregates not computed yet
+	column := func(attribute, clause string) (string, error) {
+		if c, ok := aliases[attribute]; ok {
+			return c, nil
+		}
+		if a, ok := sqlparser.ParseAggregate(attribute); ok {
+			if a.Key == "*" && a.Function != sqlparser.AggregateCount {
+				return "", fmt.Errorf("%s takes an attribute, only COUNT takes *", a.Function)
+			} else if _, err := sqlparser.ParsePath(a.Key); err != nil && a.Key != "*" {
+				return "", fmt.Errorf("Invalid %s, %v", a, err)
+			}
+			for _, b := range agg.aggregates {
+				if b == a {
+					return a.String(), nil
+				}
+			}
+			agg.aggregates = append(agg.aggregates, a)
+			return a.String(), nil
+		}
+		if utils.FindIndex(agg.groupBy, attribute) == -1 {
+			return "", fmt.Errorf("%s %s must be in GROUP BY or an aggregate like COUNT(*), SUM, AVG, MIN or MAX", clause, attribute)
+		}
+		return attribute, nil
+	}
+	for _, a := range stmt.AttributesToGet {
+		if a == "*" {
+			return agg, errors.New("SELECT * can't be aggregated, list the GROUP BY attributes and the aggregates")
+		}
+		expression, alias := sqlparser.ParseColumn(a)
+		c, err := column(expression, "SELECT")
+		if err != nil {
+			return agg, err
+		}
+		agg.columns = append(agg.columns, c)
+		if alias == "" {
+			agg.names = append(agg.names, c)
+		} else {
+			agg.names = append(agg.names, alias)
+			aliases[alias] = c
+		}
+	}
+	for _, h := range stmt.Having {
+		if h.Key == "" || h.Function != "" || utils.FindIndex(havingOperators, h.Operator) == -1 {
+			return agg, fmt.Errorf("Invalid HAVING %s, it takes =, !=, <, <=, >, >= and BETWEEN, like HAVING COUNT(*) > 1", h)
+		}
+		c, err := column(h.Key, "HAVING")
+		if err != nil {
+			return agg, err
+		}
+		h.Key = c
+		agg.having = append(agg.having, h)
+	}
+	for _, o := range stmt.OrderBy {
+		c, err := column(o.Key, "ORDER BY")
+		if err != nil {
+			return agg, err
+		}
+		agg.orderBy = append(agg.orderBy, sqlparser.OrderBy{Key: c, Desc: o.Desc})
+	}
+	return agg, nil
+}
+
+// countOnly tells whether DynamoDB can count the items by itself, i.e. the only aggregate is COUNT(*) of all items
+func (agg aggregation) countOnly() bool {
+	return len(agg.groupBy) == 0 && len(agg.aggregates) == 1 && agg.aggregates[0].Key == "*"
+}
+
+// attributesToGet are the attributes of the items read, nil if no attribute is needed
+func (agg aggregation) attributesToGet() []string {
+	attributes := []string{}
+	for _, g := range agg.groupBy {
+		if utils.FindIndex(attributes, g) == -1 {
+			attributes = append(attributes, g)
+		}
+	}
+	for _, a := range agg.aggregates {
+		if a.Key != "*" && utils.FindIndex(attributes, a.Key) == -1 {
+			attributes = append(attributes, a.Key)
+		}
+	}
+	if len(attributes) == 0 {
+		return nil
+	}
+	return attributes
+}
+
+// aggregateState is the running value of an aggregate over the items of a group,
+// count is the items counted by COUNT or the numbers summed by SUM and AVG
+type aggregateState struct {
+	count   int64
+	sum     *big.Rat
+	extreme *dynamodb.AttributeValue
+}
+
+// aggregateGroup holds the GROUP BY values of a group and a state for each aggregate
+type aggregateGroup struct {
+	values map[string]*dynamodb.AttributeValue
+	states []aggregateState
+}
+
+// accumulator aggregates items into groups, in the order the groups are first seen
+type accumulator struct {
+	agg    aggregation
+	groups []*aggregateGroup
+	index  map[string]*aggregateGroup
+}
+
+func newAccumulator(agg aggregation) *accumulator {
+	return &accumulator{agg: agg, index: map[string]*aggregateGroup{}}
+}
+
+// group returns the group of the GROUP BY values, created if it's not there yet
+func (acc *accumulator) group(values map[string]*dynamodb.AttributeValue) *aggregateGroup {
+	id := utils.FormatKey(values)
+	g, ok := acc.index[id]
+	if !ok {
+		g = &aggregateGroup{values: values, states: make([]aggregateState, len(acc.agg.aggregates))}
+		acc.index[id] = g
+		acc.groups = append(acc.groups, g)
+	}
+	return g
+}
+
+// add aggregates an item, NULL and missing values are left out except by COUNT(*),
+// SUM and AVG only take numbers
+func (acc *accumulator) add(item map[string]*dynamodb.AttributeValue) {
+	values := map[string]*dynamodb.AttributeValue{}
+	for _, g := range acc.agg.groupBy {
+		if v := valueAt(item, g); v != nil {
+			values[g] = v
+		}
+	}
+	g := acc.group(values)
+	for idx, a := range acc.agg.aggregates {
+		state := &g.states[idx]
+		if a.Key == "*" {
+			state.count++
+			continue
+		}
+		v := valueAt(item, a.Key)
+		if v == nil || v.NULL != nil {
+			continue
+		}
+		switch a.Function {
+		case sqlparser.AggregateCount:
+			state.count++
+		case sqlparser.AggregateSum, sqlparser.AggregateAvg:
+			if v.N == nil {
+				continue
+			}
+			if n, ok := new(big.Rat).SetString(*v.N); ok {
+				if state.sum == nil {
+					state.sum = new(big.Rat)
+				}
+				state.sum.Add(state.sum, n)
+				state.count++
+			}
+		case sqlparser.AggregateMin:
+			if state.extreme == nil || compareValues(v, state.extreme) < 0 {
+				state.extreme = v
+			}
+		case sqlparser.AggregateMax:
+			if state.extreme == nil || compareValues(v, state.extreme) > 0 {
+				state.extreme = v
+			}
+		}
+	}
+}
+
+// formatNumber returns r as a DynamoDB number, exactly if it has up to 38 decimals, rounded to 16 decimals otherwise
+func formatNumber(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	for decimals := 1; decimals <= 38; decimals++ {
+		s := r.FloatString(decimals)
+		if exact, ok := new(big.Rat).SetString(s); ok && exact.Cmp(r) == 0 {
+			return s
+		}
+	}
+	return strings.TrimRight(r.FloatString(16), "0")
+}
+
+// result is the value of an aggregate, nil for SUM, AVG, MIN and MAX of no values
+func (state aggregateState) result(function string) *dynamodb.AttributeValue {
+	switch function {
+	case sqlparser.AggregateCount:
+		return &dynamodb.AttributeValue{N: aws.String(fmt.Sprint(state.count))}
+	case sqlparser.AggregateSum:
+		if state.sum != nil {
+			return &dynamodb.AttributeValue{N: aws.String(formatNumber(state.sum))}
+		}
+	case sqlparser.AggregateAvg:
+		if state.sum != nil {
+			avg := new(big.Rat).Quo(state.sum, new(big.Rat).SetInt64(state.count))
+			return &dynamodb.AttributeValue{N: aws.String(formatNumber(avg))}
+		}
+	default:
+		return state.extreme
+	}
+	return nil
+}
+
+// rows returns a row for each group, without GROUP BY there is a single row even if there is no item
+func (acc *accumulator) rows() []map[string]*dynamodb.AttributeValue {
+	if len(acc.groups) == 0 && len(acc.agg.groupBy) == 0 {
+		acc.group(map[string]*dynamodb.AttributeValue{})
+	}
+	rows := []map[string]*dynamodb.AttributeValue{}
+	for _, g := range acc.groups {
+		row := map[string]*dynamodb.AttributeValue{}
+		for name, v := range g.values {
+			row[name] = v
+		}
+		for idx, a := range acc.agg.aggregates {
+			if v := g.states[idx].result(a.Function); v != nil {
+				row[a.String()] = v
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// matchHaving tells whether a row matches a condition of HAVING, a missing value never matches
+func matchHaving(row map[string]*dynamodb.AttributeValue, c sqlparser.Condition) bool {
+	v := row[c.Key]
+	if v == nil {
+		return false
+	}
+	compared := compareValues(v, literalValue(c.Value))
+	switch c.Operator {
+	case "=":
+		return compared == 0
+	case "!=":
+		return compared != 0
+	case "<":
+		return compared < 0
+	case "<=":
+		return compared <= 0
+	case ">":
+		return compared > 0
+	case ">=":
+		return compared >= 0
+	case sqlparser.OpBetween:
+		return compared >= 0 && compareValues(v, literalValue(c.High)) <= 0
+	}
+	return false
+}
+
+// rowValue is the value of a row by the name of its column, names of rows are not paths
+func rowValue(row map[string]*dynamodb.AttributeValue, column string) *dynamodb.AttributeValue {
+	return row[column]
+}
+
+// countItems counts the items of a Query or Scan plan with Select COUNT, so that no item is transferred
+func countItems(plan selectPlan) (int64, error) {
+	queryInput, scanInput, err := plan.readInput([]string{"*"})
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	if scanInput != nil && plan.segments > 1 {
+		scanInput.Select, scanInput.Limit = aws.String(dynamodb.SelectCount), nil
+		err := scanSegments(scanInput, plan.segments, func(output *dynamodb.ScanOutput) bool {
+			count += aws.Int64Value(output.Count)
+			return true
+		})
+		return count, err
+	}
+	for {
+		var output struct {
+			count            *int64
+			lastEvaluatedKey map[string]*dynamodb.AttributeValue
+		}
+		if queryInput != nil {
+			queryInput.Select = aws.String(dynamodb.SelectCount)
+			result, err := db.DynamoDB.Query(queryInput)
+			if err != nil {
+				return count, err
+			}
+			output.count, output.lastEvaluatedKey = result.Count, result.LastEvaluatedKey
+			queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+		} else {
+			scanInput.Select, scanInput.Limit = aws.String(dynamodb.SelectCount), nil
+			result, err := db.DynamoDB.Scan(scanInput)
+			if err != nil {
+				return count, err
+			}
+			output.count, output.lastEvaluatedKey = result.Count, result.LastEvaluatedKey
+			scanInput.ExclusiveStartKey = result.LastEvaluatedKey
+		}
+		count += aws.Int64Value(output.count)
+		Progress(fmt.Sprintf("%d items counted", count))
+		if output.lastEvaluatedKey == nil {
+			return count, nil
+		}
+	}
+}
+
+// aggregateItems reads the items matched by a select statement with aggregates, GROUP BY or HAVING,
+// and returns a row for each group, isSingleRow is true without GROUP BY.
+// COUNT(*) alone is counted by DynamoDB, other aggregates are computed client-side over every matching item.
+func aggregateItems(stmt sqlparser.SelectStatement) (rows []map[string]*dynamodb.AttributeValue, isSingleRow bool, err error) {
+	agg, err := planAggregation(stmt)
+	if err != nil {
+		return nil, false, err
+	}
+	read := sqlparser.SelectStatement{AttributesToGet: []string{"*"}, TableName: stmt.TableName, Conditions: stmt.Conditions, Limit: -1, Hints: stmt.Hints}
+	plan, err := planSelect(read)
+	if err != nil {
+		return nil, false, err
+	}
+	acc := newAccumulator(agg)
+	isSingleRow = len(agg.groupBy) == 0
+	switch {
+	case agg.countOnly() && len(plan.clientFilters) == 0 && (plan.method == methodQuery || plan.method == methodScan):
+		count, err := countItems(plan)
+		if err != nil {
+			return nil, isSingleRow, err
+		}
+		acc.group(map[string]*dynamodb.AttributeValue{}).states[0].count = count
+	case plan.method == methodQuery || plan.method == methodScan:
+		// items are aggregated as they come instead of being kept
+		attributesToGet := agg.attributesToGet()
+		if attributesToGet == nil {
+			attributesToGet = plan.keySchemas
+		}
+		for _, c := range plan.clientFilters {
+			for _, a := range conditionAttributes(c) {
+				if utils.FindIndex(attributesToGet, a) == -1 {
+					attributesToGet = append(attributesToGet, a)
+				}
+			}
+		}
+		if len(attributesToGet) == 0 {
+			attributesToGet = []string{"*"}
+		}
+		queryInput, scanInput, err := plan.readInput(attributesToGet)
+		if err != nil {
+			return nil, isSingleRow, err
+		}
+		var aggregated int64
+		match := plan.matcher()
+		keep := func(item map[string]*dynamodb.AttributeValue) bool {
+			if match(item) {
+				acc.add(item)
+				aggregated++
+				Progress(fmt.Sprintf("%d items aggregated", aggregated))
+			}
+			return false
+		}
+		if queryInput != nil {
+			_, err = queryUntilLimit(queryInput, -1, keep, nil)
+		} else {
+			scanInput.Limit = nil
+			_, err = plan.scan(scanInput, -1, keep)
+		}
+		if err != nil {
+			return nil, isSingleRow, err
+		}
+	default:
+		if attributesToGet := agg.attributesToGet(); attributesToGet != nil {
+			read.AttributesToGet = attributesToGet
+		} else if len(plan.keySchemas) > 0 {
+			read.AttributesToGet = plan.keySchemas
+		}
+		items, _, err := selectItems(read)
+		if err != nil {
+			return nil, isSingleRow, err
+		}
+		for _, item := range items {
+			acc.add(item)
+		}
+	}
+
+	return acc.groupRows(stmt), isSingleRow, nil
+}
+
+// groupRows are the rows of the groups matching HAVING, sorted by ORDER BY, as they are selected,
+// after DISTINCT, OFFSET and LIMIT
+func (acc *accumulator) groupRows(stmt sqlparser.SelectStatement) []map[string]*dynamodb.AttributeValue {
+	rows := []map[string]*dynamodb.AttributeValue{}
+	for _, row := range acc.rows() {
+		matched := true
+		for _, h := range acc.agg.having {
+			matched = matched && matchHaving(row, h)
+		}
+		if matched {
+			rows = append(rows, row)
+		}
+	}
+	sortItems(rows, acc.agg.orderBy, rowValue)
+	// HAVING and ORDER BY may use aggregates which are not selected
+	selectedRows := []map[string]*dynamodb.AttributeValue{}
+	seen := map[string]bool{}
+	for _, row := range rows {
+		selected := map[string]*dynamodb.AttributeValue{}
+		for idx, c := range acc.agg.columns {
+			if v, ok := row[c]; ok {
+				selected[acc.agg.names[idx]] = v
+			}
+		}
+		if acc.agg.distinct {
+			id := utils.FormatKey(selected)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+		}
+		selectedRows = append(selectedRows, selected)
+	}
+	selectedRows = skipItems(selectedRows, stmt.Offset)
+	if stmt.Limit >= 0 && int64(len(selectedRows)) > stmt.Limit {
+		selectedRows = selectedRows[:stmt.Limit]
+	}
+	return selectedRows
+}
+
+// explain describes how the items are aggregated for EXPLAIN
+func (agg aggregation) explain(plan selectPlan) string {
+	aggregates := []string{}
+	for _, a := range agg.aggregates {
+		aggregates = append(aggregates, a.String())
+	}
+	lines := []string{}
+	if agg.countOnly() && len(plan.clientFilters) == 0 && (plan.method == methodQuery || plan.method == methodScan) {
+		lines = append(lines, "  aggregate: COUNT(*), counted by DynamoDB with Select COUNT")
+	} else {
+		lines = append(lines, "  aggregate: "+strings.Join(aggregates, ", ")+", client-side over every matching item")
+	}
+	if len(agg.groupBy) > 0 {
+		lines = append(lines, "  group by: "+strings.Join(agg.groupBy, ", "))
+	}
+	if len(agg.having) > 0 {
+		having := []string{}
+		for _, h := range agg.having {
+			having = append(having, h.String())
+		}
+		lines = append(lines, "  having: "+strings.Join(having, " AND "))
+	}
+	if len(agg.orderBy) > 0 {
+		orderBy := []string{}
+		for _, o := range agg.orderBy {
+			orderBy = append(orderBy, o.String())
+		}
+		lines = append(lines, "  order: "+strings.Join(orderBy, ", ")+", sorted client-side")
+	}
+	if agg.distinct {
+		lines = append(lines, "  distinct: rows already seen are dropped client-side")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,146 @@
+package executors
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func Test_planAggregation(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    aggregation
+		wantErr bool
+	}{
+		{
+			name: "test planAggregation adds the aggregates of HAVING and ORDER BY",
+			sql:  "SELECT region, count(*) FROM orders GROUP BY region HAVING SUM(price) > 10 ORDER BY max(ts) DESC END",
+			want: aggregation{
+				columns:    []string{"region", "COUNT(*)"},
+				names:      []string{"region", "COUNT(*)"},
+				groupBy:    []string{"region"},
+				aggregates: []sqlparser.Aggregate{{Function: "COUNT", Key: "*"}, {Function: "SUM", Key: "price"}, {Function: "MAX", Key: "ts"}},
+				having:     []sqlparser.Condition{{Key: "SUM(price)", Operator: ">", Value: "10", NextLogicalOperator: "AND"}},
+				orderBy:    []sqlparser.OrderBy{{Key: "MAX(ts)", Desc: true}},
+			},
+		},
+		{
+			name: "test planAggregation with aliases in HAVING and ORDER BY",
+			sql:  "SELECT DISTINCT region AS r, SUM(price) AS total FROM orders GROUP BY region HAVING total > 10 ORDER BY r END",
+			want: aggregation{
+				columns:    []string{"region", "SUM(price)"},
+				names:      []string{"r", "total"},
+				distinct:   true,
+				groupBy:    []string{"region"},
+				aggregates: []sqlparser.Aggregate{{Function: "SUM", Key: "price"}},
+				having:     []sqlparser.Condition{{Key: "SUM(price)", Operator: ">", Value: "10", NextLogicalOperator: "AND"}},
+				orderBy:    []sqlparser.OrderBy{{Key: "region"}},
+			},
+		},
+		{name: "test planAggregation with an attribute not in GROUP BY", sql: "SELECT region, status, COUNT(*) FROM orders GROUP BY region END", wantErr: true},
+		{name: "test planAggregation with SUM(*)", sql: "SELECT SUM(*) FROM orders END", wantErr: true},
+		{name: "test planAggregation with LIKE in HAVING", sql: "SELECT region FROM orders GROUP BY region HAVING region LIKE 'N%' END", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := planAggregation(sqlparser.ParseSelect(tt.sql))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("planAggregation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planAggregation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_accumulator(t *testing.T) {
+	agg, err := planAggregation(sqlparser.ParseSelect(
+		"SELECT region, COUNT(*), COUNT(note), SUM(price), AVG(price), MIN(price), MAX(note) FROM orders GROUP BY region END"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := func(region, price, note string) map[string]*dynamodb.AttributeValue {
+		item := map[string]*dynamodb.AttributeValue{"region": {S: aws.String(region)}, "price": {N: aws.String(price)}}
+		if note != "" {
+			item["note"] = &dynamodb.AttributeValue{S: aws.String(note)}
+		}
+		return item
+	}
+	acc := newAccumulator(agg)
+	for _, item := range []map[string]*dynamodb.AttributeValue{
+		order("north", "10", "b"), order("south", "2.5", ""), order("north", "5.25", "a"), order("north", "1", ""),
+	} {
+		acc.add(item)
+	}
+	want := []map[string]*dynamodb.AttributeValue{
+		{
+			"region":      {S: aws.String("north")},
+			"COUNT(*)":    {N: aws.String("3")},
+			"COUNT(note)": {N: aws.String("2")},
+			"SUM(price)":  {N: aws.String("16.25")},
+			"AVG(price)":  {N: aws.String("5.4166666666666667")},
+			"MIN(price)":  {N: aws.String("1")},
+			"MAX(note)":   {S: aws.String("b")},
+		},
+		{
+			"region":      {S: aws.String("south")},
+			"COUNT(*)":    {N: aws.String("1")},
+			"COUNT(note)": {N: aws.String("0")},
+			"SUM(price)":  {N: aws.String("2.5")},
+			"AVG(price)":  {N: aws.String("2.5")},
+			"MIN(price)":  {N: aws.String("2.5")},
+		},
+	}
+	if got := acc.rows(); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows() = %v, want %v", got, want)
+	}
+	if got := newAccumulator(agg).rows(); len(got) != 0 {
+		t.Errorf("rows() of GROUP BY without items = %v, want none", got)
+	}
+}
+
+func Test_formatNumber(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *big.Rat
+		want string
+	}{
+		{name: "test formatNumber of an integer", r: big.NewRat(-42, 1), want: "-42"},
+		{name: "test formatNumber of a decimal", r: big.NewRat(1, 8), want: "0.125"},
+		{name: "test formatNumber of a repeating decimal", r: big.NewRat(2, 3), want: "0.6666666666666667"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatNumber(tt.r); got != tt.want {
+				t.Errorf("formatNumber() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_matchHaving(t *testing.T) {
+	row := map[string]*dynamodb.AttributeValue{"COUNT(*)": {N: aws.String("12")}, "region": {S: aws.String("north")}}
+	tests := []struct {
+		name      string
+		condition sqlparser.Condition
+		want      bool
+	}{
+		{name: "test matchHaving compares numbers by value", condition: sqlparser.Condition{Key: "COUNT(*)", Operator: ">", Value: "9"}, want: true},
+		{name: "test matchHaving with BETWEEN", condition: sqlparser.Condition{Key: "COUNT(*)", Operator: sqlparser.OpBetween, Value: "1", High: "10"}, want: false},
+		{name: "test matchHaving with a string", condition: sqlparser.Condition{Key: "region", Operator: "!=", Value: "'south'"}, want: true},
+		{name: "test matchHaving with a missing value", condition: sqlparser.Condition{Key: "SUM(price)", Operator: "!=", Value: "1"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchHaving(row, tt.condition); got != tt.want {
+				t.Errorf("matchHaving() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
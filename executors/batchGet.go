@@ -0,0 +1,170 @@
+package executors
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/FrontMage/dynamo.cli/db"
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/FrontMage/dynamo.cli/utils"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// batchGetSize is the most keys a BatchGetItem takes
+const batchGetSize = 100
+
+// BatchGetRetries is how many times unprocessed keys of a BatchGetItem are retried, with BatchGetBackoff doubled each time
+var BatchGetRetries = 8
+
+// BatchGetBackoff is the first wait before retrying unprocessed keys
+var BatchGetBackoff = 50 * time.Millisecond
+
+// keysFromConditions builds the primary keys from conditions which are only = and IN of key attributes,
+// like user_id IN (1, 2) or (userId, ts) IN ((1, 1001), (2, 1002)), ok is false otherwise
+func keysFromConditions(keySchemas []string, conditions []sqlparser.Condition) ([]map[string]*dynamodb.AttributeValue, bool) {
+	keys := []map[string]*dynamodb.AttributeValue{{}}
+	for _, c := range conditions {
+		attributes, rows := []string{c.Key}, [][]string{{c.Value}}
+		if c.Function != "" {
+			return nil, false
+		} else if c.Operator == sqlparser.OpIn {
+			attributes, rows = c.InList()
+		} else if c.Operator != sqlparser.OpEq {
+			return nil, false
+		}
+		for _, a := range attributes {
+			// an attribute given twice is left to the filter
+			if utils.FindIndex(keySchemas, a) == -1 || keys[0][a] != nil {
+				return nil, false
+			}
+		}
+		product := []map[string]*dynamodb.AttributeValue{}
+		for _, key := range keys {
+			for _, row := range rows {
+				next := map[string]*dynamodb.AttributeValue{}
+				for name, value := range key {
+					next[name] = value
+				}
+				for idx, a := range attributes {
+					next[a] = literalValue(row[idx])
+				}
+				product = append(product, next)
+			}
+		}
+		keys = product
+	}
+	if len(keys[0]) != len(keySchemas) {
+		return nil, false
+	}
+	// BatchGetItem rejects a key given twice
+	unique := []map[string]*dynamodb.AttributeValue{}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if id := utils.FormatKey(key); !seen[id] {
+			seen[id] = true
+			unique = append(unique, key)
+		}
+	}
+	return unique, true
+}
+
+// batchGetItems reads the items of keys with BatchGetItem, batchGetSize keys a request,
+// items are returned in the order of keys and missing ones are left out
+func batchGetItems(tableName string, keySchemas []string, keys []map[string]*dynamodb.AttributeValue, attributesToGet []string, consistent bool) ([]map[string]*dynamodb.AttributeValue, error) {
+	keysAndAttributes := &dynamodb.KeysAndAttributes{}
+	if consistent {
+		keysAndAttributes.ConsistentRead = aws.Bool(true)
+	}
+	if attributesToGet[0] != "*" {
+		// the key attributes tell which item is which, they are dropped afterwards if not asked for
+		attributes := append([]string{}, attributesToGet...)
+		for _, schema := range keySchemas {
+			if utils.FindIndex(attributes, schema) == -1 {
+				attributes = append(attributes, schema)
+			}
+		}
+		expr, err := expression.NewBuilder().WithProjection(buildProjection(attributes)).Build()
+		if err != nil {
+			return nil, err
+		}
+		keysAndAttributes.ExpressionAttributeNames = expressionNames(expr)
+		keysAndAttributes.ProjectionExpression = expr.Projection()
+	}
+
+	found := map[string]map[string]*dynamodb.AttributeValue{}
+	for start := 0; start < len(keys); start += batchGetSize {
+		end := start + batchGetSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if len(keys) > batchGetSize {
+			Progress(fmt.Sprintf("%d/%d keys read", start, len(keys)))
+		}
+		chunk := *keysAndAttributes
+		chunk.Keys = keys[start:end]
+		requestItems := map[string]*dynamodb.KeysAndAttributes{tableName: &chunk}
+		backoff := BatchGetBackoff
+		for retry := 0; ; retry++ {
+			if retry > BatchGetRetries {
+				return nil, fmt.Errorf("%d keys are still unprocessed after %d retries, the table is likely throttled", len(requestItems[tableName].Keys), BatchGetRetries)
+			}
+			if retry > 0 {
+				time.Sleep(backoff)
+				backoff *= 2
+			}
+			result, err := db.DynamoDB.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: requestItems})
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range result.Responses[tableName] {
+				found[utils.FormatKey(keyOf(item, keySchemas))] = item
+			}
+			if unprocessed := result.UnprocessedKeys[tableName]; unprocessed == nil || len(unprocessed.Keys) == 0 {
+				break
+			}
+			requestItems = result.UnprocessedKeys
+		}
+	}
+
+	items := []map[string]*dynamodb.AttributeValue{}
+	for _, key := range keys {
+		if item, ok := found[utils.FormatKey(key)]; ok {
+			if attributesToGet[0] != "*" {
+				item = projectItem(item, attributesToGet)
+			}
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// inExpression is the filter of an IN condition, a tuple IN matches any of its rows,
+// a list too long for DynamoDB is matched client-side and only narrowed down to the items having the attribute
+func inExpression(condition sqlparser.Condition) expression.ConditionBuilder {
+	attributes, rows := condition.InList()
+	if isClientSide(condition) {
+		return pathName(attributes[0]).AttributeExists()
+	}
+	if len(attributes) == 1 {
+		values := []expression.OperandBuilder{}
+		for _, row := range rows {
+			values = append(values, expression.Value(literalValue(row[0])))
+		}
+		return pathName(attributes[0]).In(values[0], values[1:]...)
+	}
+	var filter expression.ConditionBuilder
+	for idx, row := range rows {
+		match := pathName(attributes[0]).Equal(expression.Value(literalValue(row[0])))
+		for i := 1; i < len(attributes); i++ {
+			match = match.And(pathName(attributes[i]).Equal(expression.Value(literalValue(row[i]))))
+		}
+		if idx == 0 {
+			filter = match
+		} else {
+			filter = filter.Or(match)
+		}
+	}
+	return filter
+}
@@ -0,0 +1,100 @@
+package executors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func Test_keysFromConditions(t *testing.T) {
+	n := func(s string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{N: aws.String(s)} }
+	in := func(key, value string) sqlparser.Condition {
+		return sqlparser.Condition{Key: key, Operator: sqlparser.OpIn, Value: value}
+	}
+	tests := []struct {
+		name       string
+		keySchemas []string
+		conditions []sqlparser.Condition
+		want       []map[string]*dynamodb.AttributeValue
+		wantOk     bool
+	}{
+		{
+			name:       "test keysFromConditions with IN",
+			keySchemas: []string{"user_id"},
+			conditions: []sqlparser.Condition{in("user_id", "(1, 2, 1)")},
+			want:       []map[string]*dynamodb.AttributeValue{{"user_id": n("1")}, {"user_id": n("2")}},
+			wantOk:     true,
+		},
+		{
+			name:       "test keysFromConditions with IN and =",
+			keySchemas: []string{"userId", "ts"},
+			conditions: []sqlparser.Condition{{Key: "ts", Operator: sqlparser.OpEq, Value: "1001"}, in("userId", "(1, 2)")},
+			want:       []map[string]*dynamodb.AttributeValue{{"userId": n("1"), "ts": n("1001")}, {"userId": n("2"), "ts": n("1001")}},
+			wantOk:     true,
+		},
+		{
+			name:       "test keysFromConditions with a tuple",
+			keySchemas: []string{"userId", "ts"},
+			conditions: []sqlparser.Condition{in("(userId, ts)", "((1, 1001), (2, 1002))")},
+			want:       []map[string]*dynamodb.AttributeValue{{"userId": n("1"), "ts": n("1001")}, {"userId": n("2"), "ts": n("1002")}},
+			wantOk:     true,
+		},
+		{
+			name:       "test keysFromConditions without the range key",
+			keySchemas: []string{"userId", "ts"},
+			conditions: []sqlparser.Condition{in("userId", "(1, 2)")},
+		},
+		{
+			name:       "test keysFromConditions with a non-key attribute",
+			keySchemas: []string{"user_id"},
+			conditions: []sqlparser.Condition{in("user_id", "(1, 2)"), {Key: "country", Operator: sqlparser.OpEq, Value: "NZ"}},
+		},
+		{
+			name:       "test keysFromConditions with a key given twice",
+			keySchemas: []string{"user_id"},
+			conditions: []sqlparser.Condition{in("user_id", "(1, 2)"), {Key: "user_id", Operator: sqlparser.OpEq, Value: "1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := keysFromConditions(tt.keySchemas, tt.conditions)
+			if ok != tt.wantOk || (ok && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("keysFromConditions() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_inExpression(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition sqlparser.Condition
+		want      string
+	}{
+		{
+			name:      "test inExpression",
+			condition: sqlparser.Condition{Key: "status", Operator: sqlparser.OpIn, Value: "('paid', sent)"},
+			want:      "#0 IN (:0, :1)",
+		},
+		{
+			name:      "test inExpression with a tuple",
+			condition: sqlparser.Condition{Key: "(country, tier)", Operator: sqlparser.OpIn, Value: "((NZ, gold), (AU, gold))"},
+			want:      "((#0 = :0) AND (#1 = :1)) OR ((#0 = :2) AND (#1 = :3))",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := expression.NewBuilder().WithCondition(inExpression(tt.condition)).Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := *expr.Condition(); got != tt.want {
+				t.Errorf("inExpression() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
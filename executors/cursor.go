@@ -0,0 +1,98 @@
+package executors
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/FrontMage/dynamo.cli/journal"
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/FrontMage/dynamo.cli/tables"
+	"github.com/FrontMage/dynamo.cli/utils"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// cursor is where a SELECT stopped at its LIMIT, key is its last item's key which NEXT reads on after
+type cursor struct {
+	stmt sqlparser.SelectStatement
+	key  map[string]*dynamodb.AttributeValue
+}
+
+// lastCursor is the cursor of the last SELECT, nil if it read every item or can't be continued
+var lastCursor *cursor
+
+// ClearCursor forgets where the last SELECT stopped, the items of another connection are not the same
+func ClearCursor() {
+	lastCursor = nil
+}
+
+// CursorToken returns the token of AFTER continuing the last SELECT, ok is false if there is nothing to continue
+func CursorToken() (string, bool) {
+	if lastCursor == nil {
+		return "", false
+	}
+	return encodeCursor(lastCursor.key), true
+}
+
+// encodeCursor writes a key as an opaque token, base64 of its DynamoDB JSON
+func encodeCursor(key map[string]*dynamodb.AttributeValue) string {
+	b, _ := json.Marshal(journal.Item(key))
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor reads the key of a token written by encodeCursor
+func decodeCursor(token string) (map[string]*dynamodb.AttributeValue, error) {
+	key := map[string]*dynamodb.AttributeValue{}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(b, &key)
+	}
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("Invalid AFTER token %s, copy it as \\cursor prints it", token)
+	}
+	return key, nil
+}
+
+// keyAttributes are the attributes of the key a Query or Scan reads on after,
+// the primary key along with the key of the index queried
+func keyAttributes(plan selectPlan) ([]string, error) {
+	tableDesc, err := tables.GetTableDesc(&plan.tableName)
+	if err != nil {
+		return nil, err
+	}
+	return indexKeyAttributes(tableDesc.Table, plan.indexName), nil
+}
+
+// indexKeyAttributes are the attributes of the primary key and of the key of a global or local secondary index
+func indexKeyAttributes(desc *dynamodb.TableDescription, indexName string) []string {
+	attributes := briefTable(desc).keySchemas
+	if index, _, ok := hintedIndex(desc, indexName); ok {
+		for _, a := range []string{index.field, index.rangeKey} {
+			if a != "" && utils.FindIndex(attributes, a) == -1 {
+				attributes = append(attributes, a)
+			}
+		}
+	}
+	return attributes
+}
+
+// Next reads the items following the last SELECT which stopped at its LIMIT, as many as its LIMIT or n of NEXT n
+func Next(nextSQL string) (string, error) {
+	next, ok := sqlparser.ParseNext(nextSQL)
+	if !ok {
+		return "", errors.New("Usage: NEXT [n], n is the number of items to read, the LIMIT of the last SELECT by default")
+	}
+	if InReadOnlyTransaction() {
+		return "", errors.New("NEXT is not read in a transaction, COMMIT or ROLLBACK first")
+	}
+	if lastCursor == nil {
+		return "", errors.New("Nothing to continue, NEXT follows a SELECT which stopped at its LIMIT")
+	}
+	stmt := lastCursor.stmt
+	stmt.Offset, stmt.After = 0, encodeCursor(lastCursor.key)
+	if next.Limit > 0 {
+		stmt.Limit = next.Limit
+	}
+	return selectRows(stmt)
+}
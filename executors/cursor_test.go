@@ -0,0 +1,68 @@
+package executors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func Test_decodeCursor(t *testing.T) {
+	key := map[string]*dynamodb.AttributeValue{"userId": {N: aws.String("1")}, "ts": {S: aws.String("2018-01-01")}, "b": {B: []byte{0, 1}}}
+	tests := []struct {
+		name    string
+		token   string
+		want    map[string]*dynamodb.AttributeValue
+		wantErr bool
+	}{
+		{name: "test decodeCursor of encodeCursor", token: encodeCursor(key), want: key},
+		{name: "test decodeCursor of garbage", token: "not a token", wantErr: true},
+		{name: "test decodeCursor of an empty key", token: encodeCursor(map[string]*dynamodb.AttributeValue{}), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCursor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_indexKeyAttributes(t *testing.T) {
+	key := func(name, kind string) *dynamodb.KeySchemaElement {
+		return &dynamodb.KeySchemaElement{AttributeName: aws.String(name), KeyType: aws.String(kind)}
+	}
+	desc := &dynamodb.TableDescription{
+		ItemCount: aws.Int64(0),
+		KeySchema: []*dynamodb.KeySchemaElement{key("userId", dynamodb.KeyTypeHash), key("ts", dynamodb.KeyTypeRange)},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{{
+			IndexName: aws.String("byStatus"),
+			KeySchema: []*dynamodb.KeySchemaElement{key("status", dynamodb.KeyTypeHash), key("ts", dynamodb.KeyTypeRange)},
+		}},
+		LocalSecondaryIndexes: []*dynamodb.LocalSecondaryIndexDescription{{
+			IndexName: aws.String("byPrice"),
+			KeySchema: []*dynamodb.KeySchemaElement{key("userId", dynamodb.KeyTypeHash), key("price", dynamodb.KeyTypeRange)},
+		}},
+	}
+	tests := []struct {
+		name  string
+		index string
+		want  []string
+	}{
+		{name: "test indexKeyAttributes of the table", want: []string{"userId", "ts"}},
+		{name: "test indexKeyAttributes of a global index", index: "byStatus", want: []string{"userId", "ts", "status"}},
+		{name: "test indexKeyAttributes of a local index", index: "byPrice", want: []string{"userId", "ts", "price"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := indexKeyAttributes(desc, tt.index); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("indexKeyAttributes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package executors
+
+import (
+	"errors"
+
+	"github.com/FrontMage/dynamo.cli/db"
+	"github.com/FrontMage/dynamo.cli/journal"
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/FrontMage/dynamo.cli/utils"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// Delete executes deleteSQL string by parsing to dynamodb api
+func Delete(deleteSQL string) (string, error) {
+	stmt := sqlparser.ParseDelete(deleteSQL)
+	if stmt.TableName == "" {
+		return "", errors.New("Can't utils.Find table name, check your inputs")
+	}
+	if len(stmt.Conditions) == 0 && !stmt.All {
+		return "", errors.New("DELETE without WHERE is not allowed, use WHERE ALL to delete every item")
+	}
+
+	targets, err := findWriteTargets(stmt.TableName, stmt.Conditions)
+	if err != nil {
+		return "", err
+	}
+	if !confirmWrite("delete", stmt.TableName, targets.keys) {
+		return "", errors.New("Canceled, no item is deleted")
+	}
+
+	if InTransaction() {
+		// an exact key is deleted unconditionally
+		return bufferWrites(deleteSQL, stmt.TableName, targets.keys, nil, func(key map[string]*dynamodb.AttributeValue, condition *expression.ConditionBuilder) (*dynamodb.TransactWriteItem, error) {
+			expr, err := writeExpression(nil, targets.condition, condition)
+			if err != nil {
+				return nil, err
+			}
+			return &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+				ConditionExpression:       expr.Condition(),
+				ExpressionAttributeNames:  expressionNames(expr),
+				ExpressionAttributeValues: expr.Values(),
+				Key:                       key,
+				TableName:                 &stmt.TableName,
+			}}, nil
+		})
+	}
+
+	statement := journal.NextStatement()
+	if targets.condition == nil {
+		// an exact key is deleted unconditionally, the returned old item tells whether it existed
+		return deleteExactKey(statement, deleteSQL, stmt.TableName, targets.keys[0])
+	}
+	if expr, err := expression.NewBuilder().WithCondition(*targets.condition).Build(); err == nil {
+		deleteItem := func(key map[string]*dynamodb.AttributeValue) (*dynamodb.DeleteItemOutput, error) {
+			deleteInput := &dynamodb.DeleteItemInput{
+				ExpressionAttributeNames:  expressionNames(expr),
+				ExpressionAttributeValues: expr.Values(),
+				ConditionExpression:       expr.Condition(),
+				Key:                       key,
+				TableName:                 &stmt.TableName,
+			}
+			deleteInput.SetReturnValues("ALL_OLD")
+			result, err := db.DynamoDB.DeleteItem(deleteInput)
+			if err == nil {
+				recordWrite(statement, deleteSQL, stmt.TableName, key, result.Attributes, nil)
+			}
+			return result, err
+		}
+
+		// a single item delete shows the deleted item
+		if len(targets.keys) == 1 {
+			if result, err := deleteItem(targets.keys[0]); err == nil {
+				return utils.FormatPrettyMap(result.Attributes), nil
+			} else if isConditionFailed(err) {
+				return "", conditionNotMet("deleted", stmt.TableName, targets.keys[0])
+			} else {
+				return "", err
+			}
+		}
+
+		summary := writeEach(targets.keys, "deleted", func(key map[string]*dynamodb.AttributeValue) error {
+			_, err := deleteItem(key)
+			return err
+		})
+		if len(summary.failures) > 0 {
+			return "", errors.New(summary.format("deleted"))
+		}
+		return summary.format("deleted"), nil
+	} else {
+		return "", err
+	}
+}
+
+// deleteExactKey deletes the item of a primary key given in WHERE
+func deleteExactKey(statement int64, deleteSQL, tableName string, key map[string]*dynamodb.AttributeValue) (string, error) {
+	deleteInput := &dynamodb.DeleteItemInput{
+		Key:       key,
+		TableName: &tableName,
+	}
+	deleteInput.SetReturnValues("ALL_OLD")
+	if result, err := db.DynamoDB.DeleteItem(deleteInput); err == nil {
+		if len(result.Attributes) == 0 {
+			return formatCount(0, "deleted"), nil
+		}
+		recordWrite(statement, deleteSQL, tableName, key, result.Attributes, nil)
+		return utils.FormatPrettyMap(result.Attributes), nil
+	} else {
+		return "", err
+	}
+}
@@ -0,0 +1,97 @@
+package executors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/FrontMage/dynamo.cli/tables"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// checkHints refuses the hints a statement can't take
+func checkHints(stmt sqlparser.SelectStatement) error {
+	if len(stmt.Hints.Unknown) > 0 {
+		return fmt.Errorf("Unknown hint %s, supports CONSISTENT, USE_INDEX(index), FORCE_SCAN and PARALLEL(n)", strings.Join(stmt.Hints.Unknown, ", "))
+	}
+	if stmt.Hints.Index != "" && !stmt.Hints.ForceScan && len(stmt.Conditions) == 0 {
+		return fmt.Errorf("USE_INDEX(%s) queries by = of the hash key of the index, add WHERE or FORCE_SCAN", stmt.Hints.Index)
+	}
+	return nil
+}
+
+// hintedIndex finds an index of a table by its name, global is false for a local secondary index
+func hintedIndex(desc *dynamodb.TableDescription, name string) (index tableIndex, global bool, ok bool) {
+	keys := func(schema []*dynamodb.KeySchemaElement) tableIndex {
+		index := tableIndex{name: name}
+		for _, s := range schema {
+			if *s.KeyType == dynamodb.KeyTypeHash {
+				index.field = *s.AttributeName
+			} else {
+				index.rangeKey = *s.AttributeName
+			}
+		}
+		return index
+	}
+	for _, i := range desc.GlobalSecondaryIndexes {
+		if *i.IndexName == name {
+			return keys(i.KeySchema), true, true
+		}
+	}
+	for _, i := range desc.LocalSecondaryIndexes {
+		if *i.IndexName == name {
+			return keys(i.KeySchema), false, true
+		}
+	}
+	return tableIndex{}, false, false
+}
+
+// planHinted plans the read of a statement with USE_INDEX or FORCE_SCAN, which pick the index or the Scan themselves,
+// USE_INDEX queries the index by = of its hash key, along with FORCE_SCAN it scans the index
+func planHinted(plan selectPlan, stmt sqlparser.SelectStatement) (selectPlan, error) {
+	tableDesc, err := tables.GetTableDesc(&stmt.TableName)
+	if err != nil {
+		return plan, err
+	}
+	plan.keySchemas = briefTable(tableDesc.Table).keySchemas
+	plan.filters = stmt.Conditions
+	if stmt.Hints.Index == "" {
+		return plan, nil
+	}
+	index, global, ok := hintedIndex(tableDesc.Table, stmt.Hints.Index)
+	if !ok {
+		return plan, fmt.Errorf("No index %s on %s, DESC TABLE %s lists its indexes", stmt.Hints.Index, stmt.TableName, stmt.TableName)
+	}
+	if global && plan.consistent {
+		return plan, fmt.Errorf("CONSISTENT can't read the global index %s, DynamoDB reads global indexes eventually consistent", index.name)
+	}
+	plan.indexName = index.name
+	if stmt.Hints.ForceScan {
+		return plan, nil
+	}
+	var hashCondition *sqlparser.Condition
+	for idx, c := range stmt.Conditions {
+		if c.Key == index.field && c.Operator == sqlparser.OpEq && c.Function == "" {
+			hashCondition = &stmt.Conditions[idx]
+			break
+		}
+	}
+	if hashCondition == nil {
+		return plan, fmt.Errorf("USE_INDEX(%s) queries by = of its hash key %s, add FORCE_SCAN to scan the index", index.name, index.field)
+	}
+	plan.method, plan.keyConditions, plan.filters = methodQuery, []sqlparser.Condition{*hashCondition}, []sqlparser.Condition{}
+	plan.rangeKey = index.rangeKey
+	for _, c := range stmt.Conditions {
+		if c == *hashCondition {
+			continue
+		}
+		if c.Key == index.rangeKey && len(plan.keyConditions) == 1 {
+			if _, ok := sortKeyCondition(c); ok {
+				plan.keyConditions = append(plan.keyConditions, c)
+				continue
+			}
+		}
+		plan.filters = append(plan.filters, c)
+	}
+	return plan, nil
+}
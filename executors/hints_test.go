@@ -0,0 +1,65 @@
+package executors
+
+import (
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func Test_checkHints(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr bool
+	}{
+		{name: "test checkHints", sql: "SELECT /*+ CONSISTENT PARALLEL(4) */ * FROM user END"},
+		{name: "test checkHints with USE_INDEX", sql: "SELECT * FROM user WHERE email = a WITH (USE_INDEX(email-index)) END"},
+		{name: "test checkHints with USE_INDEX and FORCE_SCAN", sql: "SELECT /*+ USE_INDEX(email-index) FORCE_SCAN */ * FROM user END"},
+		{name: "test checkHints with USE_INDEX without WHERE", sql: "SELECT /*+ USE_INDEX(email-index) */ * FROM user END", wantErr: true},
+		{name: "test checkHints with an unknown hint", sql: "SELECT /*+ FAST */ * FROM user END", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkHints(sqlparser.ParseSelect(tt.sql)); (err != nil) != tt.wantErr {
+				t.Errorf("checkHints() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_hintedIndex(t *testing.T) {
+	key := func(name, kind string) *dynamodb.KeySchemaElement {
+		return &dynamodb.KeySchemaElement{AttributeName: aws.String(name), KeyType: aws.String(kind)}
+	}
+	desc := &dynamodb.TableDescription{
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{{
+			IndexName: aws.String("byEmail"),
+			KeySchema: []*dynamodb.KeySchemaElement{key("email", dynamodb.KeyTypeHash)},
+		}},
+		LocalSecondaryIndexes: []*dynamodb.LocalSecondaryIndexDescription{{
+			IndexName: aws.String("byPrice"),
+			KeySchema: []*dynamodb.KeySchemaElement{key("userId", dynamodb.KeyTypeHash), key("price", dynamodb.KeyTypeRange)},
+		}},
+	}
+	tests := []struct {
+		name       string
+		index      string
+		want       tableIndex
+		wantGlobal bool
+		wantOk     bool
+	}{
+		{name: "test hintedIndex", index: "byEmail", want: tableIndex{name: "byEmail", field: "email"}, wantGlobal: true, wantOk: true},
+		{name: "test hintedIndex with a local index", index: "byPrice", want: tableIndex{name: "byPrice", field: "userId", rangeKey: "price"}, wantOk: true},
+		{name: "test hintedIndex without the index", index: "email-index"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, global, ok := hintedIndex(desc, tt.index)
+			if got != tt.want || global != tt.wantGlobal || ok != tt.wantOk {
+				t.Errorf("hintedIndex() = %+v, %v, %v, want %+v, %v, %v", got, global, ok, tt.want, tt.wantGlobal, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package executors
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/FrontMage/dynamo.cli/db"
+	"github.com/FrontMage/dynamo.cli/journal"
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/FrontMage/dynamo.cli/tables"
+	"github.com/FrontMage/dynamo.cli/utils"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// Insert executes insertSQL string by parsing to dynamodb api, an existing item is never overwritten
+func Insert(insertSQL string) (string, error) {
+	stmt := sqlparser.ParseInsert(insertSQL)
+	if stmt.TableName == "" {
+		return "", errors.New("Can't parse INSERT, use INSERT INTO table (a, b) VALUES (1, 'x')")
+	}
+	items, err := itemsOf(stmt)
+	if err != nil {
+		return "", err
+	}
+
+	tableDesc, err := tables.GetTableDesc(&stmt.TableName)
+	if err != nil {
+		return "", err
+	}
+	tableInfo := briefTable(tableDesc.Table)
+	// rows are written like the items of a multi-item update, keyed by themselves
+	rows := map[string]map[string]*dynamodb.AttributeValue{}
+	keys := []map[string]*dynamodb.AttributeValue{}
+	for _, item := range items {
+		key := keyOf(item, tableInfo.keySchemas)
+		rows[utils.FormatKey(key)] = item
+		keys = append(keys, key)
+	}
+	if !confirmWrite("insert", stmt.TableName, keys) {
+		return "", errors.New("Canceled, no item is inserted")
+	}
+
+	statement := journal.NextStatement()
+	notExists := expression.AttributeNotExists(attributeName(tableInfo.keySchemas[0]))
+	expr, err := expression.NewBuilder().WithCondition(notExists).Build()
+	if err != nil {
+		return "", err
+	}
+	if InTransaction() {
+		return bufferWrites(insertSQL, stmt.TableName, keys, nil, func(key map[string]*dynamodb.AttributeValue, condition *expression.ConditionBuilder) (*dynamodb.TransactWriteItem, error) {
+			expr, err := writeExpression(nil, &notExists, condition)
+			if err != nil {
+				return nil, err
+			}
+			return &dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+				ConditionExpression:       expr.Condition(),
+				ExpressionAttributeNames:  expressionNames(expr),
+				ExpressionAttributeValues: expr.Values(),
+				Item:                      rows[utils.FormatKey(key)],
+				TableName:                 &stmt.TableName,
+			}}, nil
+		})
+	}
+	putItem := func(item map[string]*dynamodb.AttributeValue) error {
+		_, err := db.DynamoDB.PutItem(&dynamodb.PutItemInput{
+			ConditionExpression:      expr.Condition(),
+			ExpressionAttributeNames: expressionNames(expr),
+			Item:                     item,
+			TableName:                &stmt.TableName,
+		})
+		if isConditionFailed(err) {
+			return errors.New("Item already exists, use UPDATE to change it")
+		} else if err != nil {
+			return err
+		}
+		recordWrite(statement, insertSQL, stmt.TableName, keyOf(item, tableInfo.keySchemas), nil, item)
+		return nil
+	}
+
+	if len(items) == 1 {
+		if err := putItem(items[0]); err != nil {
+			return "", err
+		}
+		return formatCount(1, "inserted"), nil
+	}
+	summary := writeEach(keys, "inserted", func(key map[string]*dynamodb.AttributeValue) error {
+		return putItem(rows[utils.FormatKey(key)])
+	})
+	if len(summary.failures) > 0 {
+		return "", errors.New(summary.format("inserted"))
+	}
+	return summary.format("inserted"), nil
+}
+
+// itemsOf converts the rows of an insert statement to items
+func itemsOf(stmt sqlparser.InsertStatement) ([]map[string]*dynamodb.AttributeValue, error) {
+	items := []map[string]*dynamodb.AttributeValue{}
+	for idx, row := range stmt.Rows {
+		if len(row) != len(stmt.Attributes) {
+			return nil, fmt.Errorf("Row %d has %d values for %d attributes", idx+1, len(row), len(stmt.Attributes))
+		}
+		item := map[string]*dynamodb.AttributeValue{}
+		for i, name := range stmt.Attributes {
+			item[name] = literalValue(row[i])
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return nil, errors.New("Nothing to insert, use VALUES (1, 'x'), (2, 'y')")
+	}
+	return items, nil
+}
@@ -0,0 +1,34 @@
+package executors
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/tables"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestInsert_confirm(t *testing.T) {
+	defer tables.ClearCache()
+	tables.ClearCache()
+	tables.TableInfoCache["user"] = &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+		ItemCount: aws.Int64(0),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("user_id"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		},
+	}}
+	defer func(confirm func(string) bool) { Confirm = confirm }(Confirm)
+	asked := ""
+	Confirm = func(question string) bool {
+		asked = question
+		return false
+	}
+	_, err := Insert("INSERT INTO user (user_id, name) VALUES (1, 'a'), (2, 'b') END")
+	if err == nil || err.Error() != "Canceled, no item is inserted" {
+		t.Errorf("Insert() error = %v, want the insert canceled", err)
+	}
+	if !strings.HasPrefix(asked, "This will insert about 2 items of user, first keys:\n  {\"user_id\":1}\n  {\"user_id\":2}") {
+		t.Errorf("Insert() asked %q", asked)
+	}
+}
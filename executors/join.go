@@ -0,0 +1,597 @@
+package executors
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/FrontMage/dynamo.cli/db"
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/FrontMage/dynamo.cli/tables"
+	"github.com/FrontMage/dynamo.cli/utils"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// joinBatchSize is how many items of the driving table are joined at a time, the keys of a BatchGetItem
+const joinBatchSize = batchGetSize
+
+// joinStep is how the items of a joined table are found for the rows joined so far,
+// left are the paths of the rows and right the paths of the items they are matched on, like o.userId and id.
+// method is methodBatchGetItem when right has the primary key, methodQuery when it has the hash key
+// of the table or of an index, otherwise methodScan which reads the whole table once and joins it by hash
+// joinStep lookup are the indexes of left and right looked up by BatchGetItem or Query
+type joinStep struct {
+	join       sqlparser.Join
+	left       []string
+	right      []string
+	method     string
+	keySchemas []string
+	indexName  string
+	lookup     []int
+	attributes []string
+	hashed     map[string][]map[string]*dynamodb.AttributeValue
+}
+
+// joinPlan is how the rows of a select statement with JOIN are read, a row holds the item of each table under its alias.
+// The driving table, the first one after FROM, is read by planSelect with the conditions of WHERE on it,
+// filters are the other conditions, matched on the joined rows
+type joinPlan struct {
+	alias    string
+	driving  sqlparser.SelectStatement
+	steps    []*joinStep
+	filters  []sqlparser.Condition
+	matchers []func(map[string]*dynamodb.AttributeValue) bool
+}
+
+// planJoin plans the reads of a select statement with JOIN, needed are the paths of the rows its columns use,
+// every path must start with the alias of its table
+func planJoin(stmt sqlparser.SelectStatement, needed []string) (joinPlan, error) {
+	plan := joinPlan{alias: stmt.TableAlias}
+	if stmt.After != "" {
+		return plan, errors.New("AFTER continues a SELECT of a single table, not a JOIN")
+	} else if stmt.Offset < 0 {
+		return plan, errors.New("OFFSET takes the number of rows to skip, like LIMIT 10 OFFSET 20")
+	}
+	aliases := []string{stmt.TableAlias}
+	for _, j := range stmt.Joins {
+		if j.TableName == "" {
+			return plan, errors.New("Invalid JOIN, it takes a table, an optional alias and ON, like JOIN users u ON o.userId = u.id")
+		}
+		if utils.FindIndex(aliases, j.Alias) != -1 {
+			return plan, fmt.Errorf("%s names two tables, give each one its own alias like JOIN %s b ON ...", j.Alias, j.TableName)
+		}
+		aliases = append(aliases, j.Alias)
+	}
+	// split returns the alias a path starts with and the path in the items of its table
+	split := func(path string) (string, string, error) {
+		for _, a := range aliases {
+			if strings.HasPrefix(path, a+".") {
+				if _, err := sqlparser.ParsePath(path[len(a)+1:]); err != nil {
+					return "", "", err
+				}
+				return a, path[len(a)+1:], nil
+			}
+		}
+		return "", "", fmt.Errorf("%s must start with the alias of its table, like %s.%s", path, stmt.TableAlias, path)
+	}
+	attributes := map[string][]string{}
+	all := false
+	add := func(path string) error {
+		if path == "*" {
+			all = true
+			return nil
+		}
+		alias, attribute, err := split(path)
+		if err == nil && utils.FindIndex(attributes[alias], attribute) == -1 {
+			attributes[alias] = append(attributes[alias], attribute)
+		}
+		return err
+	}
+	for _, path := range needed {
+		if err := add(path); err != nil {
+			return plan, err
+		}
+	}
+
+	// conditions on the driving table are read by it, the others are matched on the joined rows,
+	// as are the hints, its batches are read on one after the other which a parallel Scan can't do
+	if stmt.Hints.Parallel > 1 {
+		return plan, errors.New("PARALLEL splits the Scan of a SELECT without JOIN or table aliases")
+	}
+	plan.driving = sqlparser.SelectStatement{TableName: stmt.TableName, Conditions: []sqlparser.Condition{}, Limit: joinBatchSize, Hints: stmt.Hints}
+	for _, c := range stmt.Conditions {
+		keys := []string{c.Key}
+		if c.Operator == sqlparser.OpIn {
+			keys, _ = c.InList()
+		}
+		driving := true
+		for idx, k := range keys {
+			if c.Key == "" {
+				// planSelect reports it as it does without JOIN
+				break
+			}
+			alias, attribute, err := split(k)
+			if err != nil {
+				return plan, err
+			}
+			driving = driving && alias == plan.alias
+			keys[idx] = attribute
+		}
+		if driving {
+			if c.Key != "" {
+				c.Key = keys[0]
+				if len(keys) > 1 {
+					c.Key = "(" + strings.Join(keys, ", ") + ")"
+				}
+			}
+			plan.driving.Conditions = append(plan.driving.Conditions, c)
+			continue
+		}
+		paths := []string{}
+		match, err := compileCondition(c, &paths)
+		if err != nil {
+			return plan, err
+		}
+		for _, p := range paths {
+			add(p)
+		}
+		plan.filters, plan.matchers = append(plan.filters, c), append(plan.matchers, match)
+	}
+	// the keys of an IN are read at once, LIMIT would stop the join after the first batch
+	if driving, err := planRead(plan.driving); err == nil && driving.method == methodBatchGetItem {
+		plan.driving.Limit = -1
+	}
+
+	for idx, j := range stmt.Joins {
+		step := &joinStep{join: j, method: methodScan}
+		for _, c := range j.On {
+			invalid := fmt.Errorf("ON of JOIN %s takes = of its attributes and those of a table before it, like ON o.userId = u.id", j.TableName)
+			if c.Operator != sqlparser.OpEq || c.Function != "" || c.Key == "" {
+				return plan, invalid
+			}
+			keyAlias, keyAttribute, err := split(c.Key)
+			if err != nil {
+				return plan, err
+			}
+			valueAlias, valueAttribute, err := split(c.Value)
+			if err != nil {
+				return plan, err
+			}
+			switch {
+			case valueAlias == j.Alias && utils.FindIndex(aliases[:idx+1], keyAlias) != -1:
+				step.left, step.right = append(step.left, c.Key), append(step.right, valueAttribute)
+			case keyAlias == j.Alias && utils.FindIndex(aliases[:idx+1], valueAlias) != -1:
+				step.left, step.right = append(step.left, c.Value), append(step.right, keyAttribute)
+			default:
+				return plan, invalid
+			}
+		}
+		if len(step.left) == 0 {
+			return plan, fmt.Errorf("JOIN %s takes ON, like JOIN users u ON o.userId = u.id", j.TableName)
+		}
+		for idx := range step.left {
+			add(step.left[idx])
+			add(j.Alias + "." + step.right[idx])
+		}
+		if err := step.plan(); err != nil {
+			return plan, err
+		}
+		plan.steps = append(plan.steps, step)
+	}
+
+	plan.driving.AttributesToGet = attributes[plan.alias]
+	if all || len(plan.driving.AttributesToGet) == 0 {
+		plan.driving.AttributesToGet = []string{"*"}
+	}
+	for _, step := range plan.steps {
+		step.attributes = attributes[step.join.Alias]
+		if all {
+			step.attributes = []string{"*"}
+		}
+	}
+	return plan, nil
+}
+
+// plan picks how the joined items are found, by BatchGetItem if the primary key is matched,
+// by a Query if the hash key of the table or of an index is, otherwise by a hash join
+func (step *joinStep) plan() error {
+	tableDesc, err := tables.GetTableDesc(&step.join.TableName)
+	if err != nil {
+		return err
+	}
+	table := briefTable(tableDesc.Table)
+	step.keySchemas = table.keySchemas
+	lookup := []int{}
+	for _, schema := range table.keySchemas {
+		if idx := utils.FindIndex(step.right, schema); idx != -1 {
+			lookup = append(lookup, idx)
+		}
+	}
+	if len(lookup) == len(table.keySchemas) {
+		step.method, step.lookup = methodBatchGetItem, lookup
+		return nil
+	}
+	if idx := utils.FindIndex(step.right, table.hashKey); idx != -1 {
+		step.method, step.lookup = methodQuery, []int{idx}
+		return nil
+	}
+	for _, index := range table.globalSecondaryIndexes {
+		if idx := utils.FindIndex(step.right, index.field); idx != -1 {
+			step.method, step.lookup, step.indexName = methodQuery, []int{idx}, index.name
+			return nil
+		}
+	}
+	return nil
+}
+
+// joinValue is how JOIN matches a value, numbers by value whichever way they are written
+func joinValue(v *dynamodb.AttributeValue) string {
+	if v.N != nil {
+		if r, ok := new(big.Rat).SetString(*v.N); ok {
+			return "N:" + r.RatString()
+		}
+	}
+	return typeOf(v) + ":" + utils.FormatKey(map[string]*dynamodb.AttributeValue{"": v})
+}
+
+// joinKey is what a document is matched on by the values of paths, ok is false if one is missing or NULL,
+// which matches nothing
+func joinKey(doc map[string]*dynamodb.AttributeValue, paths []string) (string, bool) {
+	values := []string{}
+	for _, p := range paths {
+		v := valueAt(doc, p)
+		if v == nil || v.NULL != nil {
+			return "", false
+		}
+		values = append(values, joinValue(v))
+	}
+	return strings.Join(values, "\x00"), true
+}
+
+// index groups the joined items by their values of right
+func (step *joinStep) index(items []map[string]*dynamodb.AttributeValue, hashed map[string][]map[string]*dynamodb.AttributeValue) {
+	for _, item := range items {
+		if key, ok := joinKey(item, step.right); ok {
+			hashed[key] = append(hashed[key], item)
+		}
+	}
+}
+
+// find returns the joined items which may match rows grouped by their values of right,
+// reading each key or hash key the rows have once, or the whole table the first time for a hash join
+func (step *joinStep) find(rows []map[string]*dynamodb.AttributeValue) (map[string][]map[string]*dynamodb.AttributeValue, error) {
+	if step.method == methodScan {
+		if step.hashed == nil {
+			if err := step.scan(); err != nil {
+				return nil, err
+			}
+		}
+		return step.hashed, nil
+	}
+	keys := []map[string]*dynamodb.AttributeValue{}
+	seen := map[string]bool{}
+	for _, row := range rows {
+		key, values := map[string]*dynamodb.AttributeValue{}, []string{}
+		for _, idx := range step.lookup {
+			// keys are strings, numbers or binaries, other values match no item
+			if v := valueAt(row, step.left[idx]); v != nil && (v.S != nil || v.N != nil || v.B != nil) {
+				key[step.right[idx]] = v
+				values = append(values, joinValue(v))
+			}
+		}
+		id := strings.Join(values, "\x00")
+		if len(key) == len(step.lookup) && !seen[id] {
+			seen[id] = true
+			keys = append(keys, key)
+		}
+	}
+	hashed := map[string][]map[string]*dynamodb.AttributeValue{}
+	if len(keys) == 0 {
+		return hashed, nil
+	}
+	if step.method == methodBatchGetItem {
+		items, err := batchGetItems(step.join.TableName, step.keySchemas, keys, step.attributes, false)
+		if err != nil {
+			return nil, err
+		}
+		step.index(items, hashed)
+		return hashed, nil
+	}
+	hashKey := step.right[step.lookup[0]]
+	for _, key := range keys {
+		builder := expression.NewBuilder().WithKeyCondition(keyName(hashKey).Equal(expression.Value(key[hashKey])))
+		if step.attributes[0] != "*" {
+			builder = builder.WithProjection(buildProjection(step.attributes))
+		}
+		expr, err := builder.Build()
+		if err != nil {
+			return nil, err
+		}
+		queryInput := &dynamodb.QueryInput{
+			TableName:                 &step.join.TableName,
+			ExpressionAttributeNames:  expressionNames(expr),
+			ExpressionAttributeValues: expr.Values(),
+			KeyConditionExpression:    expr.KeyCondition(),
+			ProjectionExpression:      expr.Projection(),
+		}
+		if step.indexName != "" {
+			queryInput.IndexName = aws.String(step.indexName)
+		}
+		items, err := queryUntilLimit(queryInput, -1, nil, []map[string]*dynamodb.AttributeValue{})
+		if err != nil {
+			return nil, err
+		}
+		step.index(items, hashed)
+	}
+	return hashed, nil
+}
+
+// scan reads every item of the joined table for a hash join, up to SortLimit of them
+func (step *joinStep) scan() error {
+	scanInput := &dynamodb.ScanInput{TableName: &step.join.TableName}
+	if step.attributes[0] != "*" {
+		expr, err := expression.NewBuilder().WithProjection(buildProjection(step.attributes)).Build()
+		if err != nil {
+			return err
+		}
+		scanInput.ExpressionAttributeNames = expressionNames(expr)
+		scanInput.ProjectionExpression = expr.Projection()
+	}
+	hashed := map[string][]map[string]*dynamodb.AttributeValue{}
+	var read int64
+	for {
+		result, err := db.DynamoDB.Scan(scanInput)
+		if err != nil {
+			return err
+		}
+		step.index(result.Items, hashed)
+		read += int64(len(result.Items))
+		if read > SortLimit {
+			return fmt.Errorf("More than %d items of %s to hash join, join on its key or an index or raise sort_limit", SortLimit, step.join.TableName)
+		}
+		Progress(fmt.Sprintf("%d items of %s read to hash join", read, step.join.TableName))
+		if result.LastEvaluatedKey == nil {
+			break
+		}
+		scanInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+	step.hashed = hashed
+	return nil
+}
+
+// joinRows joins the items of the step's table to rows, a row without a match is dropped by INNER JOIN
+// and kept without them by LEFT JOIN
+func (step *joinStep) joinRows(rows []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
+	hashed, err := step.find(rows)
+	if err != nil {
+		return nil, err
+	}
+	joined := []map[string]*dynamodb.AttributeValue{}
+	for _, row := range rows {
+		var matches []map[string]*dynamodb.AttributeValue
+		if key, ok := joinKey(row, step.left); ok {
+			matches = hashed[key]
+		}
+		for _, item := range matches {
+			r := map[string]*dynamodb.AttributeValue{step.join.Alias: {M: item}}
+			for alias, v := range row {
+				r[alias] = v
+			}
+			joined = append(joined, r)
+		}
+		if len(matches) == 0 && step.join.Kind == sqlparser.JoinLeft {
+			joined = append(joined, row)
+		}
+	}
+	return joined, nil
+}
+
+// read reads the driving items joinBatchSize at a time, joins them and passes the rows matching WHERE to emit
+// until it returns false
+func (plan joinPlan) read(emit func(map[string]*dynamodb.AttributeValue) bool) error {
+	read := plan.driving
+	var driven int64
+	for {
+		items, _, resumeKey, err := selectItemsWith(read, nil)
+		if err != nil {
+			return err
+		}
+		rows := []map[string]*dynamodb.AttributeValue{}
+		for _, item := range items {
+			rows = append(rows, map[string]*dynamodb.AttributeValue{plan.alias: {M: item}})
+		}
+		for _, step := range plan.steps {
+			if rows, err = step.joinRows(rows); err != nil {
+				return err
+			}
+		}
+		for _, row := range rows {
+			matched := true
+			for _, match := range plan.matchers {
+				matched = matched && match(row)
+			}
+			if matched && !emit(row) {
+				return nil
+			}
+		}
+		driven += int64(len(items))
+		Progress(fmt.Sprintf("%d items of %s joined", driven, plan.driving.TableName))
+		// the driving table is read on after its last item, as NEXT does
+		if resumeKey == nil {
+			return nil
+		}
+		read.After = encodeCursor(resumeKey)
+	}
+}
+
+// joinItems reads the rows of a select statement with JOIN, computing their columns or aggregating them,
+// isSingleRow is true for aggregates without GROUP BY.
+// Rows are sorted before their columns are computed, so ORDER BY may use any attribute of the joined tables
+func joinItems(stmt sqlparser.SelectStatement) (rows []map[string]*dynamodb.AttributeValue, isSingleRow bool, err error) {
+	if stmt.IsAggregate() {
+		agg, err := planAggregation(stmt)
+		if err != nil {
+			return nil, false, err
+		}
+		plan, err := planJoin(stmt, agg.attributesToGet())
+		if err != nil {
+			return nil, false, err
+		}
+		acc := newAccumulator(agg)
+		if err := plan.read(func(row map[string]*dynamodb.AttributeValue) bool {
+			acc.add(row)
+			return true
+		}); err != nil {
+			return nil, false, err
+		}
+		return acc.groupRows(stmt), len(agg.groupBy) == 0, nil
+	}
+	comp, plan, err := planJoinComputation(stmt)
+	if err != nil {
+		return nil, false, err
+	}
+	rows = []map[string]*dynamodb.AttributeValue{}
+	if stmt.Limit == 0 {
+		return rows, false, nil
+	}
+	seen := map[string]bool{}
+	var skipped int64
+	// add adds the row of a joined row unless it's a DISTINCT one already seen or skipped by OFFSET,
+	// false once LIMIT rows are added
+	add := func(joined map[string]*dynamodb.AttributeValue) bool {
+		row := comp.row(joined)
+		if comp.distinct {
+			id := utils.FormatKey(row)
+			if seen[id] {
+				return true
+			}
+			seen[id] = true
+		}
+		if skipped < stmt.Offset {
+			skipped++
+			return true
+		}
+		rows = append(rows, row)
+		return stmt.Limit < 0 || int64(len(rows)) < stmt.Limit
+	}
+	if len(stmt.OrderBy) == 0 {
+		return rows, false, plan.read(add)
+	}
+	Warn(fmt.Sprintf("ORDER BY %s is sorted client-side, every joined row is read first", orderByText(stmt.OrderBy)))
+	joined := []map[string]*dynamodb.AttributeValue{}
+	if err := plan.read(func(row map[string]*dynamodb.AttributeValue) bool {
+		joined = append(joined, row)
+		return int64(len(joined)) <= SortLimit
+	}); err != nil {
+		return nil, false, err
+	}
+	if int64(len(joined)) > SortLimit {
+		return nil, false, fmt.Errorf("More than %d joined rows to sort client-side, narrow WHERE or raise sort_limit", SortLimit)
+	}
+	sortItems(joined, stmt.OrderBy, comp.joinedValue)
+	for _, row := range joined {
+		if !add(row) {
+			break
+		}
+	}
+	return rows, false, nil
+}
+
+// planJoinComputation plans the columns and the reads of a select statement with JOIN and without aggregates
+func planJoinComputation(stmt sqlparser.SelectStatement) (computation, joinPlan, error) {
+	columns := stmt
+	columns.OrderBy = nil
+	comp, err := planComputation(columns)
+	if err != nil {
+		return comp, joinPlan{}, err
+	}
+	needed := append([]string{}, comp.needed...)
+	for _, o := range stmt.OrderBy {
+		if !comp.computes(o.Key) {
+			needed = append(needed, o.Key)
+		}
+	}
+	plan, err := planJoin(stmt, needed)
+	return comp, plan, err
+}
+
+// computes tells whether key names a computed column
+func (comp computation) computes(key string) bool {
+	for _, c := range comp.columns {
+		if c.name == key && !c.plain {
+			return true
+		}
+	}
+	return false
+}
+
+// joinedValue is the value of a joined row by a computed column name, or by a path
+func (comp computation) joinedValue(row map[string]*dynamodb.AttributeValue, key string) *dynamodb.AttributeValue {
+	for _, c := range comp.columns {
+		if c.name == key && !c.plain {
+			return c.compute(row)
+		}
+	}
+	return valueAt(row, key)
+}
+
+// explain describes how the tables are joined for EXPLAIN
+func (plan joinPlan) explain() string {
+	lines := []string{fmt.Sprintf("  batches: %d items of %s joined at a time", joinBatchSize, plan.driving.TableName)}
+	for _, step := range plan.steps {
+		line := "  join: " + step.join.String() + ", "
+		switch {
+		case step.method == methodBatchGetItem:
+			line += fmt.Sprintf("BatchGetItem of the keys of every %d items", joinBatchSize)
+		case step.indexName != "":
+			line += fmt.Sprintf("Query using index %s of every %s", step.indexName, step.left[step.lookup[0]])
+		case step.method == methodQuery:
+			line += "Query of every " + step.left[step.lookup[0]]
+		default:
+			line += fmt.Sprintf("hash join of a Scan of every item, up to %d", SortLimit)
+		}
+		lines = append(lines, line)
+	}
+	if len(plan.filters) > 0 {
+		filters := []string{}
+		for _, c := range plan.filters {
+			filters = append(filters, c.String())
+		}
+		lines = append(lines, "  client-side filter: "+strings.Join(filters, " AND ")+", matched on the joined rows")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// explainJoin describes how the rows of a select statement with JOIN would be read for EXPLAIN
+func explainJoin(stmt sqlparser.SelectStatement, paging string) (string, error) {
+	var plan joinPlan
+	var rows string
+	if stmt.IsAggregate() {
+		agg, err := planAggregation(stmt)
+		if err != nil {
+			return "", err
+		}
+		if plan, err = planJoin(stmt, agg.attributesToGet()); err != nil {
+			return "", err
+		}
+		// joined rows are never counted by DynamoDB
+		rows = agg.explain(selectPlan{})
+	} else {
+		comp, p, err := planJoinComputation(stmt)
+		if err != nil {
+			return "", err
+		}
+		plan, rows = p, comp.explain()
+		if len(stmt.OrderBy) > 0 {
+			rows += fmt.Sprintf("\n  order: %s, sorted client-side, up to %d joined rows", orderByText(stmt.OrderBy), SortLimit)
+		}
+	}
+	driving, err := planSelect(plan.driving)
+	if err != nil {
+		return "", err
+	}
+	return driving.explain() + "\n" + plan.explain() + "\n" + rows + paging, nil
+}
@@ -0,0 +1,74 @@
+package executors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func Test_joinKey(t *testing.T) {
+	n := func(s string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{N: aws.String(s)} }
+	doc := func(v *dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{"o": {M: map[string]*dynamodb.AttributeValue{"id": v}}}
+	}
+	one, _ := joinKey(doc(n("1")), []string{"o.id"})
+	if other, ok := joinKey(doc(n("1.0")), []string{"o.id"}); !ok || other != one {
+		t.Errorf("joinKey() of 1.0 = %q, want %q", other, one)
+	}
+	if other, _ := joinKey(doc(&dynamodb.AttributeValue{S: aws.String("1")}), []string{"o.id"}); other == one {
+		t.Errorf("joinKey() of '1' = %q, want it apart from 1", other)
+	}
+	if _, ok := joinKey(doc(&dynamodb.AttributeValue{NULL: aws.Bool(true)}), []string{"o.id"}); ok {
+		t.Error("joinKey() of NULL is ok, want it to match nothing")
+	}
+	if _, ok := joinKey(doc(n("1")), []string{"o.missing"}); ok {
+		t.Error("joinKey() of a missing value is ok, want it to match nothing")
+	}
+}
+
+func Test_joinStep_joinRows(t *testing.T) {
+	n := func(s string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{N: aws.String(s)} }
+	order := func(id, userID string) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{"o": {M: map[string]*dynamodb.AttributeValue{"id": n(id), "userId": n(userID)}}}
+	}
+	users := []map[string]*dynamodb.AttributeValue{{"id": n("1"), "name": {S: aws.String("a")}}, {"id": n("2"), "name": {S: aws.String("b")}}}
+	rows := []map[string]*dynamodb.AttributeValue{order("10", "1"), order("11", "3"), order("12", "2")}
+	tests := []struct {
+		name string
+		kind string
+		want []string
+	}{
+		{name: "test joinRows of INNER JOIN", kind: sqlparser.JoinInner, want: []string{"10 a", "12 b"}},
+		{name: "test joinRows of LEFT JOIN", kind: sqlparser.JoinLeft, want: []string{"10 a", "11 -", "12 b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &joinStep{
+				join:   sqlparser.Join{Kind: tt.kind, TableName: "users", Alias: "u"},
+				left:   []string{"o.userId"},
+				right:  []string{"id"},
+				method: methodScan,
+				hashed: map[string][]map[string]*dynamodb.AttributeValue{},
+			}
+			step.index(users, step.hashed)
+			joined, err := step.joinRows(rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, row := range joined {
+				name := "-"
+				if v := valueAt(row, "u.name"); v != nil {
+					name = *v.S
+				}
+				got = append(got, *valueAt(row, "o.id").N+" "+name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("joinRows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
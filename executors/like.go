@@ -0,0 +1,213 @@
+package executors
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// How DynamoDB can evaluate a LIKE pattern
+const (
+	likeEqual      = "equal"       // no wildcard, like 'abc'
+	likeBeginsWith = "begins_with" // like 'abc%'
+	likeContains   = "contains"    // like '%abc%'
+	likeClientSide = "client-side" // anything else is matched on the items read
+)
+
+// likeToken is a character of a LIKE pattern, % and _ are wildcards unless escaped by \
+type likeToken struct {
+	char     rune
+	wildcard bool
+}
+
+func likeTokens(pattern string) []likeToken {
+	tokens := []likeToken{}
+	escaped := false
+	for _, r := range pattern {
+		if escaped {
+			tokens = append(tokens, likeToken{char: r})
+			escaped = false
+		} else if r == '\\' {
+			escaped = true
+		} else {
+			tokens = append(tokens, likeToken{char: r, wildcard: r == '%' || r == '_'})
+		}
+	}
+	if escaped {
+		tokens = append(tokens, likeToken{char: '\\'})
+	}
+	return tokens
+}
+
+// literalOf returns the text of tokens, ok is false if there is a wildcard
+func literalOf(tokens []likeToken) (string, bool) {
+	var b strings.Builder
+	for _, t := range tokens {
+		if t.wildcard {
+			return "", false
+		}
+		b.WriteRune(t.char)
+	}
+	return b.String(), true
+}
+
+// parseLike returns how DynamoDB can evaluate a LIKE pattern and the literal to compare with,
+// the literal of a client-side pattern is what comes before the first wildcard
+func parseLike(pattern string) (string, string) {
+	tokens := likeTokens(pattern)
+	n := len(tokens)
+	if literal, ok := literalOf(tokens); ok {
+		return likeEqual, literal
+	}
+	if n > 1 && tokens[n-1].char == '%' && tokens[n-1].wildcard {
+		if literal, ok := literalOf(tokens[:n-1]); ok {
+			return likeBeginsWith, literal
+		}
+	}
+	if n > 2 && tokens[0].char == '%' && tokens[0].wildcard && tokens[n-1].char == '%' && tokens[n-1].wildcard {
+		if literal, ok := literalOf(tokens[1 : n-1]); ok {
+			return likeContains, literal
+		}
+	}
+	prefix := ""
+	for idx, t := range tokens {
+		if t.wildcard {
+			prefix, _ = literalOf(tokens[:idx])
+			break
+		}
+	}
+	return likeClientSide, prefix
+}
+
+// likeRegexpOf translates a LIKE pattern to a regular expression matching the whole text
+func likeRegexpOf(pattern string, ignoreCase bool) *regexp.Regexp {
+	var b strings.Builder
+	if ignoreCase {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("(?s)^")
+	for _, t := range likeTokens(pattern) {
+		switch {
+		case t.wildcard && t.char == '%':
+			b.WriteString(".*")
+		case t.wildcard:
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(t.char)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// likeExpression is the condition of LIKE, ILIKE or REGEXP,
+// a pattern DynamoDB can't evaluate gives a broader condition and is matched again by clientMatcher
+func likeExpression(condition sqlparser.Condition) expression.ConditionBuilder {
+	name := pathName(condition.Key)
+	if condition.Operator != sqlparser.OpLike {
+		return name.AttributeExists()
+	}
+	kind, literal := parseLike(stringArgument(condition.Value))
+	switch {
+	case kind == likeEqual:
+		return name.Equal(expression.Value(literal))
+	case kind == likeContains:
+		return name.Contains(literal)
+	case literal != "":
+		return name.BeginsWith(literal)
+	}
+	return name.AttributeExists()
+}
+
+// inFilterSize is the most values DynamoDB takes in IN of a filter, longer lists are matched client-side
+const inFilterSize = 100
+
+// isClientSide tells whether a condition is matched on the items read instead of by DynamoDB
+func isClientSide(condition sqlparser.Condition) bool {
+	switch condition.Operator {
+	case sqlparser.OpILike, sqlparser.OpRegexp:
+		return true
+	case sqlparser.OpIn:
+		_, rows := condition.InList()
+		return len(rows) > inFilterSize
+	case sqlparser.OpLike:
+		kind, _ := parseLike(stringArgument(condition.Value))
+		return kind == likeClientSide
+	}
+	return false
+}
+
+// clientSideConditions returns the conditions which are matched on the items read
+func clientSideConditions(conditions []sqlparser.Condition) []sqlparser.Condition {
+	clientSide := []sqlparser.Condition{}
+	for _, c := range conditions {
+		if isClientSide(c) {
+			clientSide = append(clientSide, c)
+		}
+	}
+	return clientSide
+}
+
+// conditionAttributes are the attributes a condition matches, several for IN of a tuple
+func conditionAttributes(condition sqlparser.Condition) []string {
+	if condition.Operator == sqlparser.OpIn {
+		return condition.InAttributes()
+	}
+	return []string{condition.Key}
+}
+
+// clientMatcher returns a function telling whether an item matches a client-side condition,
+// strings are matched as they are and numbers by their digits, other types never match,
+// IN matches values as JOIN does
+func clientMatcher(condition sqlparser.Condition) (func(map[string]*dynamodb.AttributeValue) bool, error) {
+	if condition.Operator == sqlparser.OpIn {
+		attributes, rows := condition.InList()
+		listed := map[string]bool{}
+		for _, row := range rows {
+			values := []string{}
+			for _, v := range row {
+				values = append(values, joinValue(literalValue(v)))
+			}
+			listed[strings.Join(values, "\x00")] = true
+		}
+		return func(item map[string]*dynamodb.AttributeValue) bool {
+			key, ok := joinKey(item, attributes)
+			return ok && listed[key]
+		}, nil
+	}
+	match, err := patternMatcher(condition)
+	if err != nil {
+		return nil, err
+	}
+	return func(item map[string]*dynamodb.AttributeValue) bool {
+		return match(valueAt(item, condition.Key))
+	}, nil
+}
+
+// patternMatcher returns a function telling whether a value matches the pattern of LIKE, ILIKE or REGEXP
+func patternMatcher(condition sqlparser.Condition) (func(*dynamodb.AttributeValue) bool, error) {
+	pattern := stringArgument(condition.Value)
+	var re *regexp.Regexp
+	if condition.Operator == sqlparser.OpRegexp {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("Invalid REGEXP %s, %v", condition.Value, err)
+		}
+	} else {
+		re = likeRegexpOf(pattern, condition.Operator == sqlparser.OpILike)
+	}
+	return func(av *dynamodb.AttributeValue) bool {
+		if av == nil {
+			return false
+		} else if av.S != nil {
+			return re.MatchString(*av.S)
+		} else if av.N != nil {
+			return re.MatchString(*av.N)
+		}
+		return false
+	}, nil
+}
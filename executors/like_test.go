@@ -0,0 +1,107 @@
+package executors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func Test_parseLike(t *testing.T) {
+	tests := []struct {
+		name        string
+		pattern     string
+		wantKind    string
+		wantLiteral string
+	}{
+		{name: "test parseLike without wildcards", pattern: "Jack", wantKind: likeEqual, wantLiteral: "Jack"},
+		{name: "test parseLike with a prefix", pattern: "Ja%", wantKind: likeBeginsWith, wantLiteral: "Ja"},
+		{name: "test parseLike with a substring", pattern: "%ac%", wantKind: likeContains, wantLiteral: "ac"},
+		{name: "test parseLike with escaped wildcards", pattern: `100\%\_%`, wantKind: likeBeginsWith, wantLiteral: "100%_"},
+		{name: "test parseLike with a suffix", pattern: "%ck", wantKind: likeClientSide, wantLiteral: ""},
+		{name: "test parseLike with _", pattern: "J_ck%", wantKind: likeClientSide, wantLiteral: "J"},
+		{name: "test parseLike with %", pattern: "%", wantKind: likeClientSide, wantLiteral: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kind, literal := parseLike(tt.pattern); kind != tt.wantKind || literal != tt.wantLiteral {
+				t.Errorf("parseLike() = %v, %v, want %v, %v", kind, literal, tt.wantKind, tt.wantLiteral)
+			}
+		})
+	}
+}
+
+func Test_likeExpression(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition sqlparser.Condition
+		want      expression.ConditionBuilder
+	}{
+		{
+			name:      "test likeExpression with a prefix",
+			condition: sqlparser.Condition{Key: "name", Operator: sqlparser.OpLike, Value: "'Ja%'"},
+			want:      expression.Name("name").BeginsWith("Ja"),
+		},
+		{
+			name:      "test likeExpression with a substring",
+			condition: sqlparser.Condition{Key: "name", Operator: sqlparser.OpLike, Value: "'%ac%'"},
+			want:      expression.Name("name").Contains("ac"),
+		},
+		{
+			name:      "test likeExpression narrows a client-side pattern by its prefix",
+			condition: sqlparser.Condition{Key: "name", Operator: sqlparser.OpLike, Value: "'J_ck'"},
+			want:      expression.Name("name").BeginsWith("J"),
+		},
+		{
+			name:      "test likeExpression with ILIKE",
+			condition: sqlparser.Condition{Key: "name", Operator: sqlparser.OpILike, Value: "'ja%'"},
+			want:      expression.Name("name").AttributeExists(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := likeExpression(tt.condition); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("likeExpression() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_clientMatcher(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{
+		"name":  {S: aws.String("Jack\nJones")},
+		"coins": {N: aws.String("1024")},
+		"tags":  {SS: aws.StringSlice([]string{"Jack"})},
+	}
+	tests := []struct {
+		name      string
+		condition sqlparser.Condition
+		want      bool
+	}{
+		{name: "test clientMatcher with a suffix", condition: sqlparser.Condition{Key: "name", Operator: sqlparser.OpLike, Value: "'%Jones'"}, want: true},
+		{name: "test clientMatcher with _", condition: sqlparser.Condition{Key: "name", Operator: sqlparser.OpLike, Value: "'J_ck_Jones'"}, want: true},
+		{name: "test clientMatcher is case sensitive", condition: sqlparser.Condition{Key: "name", Operator: sqlparser.OpLike, Value: "'%jones'"}, want: false},
+		{name: "test clientMatcher with ILIKE", condition: sqlparser.Condition{Key: "name", Operator: sqlparser.OpILike, Value: "'%jones'"}, want: true},
+		{name: "test clientMatcher with REGEXP", condition: sqlparser.Condition{Key: "name", Operator: sqlparser.OpRegexp, Value: "'^Ja(ck|ne)'"}, want: true},
+		{name: "test clientMatcher with a number", condition: sqlparser.Condition{Key: "coins", Operator: sqlparser.OpLike, Value: "'%24'"}, want: true},
+		{name: "test clientMatcher with a set", condition: sqlparser.Condition{Key: "tags", Operator: sqlparser.OpLike, Value: "'%'"}, want: false},
+		{name: "test clientMatcher with a missing attribute", condition: sqlparser.Condition{Key: "email", Operator: sqlparser.OpLike, Value: "'%'"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := clientMatcher(tt.condition)
+			if err != nil {
+				t.Fatalf("clientMatcher() error = %v", err)
+			}
+			if got := match(item); got != tt.want {
+				t.Errorf("clientMatcher() matched %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := clientMatcher(sqlparser.Condition{Key: "name", Operator: sqlparser.OpRegexp, Value: "'(ab'"}); err == nil {
+		t.Errorf("clientMatcher() of an invalid REGEXP should fail")
+	}
+}
@@ -0,0 +1,80 @@
+package executors
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// SortLimit is the most items ORDER BY sorts client-side, a statement matching more is refused
+// rather than read into memory
+var SortLimit int64 = 10000
+
+// typeRank orders values of different types, numbers first and missing values last
+func typeRank(av *dynamodb.AttributeValue) int {
+	switch {
+	case av == nil:
+		return 6
+	case av.N != nil:
+		return 0
+	case av.S != nil:
+		return 1
+	case av.B != nil:
+		return 2
+	case av.BOOL != nil:
+		return 3
+	case av.NULL != nil:
+		return 5
+	}
+	return 4
+}
+
+// compareValues compares two values as ORDER BY does, numbers by value, strings and binaries byte by byte,
+// false before true, maps, lists and sets only by their type
+func compareValues(a, b *dynamodb.AttributeValue) int {
+	if ra, rb := typeRank(a), typeRank(b); ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case a == nil:
+		return 0
+	case a.N != nil:
+		x, okX := new(big.Float).SetPrec(128).SetString(*a.N)
+		y, okY := new(big.Float).SetPrec(128).SetString(*b.N)
+		if okX && okY {
+			return x.Cmp(y)
+		}
+		return strings.Compare(*a.N, *b.N)
+	case a.S != nil:
+		return strings.Compare(*a.S, *b.S)
+	case a.B != nil:
+		return bytes.Compare(a.B, b.B)
+	case a.BOOL != nil && *a.BOOL != *b.BOOL:
+		if *a.BOOL {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
+
+// sortItems sorts items by the attributes of ORDER BY, items equal on all of them keep their order,
+// valueOf returns the value of an attribute, valueAt for paths
+func sortItems(items []map[string]*dynamodb.AttributeValue, orderBy []sqlparser.OrderBy,
+	valueOf func(map[string]*dynamodb.AttributeValue, string) *dynamodb.AttributeValue) {
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, o := range orderBy {
+			if c := compareValues(valueOf(items[i], o.Key), valueOf(items[j], o.Key)); c != 0 {
+				return (c < 0) != o.Desc
+			}
+		}
+		return false
+	})
+}
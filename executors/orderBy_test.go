@@ -0,0 +1,68 @@
+package executors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func Test_compareValues(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *dynamodb.AttributeValue
+		b    *dynamodb.AttributeValue
+		want int
+	}{
+		{name: "test compareValues of numbers by value", a: &dynamodb.AttributeValue{N: aws.String("9")}, b: &dynamodb.AttributeValue{N: aws.String("10")}, want: -1},
+		{name: "test compareValues of decimals", a: &dynamodb.AttributeValue{N: aws.String("1.50")}, b: &dynamodb.AttributeValue{N: aws.String("1.5")}, want: 0},
+		{name: "test compareValues of strings", a: &dynamodb.AttributeValue{S: aws.String("b")}, b: &dynamodb.AttributeValue{S: aws.String("a")}, want: 1},
+		{name: "test compareValues of a number and a string", a: &dynamodb.AttributeValue{S: aws.String("1")}, b: &dynamodb.AttributeValue{N: aws.String("2")}, want: 1},
+		{name: "test compareValues of a missing value", a: nil, b: &dynamodb.AttributeValue{NULL: aws.Bool(true)}, want: 1},
+		{name: "test compareValues of booleans", a: &dynamodb.AttributeValue{BOOL: aws.Bool(false)}, b: &dynamodb.AttributeValue{BOOL: aws.Bool(true)}, want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareValues(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sortItems(t *testing.T) {
+	item := func(id, country string, coins string) map[string]*dynamodb.AttributeValue {
+		item := map[string]*dynamodb.AttributeValue{"user_id": {N: aws.String(id)}, "country": {S: aws.String(country)}}
+		if coins != "" {
+			item["coins"] = &dynamodb.AttributeValue{N: aws.String(coins)}
+		}
+		return item
+	}
+	ids := func(items []map[string]*dynamodb.AttributeValue) []string {
+		ids := []string{}
+		for _, i := range items {
+			ids = append(ids, *i["user_id"].N)
+		}
+		return ids
+	}
+	tests := []struct {
+		name    string
+		orderBy []sqlparser.OrderBy
+		want    []string
+	}{
+		{name: "test sortItems ascending", orderBy: []sqlparser.OrderBy{{Key: "coins"}}, want: []string{"4", "2", "1", "3"}},
+		{name: "test sortItems descending", orderBy: []sqlparser.OrderBy{{Key: "coins", Desc: true}}, want: []string{"3", "1", "2", "4"}},
+		{name: "test sortItems by two attributes", orderBy: []sqlparser.OrderBy{{Key: "country"}, {Key: "coins", Desc: true}}, want: []string{"3", "1", "2", "4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := []map[string]*dynamodb.AttributeValue{item("1", "AU", "100"), item("2", "NZ", "20"), item("3", "AU", ""), item("4", "NZ", "9")}
+			sortItems(items, tt.orderBy, valueAt)
+			if got := ids(items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortItems() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
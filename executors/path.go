@@ -0,0 +1,185 @@
+package executors
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// escapedNamePrefix starts the placeholder of a name expression.Name would split, like one with a dot,
+// expressionNames puts the name back
+const escapedNamePrefix = "__escaped_"
+
+// pathName is the name builder of a document path like address.city, items[0] or `first.name`
+func pathName(path string) expression.NameBuilder {
+	return expression.Name(escapedPath(path))
+}
+
+// escapedPath is path as expression.Name reads it, with the names it would split escaped
+func escapedPath(path string) string {
+	elements, err := sqlparser.ParsePath(path)
+	if err != nil {
+		// the expression builder reports it
+		return path
+	}
+	var b strings.Builder
+	for idx, e := range elements {
+		if e.Name == "" {
+			fmt.Fprintf(&b, "[%d]", e.Index)
+			continue
+		}
+		if idx > 0 {
+			b.WriteString(".")
+		}
+		if strings.ContainsAny(e.Name, ".[]") || strings.HasPrefix(e.Name, escapedNamePrefix) {
+			b.WriteString(escapedNamePrefix + hex.EncodeToString([]byte(e.Name)))
+		} else {
+			b.WriteString(e.Name)
+		}
+	}
+	return b.String()
+}
+
+// expressionNames returns the ExpressionAttributeNames of expr with the names escaped by pathName put back
+func expressionNames(expr expression.Expression) map[string]*string {
+	names := expr.Names()
+	for placeholder, name := range names {
+		if strings.HasPrefix(*name, escapedNamePrefix) {
+			if unescaped, err := hex.DecodeString(strings.TrimPrefix(*name, escapedNamePrefix)); err == nil {
+				names[placeholder] = aws.String(string(unescaped))
+			}
+		}
+	}
+	return names
+}
+
+// valueAt returns the value of an item at a document path, nil if there is none
+func valueAt(item map[string]*dynamodb.AttributeValue, path string) *dynamodb.AttributeValue {
+	elements, err := sqlparser.ParsePath(path)
+	if err != nil {
+		return nil
+	}
+	value := &dynamodb.AttributeValue{M: item}
+	for _, e := range elements {
+		if e.Name != "" && value.M != nil {
+			value = value.M[e.Name]
+		} else if e.Name == "" && e.Index < len(value.L) {
+			value = value.L[e.Index]
+		} else {
+			return nil
+		}
+		if value == nil {
+			return nil
+		}
+	}
+	return value
+}
+
+// projectValue keeps the parts of value at the paths as DynamoDB projects them,
+// maps keep the projected keys and lists the projected elements in the order of their indexes
+func projectValue(value *dynamodb.AttributeValue, paths [][]sqlparser.PathElement) *dynamodb.AttributeValue {
+	byName := map[string][][]sqlparser.PathElement{}
+	byIndex := map[int][][]sqlparser.PathElement{}
+	for _, path := range paths {
+		if len(path) == 0 {
+			return value
+		}
+		if path[0].Name != "" {
+			byName[path[0].Name] = append(byName[path[0].Name], path[1:])
+		} else {
+			byIndex[path[0].Index] = append(byIndex[path[0].Index], path[1:])
+		}
+	}
+	if value.M != nil && len(byName) > 0 {
+		projected := map[string]*dynamodb.AttributeValue{}
+		for name, rest := range byName {
+			if v, ok := value.M[name]; ok {
+				if v = projectValue(v, rest); v != nil {
+					projected[name] = v
+				}
+			}
+		}
+		if len(projected) > 0 {
+			return &dynamodb.AttributeValue{M: projected}
+		}
+	} else if value.L != nil && len(byIndex) > 0 {
+		indexes := []int{}
+		for index := range byIndex {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		projected := []*dynamodb.AttributeValue{}
+		for _, index := range indexes {
+			if index < len(value.L) {
+				if v := projectValue(value.L[index], byIndex[index]); v != nil {
+					projected = append(projected, v)
+				}
+			}
+		}
+		if len(projected) > 0 {
+			return &dynamodb.AttributeValue{L: projected}
+		}
+	}
+	return nil
+}
+
+// attributeName is the name builder of a top-level attribute, which may contain dots or brackets
+func attributeName(name string) expression.NameBuilder {
+	return pathName(quotedName(name))
+}
+
+// keyName is the key builder of a key attribute, escaped like attributeName so expressionNames puts it back
+func keyName(name string) expression.KeyBuilder {
+	return expression.Key(escapedPath(quotedName(name)))
+}
+
+// quotedName is the path of a top-level attribute named name
+func quotedName(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// withValueAt returns value with v put at a document path, or the path removed when v is nil,
+// the maps and lists along the path are copied and the rest is shared with value
+func withValueAt(value *dynamodb.AttributeValue, path []sqlparser.PathElement, v *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if len(path) == 0 {
+		return v
+	}
+	e := path[0]
+	if e.Name != "" {
+		if value == nil || value.M == nil {
+			return value
+		}
+		m := map[string]*dynamodb.AttributeValue{}
+		for name, element := range value.M {
+			m[name] = element
+		}
+		if next := withValueAt(m[e.Name], path[1:], v); next != nil {
+			m[e.Name] = next
+		} else if len(path) == 1 {
+			delete(m, e.Name)
+		}
+		return &dynamodb.AttributeValue{M: m}
+	}
+	if value == nil || value.L == nil {
+		return value
+	}
+	l := append([]*dynamodb.AttributeValue{}, value.L...)
+	switch {
+	case len(path) == 1 && v == nil:
+		if e.Index < len(l) {
+			l = append(l[:e.Index], l[e.Index+1:]...)
+		}
+	case e.Index < len(l):
+		l[e.Index] = withValueAt(l[e.Index], path[1:], v)
+	case len(path) == 1:
+		// DynamoDB appends an element set past the end of a list
+		l = append(l, v)
+	}
+	return &dynamodb.AttributeValue{L: l}
+}
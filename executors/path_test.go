@@ -0,0 +1,93 @@
+package executors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func Test_pathName(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		wantNames map[string]*string
+		wantExpr  string
+	}{
+		{
+			name:      "test pathName with a nested path",
+			path:      "items[1].sku",
+			wantNames: map[string]*string{"#0": aws.String("items"), "#1": aws.String("sku")},
+			wantExpr:  "#0[1].#1",
+		},
+		{
+			name:      "test pathName with a name containing a dot",
+			path:      "`first.name`.`a[0]`",
+			wantNames: map[string]*string{"#0": aws.String("first.name"), "#1": aws.String("a[0]")},
+			wantExpr:  "#0.#1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := expression.NewBuilder().WithProjection(expression.NamesList(pathName(tt.path))).Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if got := expressionNames(expr); !reflect.DeepEqual(got, tt.wantNames) || *expr.Projection() != tt.wantExpr {
+				t.Errorf("pathName() = %v %v, want %v %v", *expr.Projection(), got, tt.wantExpr, tt.wantNames)
+			}
+		})
+	}
+}
+
+func Test_keyName(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		wantNames map[string]*string
+	}{
+		{name: "test keyName with a plain name", key: "user_id", wantNames: map[string]*string{"#0": aws.String("user_id")}},
+		{name: "test keyName with a name containing a dot", key: "user.id", wantNames: map[string]*string{"#0": aws.String("user.id")}},
+		{name: "test keyName with a name like an escaped one", key: escapedNamePrefix + "6964", wantNames: map[string]*string{"#0": aws.String(escapedNamePrefix + "6964")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := expression.NewBuilder().WithKeyCondition(keyName(tt.key).Equal(expression.Value(1))).Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if got := expressionNames(expr); !reflect.DeepEqual(got, tt.wantNames) || *expr.KeyCondition() != "#0 = :0" {
+				t.Errorf("keyName() = %v %v, want %v", *expr.KeyCondition(), got, tt.wantNames)
+			}
+		})
+	}
+}
+
+func Test_valueAt(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{
+		"address": {M: map[string]*dynamodb.AttributeValue{"city": {S: aws.String("Auckland")}}},
+		"items":   {L: []*dynamodb.AttributeValue{{M: map[string]*dynamodb.AttributeValue{"sku": {S: aws.String("a1")}}}}},
+		"a.b":     {N: aws.String("1")},
+	}
+	tests := []struct {
+		name string
+		path string
+		want *dynamodb.AttributeValue
+	}{
+		{name: "test valueAt with a map", path: "address.city", want: &dynamodb.AttributeValue{S: aws.String("Auckland")}},
+		{name: "test valueAt with a list", path: "items[0].sku", want: &dynamodb.AttributeValue{S: aws.String("a1")}},
+		{name: "test valueAt with an escaped name", path: "`a.b`", want: &dynamodb.AttributeValue{N: aws.String("1")}},
+		{name: "test valueAt out of the list", path: "items[1].sku"},
+		{name: "test valueAt indexing a map", path: "address[0]"},
+		{name: "test valueAt with a missing attribute", path: "a.b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := valueAt(item, tt.path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("valueAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,614 @@
+package executors
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/FrontMage/dynamo.cli/utils"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var numberRegexp = regexp.MustCompile(`^-?\d+(\.\d+)?([eE][-+]?\d+)?$`)
+
+// projectionArities are the functions of computed columns with their least and most number of arguments
+var projectionArities = map[string][2]int{
+	"+":      {2, 2},
+	"-":      {2, 2},
+	"*":      {2, 2},
+	"/":      {2, 2},
+	"upper":  {1, 1},
+	"lower":  {1, 1},
+	"substr": {2, 3},
+	"size":   {1, 1},
+}
+
+// column is a column of SELECT, name is how it's named in the rows, its alias or the expression as written,
+// a plain column is an attribute path without alias, projected as it is
+type column struct {
+	text    string
+	name    string
+	plain   bool
+	compute func(map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue
+}
+
+// computation is how the rows of a select statement with DISTINCT, aliases or computed columns are made of the items read,
+// needed are the attributes the columns are computed of, orderBy is set if rows are sorted by a computed column
+type computation struct {
+	columns  []column
+	needed   []string
+	distinct bool
+	orderBy  []sqlparser.OrderBy
+}
+
+// isComputed tells whether the rows of a select statement are computed client-side rather than projected by DynamoDB
+func isComputed(stmt sqlparser.SelectStatement) bool {
+	if stmt.Distinct {
+		return true
+	}
+	for _, a := range stmt.AttributesToGet {
+		if a == "*" {
+			continue
+		}
+		if expression, alias := sqlparser.ParseColumn(a); alias != "" || sqlparser.ParseProjection(expression).Path == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// planComputation compiles the columns of SELECT, ORDER BY a computed column must only use selected columns
+func planComputation(stmt sqlparser.SelectStatement) (computation, error) {
+	comp := computation{distinct: stmt.Distinct, needed: []string{}}
+	for _, a := range stmt.AttributesToGet {
+		if a == "*" {
+			comp.columns = append(comp.columns, column{text: a, name: a, plain: true})
+			comp.needed = append(comp.needed, a)
+			continue
+		}
+		expression, alias := sqlparser.ParseColumn(a)
+		operand := sqlparser.ParseProjection(expression)
+		compute, err := compileOperand(operand, &comp.needed)
+		if err != nil {
+			return comp, fmt.Errorf("Can't compute %s, %v", expression, err)
+		}
+		c := column{text: a, name: alias, plain: alias == "" && operand.Path != "", compute: compute}
+		if c.name == "" {
+			c.name = expression
+		}
+		comp.columns = append(comp.columns, c)
+	}
+	if utils.FindIndex(comp.needed, "*") != -1 || len(comp.needed) == 0 {
+		comp.needed = []string{"*"}
+	}
+	for _, o := range stmt.OrderBy {
+		for _, c := range comp.columns {
+			if c.name == o.Key && !c.plain {
+				comp.orderBy = stmt.OrderBy
+			}
+		}
+	}
+	for _, o := range comp.orderBy {
+		selected := false
+		for _, c := range comp.columns {
+			selected = selected || c.name == o.Key || c.name == "*"
+		}
+		if !selected {
+			return comp, fmt.Errorf("ORDER BY %s must be selected to sort by computed columns", o.Key)
+		}
+	}
+	return comp, nil
+}
+
+// read is the statement reading the items, of the attributes needed and sorted by DynamoDB or planSelect
+// unless rows are sorted by a computed column, then up to SortLimit of them are read and OFFSET applies to the rows
+func (comp computation) read(stmt sqlparser.SelectStatement) sqlparser.SelectStatement {
+	read := stmt
+	read.AttributesToGet = comp.needed
+	read.Distinct = false
+	if len(comp.orderBy) > 0 {
+		read.OrderBy = nil
+		read.Limit = SortLimit + 1
+		read.Offset = 0
+	}
+	return read
+}
+
+// row computes the columns of an item, plain columns keep their sub-documents as a projection does,
+// computed values are named by their column and left out if they are missing
+func (comp computation) row(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	plain := []string{}
+	for _, c := range comp.columns {
+		if c.plain {
+			plain = append(plain, c.name)
+		}
+	}
+	row := map[string]*dynamodb.AttributeValue{}
+	if utils.FindIndex(plain, "*") != -1 {
+		row = projectItem(item, []string{"*"})
+	} else if len(plain) > 0 {
+		row = projectItem(item, plain)
+	}
+	for _, c := range comp.columns {
+		if c.plain {
+			continue
+		}
+		if v := c.compute(item); v != nil {
+			row[c.name] = v
+		}
+	}
+	return row
+}
+
+// rowValue is the value of a row by a column name, or by a path for plain columns
+func (comp computation) rowValue(row map[string]*dynamodb.AttributeValue, key string) *dynamodb.AttributeValue {
+	for _, c := range comp.columns {
+		if c.name == key && !c.plain {
+			return row[key]
+		}
+	}
+	return valueAt(row, key)
+}
+
+// computeItems reads the items of a select statement with DISTINCT, aliases or computed columns and returns their rows,
+// only the attributes needed are read and the rows are computed client-side, DISTINCT drops rows already seen.
+// resumeKey is as selectItemsWith returns it, nil with DISTINCT which would not know the rows seen before and when rows are sorted
+func computeItems(stmt sqlparser.SelectStatement) ([]map[string]*dynamodb.AttributeValue, bool, map[string]*dynamodb.AttributeValue, error) {
+	comp, err := planComputation(stmt)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	var keep func(map[string]*dynamodb.AttributeValue) bool
+	if comp.distinct {
+		seen := map[string]bool{}
+		keep = func(item map[string]*dynamodb.AttributeValue) bool {
+			id := utils.FormatKey(comp.row(item))
+			if seen[id] {
+				return false
+			}
+			seen[id] = true
+			return true
+		}
+	}
+	if len(comp.orderBy) > 0 {
+		Warn(fmt.Sprintf("ORDER BY %s is sorted client-side, every matching item is read first", orderByText(comp.orderBy)))
+	}
+	items, isSingleItem, resumeKey, err := selectItemsWith(comp.read(stmt), keep)
+	if err != nil {
+		return nil, isSingleItem, nil, err
+	}
+	if comp.distinct || len(comp.orderBy) > 0 {
+		resumeKey = nil
+	}
+	rows := []map[string]*dynamodb.AttributeValue{}
+	for _, item := range items {
+		rows = append(rows, comp.row(item))
+	}
+	if len(comp.orderBy) > 0 {
+		if int64(len(rows)) > SortLimit {
+			return nil, isSingleItem, nil, fmt.Errorf("More than %d items to sort client-side, narrow WHERE or raise sort_limit", SortLimit)
+		}
+		sortItems(rows, comp.orderBy, comp.rowValue)
+		rows = skipItems(rows, stmt.Offset)
+		if stmt.Limit >= 0 && int64(len(rows)) > stmt.Limit {
+			rows = rows[:stmt.Limit]
+		}
+	}
+	return rows, isSingleItem, resumeKey, nil
+}
+
+// explain describes how the rows are computed for EXPLAIN
+func (comp computation) explain() string {
+	lines := []string{"  projection: " + strings.Join(comp.needed, ", ")}
+	computed := []string{}
+	for _, c := range comp.columns {
+		if !c.plain {
+			computed = append(computed, c.text)
+		}
+	}
+	if len(computed) > 0 {
+		lines = append(lines, "  computed client-side: "+strings.Join(computed, ", "))
+	}
+	if comp.distinct {
+		lines = append(lines, "  distinct: rows already seen are dropped client-side")
+	}
+	if len(comp.orderBy) > 0 {
+		lines = append(lines, fmt.Sprintf("  order: %s, sorted client-side, up to %d items", orderByText(comp.orderBy), SortLimit))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// orderByText is ORDER BY as it's written
+func orderByText(orderBy []sqlparser.OrderBy) string {
+	texts := []string{}
+	for _, o := range orderBy {
+		texts = append(texts, o.String())
+	}
+	return strings.Join(texts, ", ")
+}
+
+// projectionLiteral converts a literal of a computed column, numbers with decimals are numbers as well,
+// ok is false if s is not a quoted string, a number, a list or a set
+func projectionLiteral(s string) (*dynamodb.AttributeValue, bool) {
+	s = strings.TrimSpace(s)
+	switch {
+	case numberRegexp.MatchString(s):
+		return &dynamodb.AttributeValue{N: aws.String(s)}, true
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return &dynamodb.AttributeValue{S: aws.String(s[1 : len(s)-1])}, true
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'',
+		strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]"),
+		strings.HasPrefix(s, "<<") && strings.HasSuffix(s, ">>"):
+		return literalValue(s), true
+	}
+	return nil, false
+}
+
+// compileOperand returns a function computing an operand of an item, nil if it can't be computed,
+// like a missing attribute or upper of a number, the attributes it reads are added to paths
+func compileOperand(operand sqlparser.Operand, paths *[]string) (func(map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue, error) {
+	switch {
+	case operand.Path != "":
+		if _, err := sqlparser.ParsePath(operand.Path); err != nil {
+			return nil, err
+		}
+		if utils.FindIndex(*paths, operand.Path) == -1 {
+			*paths = append(*paths, operand.Path)
+		}
+		return func(item map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+			return valueAt(item, operand.Path)
+		}, nil
+	case operand.Function == "":
+		value, ok := projectionLiteral(operand.Value)
+		if !ok {
+			return nil, fmt.Errorf("%s is neither an attribute nor a literal, quote strings like 'text'", operand.Value)
+		}
+		return func(map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+			return value
+		}, nil
+	case operand.Function == sqlparser.FunctionCase:
+		return compileCase(operand, paths)
+	}
+	arity, ok := projectionArities[operand.Function]
+	if !ok {
+		return nil, fmt.Errorf("Unknown function %s, supports +, -, *, /, upper, lower, substr, size and CASE WHEN", operand.Function)
+	}
+	if len(operand.Args) < arity[0] || len(operand.Args) > arity[1] {
+		return nil, fmt.Errorf("Wrong number of arguments of %s", operand.Function)
+	}
+	args := []func(map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue{}
+	for _, a := range operand.Args {
+		arg, err := compileOperand(a, paths)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return func(item map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+		values := []*dynamodb.AttributeValue{}
+		for _, arg := range args {
+			values = append(values, arg(item))
+		}
+		return applyFunction(operand.Function, values)
+	}, nil
+}
+
+// compileCase returns a function computing a CASE WHEN, the value of the first WHEN matched,
+// otherwise the ELSE value, nil without ELSE
+func compileCase(operand sqlparser.Operand, paths *[]string) (func(map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue, error) {
+	if len(operand.When) == 0 {
+		return nil, errors.New("CASE takes WHEN conditions THEN values, an ELSE value and END, like CASE WHEN coins > 100 THEN 'rich' ELSE 'poor' END")
+	}
+	whens := [][]func(map[string]*dynamodb.AttributeValue) bool{}
+	for _, conditions := range operand.When {
+		matchers := []func(map[string]*dynamodb.AttributeValue) bool{}
+		for _, c := range conditions {
+			match, err := compileCondition(c, paths)
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, match)
+		}
+		whens = append(whens, matchers)
+	}
+	values := []func(map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue{}
+	for _, a := range operand.Args {
+		value, err := compileOperand(a, paths)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return func(item map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+		for idx, matchers := range whens {
+			matched := true
+			for _, match := range matchers {
+				matched = matched && match(item)
+			}
+			if matched {
+				return values[idx](item)
+			}
+		}
+		if len(values) > len(whens) {
+			return values[len(values)-1](item)
+		}
+		return nil
+	}, nil
+}
+
+// conditionValue converts the value of a WHEN condition, a bare word is a string as it's in WHERE
+func conditionValue(s string) *dynamodb.AttributeValue {
+	if v, ok := projectionLiteral(s); ok {
+		return v
+	}
+	return literalValue(s)
+}
+
+// compileCondition returns a function telling whether an item matches a condition of WHEN,
+// it takes what WHERE takes except IN of several attributes, a missing value only matches IS MISSING and attribute_not_exists
+func compileCondition(c sqlparser.Condition, paths *[]string) (func(map[string]*dynamodb.AttributeValue) bool, error) {
+	if c.Key == "" {
+		return nil, errors.New("Invalid WHEN condition, it takes what WHERE takes, like WHEN coins > 100")
+	}
+	key := sqlparser.ParseProjection(c.Key)
+	if c.Operator == sqlparser.OpIn {
+		attributes, _ := c.InList()
+		if len(attributes) != 1 {
+			return nil, fmt.Errorf("WHEN takes IN of a single attribute, not %s", c)
+		}
+		key = sqlparser.ParseProjection(attributes[0])
+	}
+	valueOf, err := compileOperand(key, paths)
+	if err != nil {
+		return nil, err
+	}
+	if c.Function == sqlparser.FunctionSize {
+		sizeOf := valueOf
+		valueOf = func(item map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+			return applyFunction(sqlparser.FunctionSize, []*dynamodb.AttributeValue{sizeOf(item)})
+		}
+	}
+	var match func(*dynamodb.AttributeValue) bool
+	switch c.Operator {
+	case "=", "!=", "<", "<=", ">", ">=":
+		value := conditionValue(c.Value)
+		match = func(v *dynamodb.AttributeValue) bool {
+			switch {
+			case v == nil:
+				return false
+			case c.Operator == "=":
+				return sameValue(v, value)
+			case c.Operator == "!=":
+				return !sameValue(v, value)
+			case !sameType(v, value):
+				return false
+			}
+			compared := compareValues(v, value)
+			switch c.Operator {
+			case "<":
+				return compared < 0
+			case "<=":
+				return compared <= 0
+			case ">":
+				return compared > 0
+			}
+			return compared >= 0
+		}
+	case sqlparser.OpBetween:
+		low, high := conditionValue(c.Value), conditionValue(c.High)
+		match = func(v *dynamodb.AttributeValue) bool {
+			return v != nil && sameType(v, low) && compareValues(v, low) >= 0 && compareValues(v, high) <= 0
+		}
+	case sqlparser.OpIn:
+		_, rows := c.InList()
+		values := []*dynamodb.AttributeValue{}
+		for _, row := range rows {
+			values = append(values, conditionValue(row[0]))
+		}
+		match = func(v *dynamodb.AttributeValue) bool {
+			for _, value := range values {
+				if v != nil && sameValue(v, value) {
+					return true
+				}
+			}
+			return false
+		}
+	case sqlparser.OpLike, sqlparser.OpILike, sqlparser.OpRegexp:
+		if match, err = patternMatcher(c); err != nil {
+			return nil, err
+		}
+	case sqlparser.OpIsMissing, sqlparser.OpAttributeNotExists:
+		match = func(v *dynamodb.AttributeValue) bool { return v == nil }
+	case sqlparser.OpIsNotMissing, sqlparser.OpAttributeExists:
+		match = func(v *dynamodb.AttributeValue) bool { return v != nil }
+	case sqlparser.OpIsNull:
+		match = func(v *dynamodb.AttributeValue) bool { return v != nil && v.NULL != nil }
+	case sqlparser.OpIsNotNull:
+		match = func(v *dynamodb.AttributeValue) bool { return v != nil && v.NULL == nil }
+	case sqlparser.OpAttributeType:
+		attributeType := strings.ToUpper(stringArgument(c.Value))
+		match = func(v *dynamodb.AttributeValue) bool { return v != nil && typeOf(v) == attributeType }
+	case sqlparser.OpBeginsWith:
+		prefix := stringArgument(c.Value)
+		match = func(v *dynamodb.AttributeValue) bool {
+			return v != nil && v.S != nil && strings.HasPrefix(*v.S, prefix)
+		}
+	case sqlparser.OpContains:
+		value := conditionValue(c.Value)
+		match = func(v *dynamodb.AttributeValue) bool { return v != nil && containsValue(v, value) }
+	default:
+		return nil, fmt.Errorf("WHEN does not take %s", c)
+	}
+	return func(item map[string]*dynamodb.AttributeValue) bool {
+		return match(valueOf(item))
+	}, nil
+}
+
+// sameType tells whether two values have the same scalar type, values of other types are never ordered
+func sameType(a, b *dynamodb.AttributeValue) bool {
+	rank := typeRank(a)
+	return rank == typeRank(b) && rank < 4
+}
+
+// sameValue tells whether two values are equal, numbers by value
+func sameValue(a, b *dynamodb.AttributeValue) bool {
+	if sameType(a, b) {
+		return compareValues(a, b) == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// containsValue tells whether a string contains a substring, or a set or list contains an element
+func containsValue(v, element *dynamodb.AttributeValue) bool {
+	switch {
+	case v.S != nil && element.S != nil:
+		return strings.Contains(*v.S, *element.S)
+	case v.SS != nil && element.S != nil:
+		return utils.FindIndex(aws.StringValueSlice(v.SS), *element.S) != -1
+	case v.NS != nil && element.N != nil:
+		for _, n := range v.NS {
+			if sameValue(&dynamodb.AttributeValue{N: n}, element) {
+				return true
+			}
+		}
+	case v.L != nil:
+		for _, e := range v.L {
+			if sameValue(e, element) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeOf is the type of a value as attribute_type takes it, like S, N or SS
+func typeOf(v *dynamodb.AttributeValue) string {
+	switch {
+	case v.S != nil:
+		return dynamodb.ScalarAttributeTypeS
+	case v.N != nil:
+		return dynamodb.ScalarAttributeTypeN
+	case v.B != nil:
+		return dynamodb.ScalarAttributeTypeB
+	case v.BOOL != nil:
+		return "BOOL"
+	case v.NULL != nil:
+		return "NULL"
+	case v.M != nil:
+		return "M"
+	case v.L != nil:
+		return "L"
+	case v.SS != nil:
+		return "SS"
+	case v.NS != nil:
+		return "NS"
+	case v.BS != nil:
+		return "BS"
+	}
+	return ""
+}
+
+// integerOf returns the value of an integer, ok is false for other values
+func integerOf(v *dynamodb.AttributeValue) (int, bool) {
+	if v == nil || v.N == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(*v.N)
+	return n, err == nil
+}
+
+// applyFunction computes a function of a computed column, nil if it does not apply to the values,
+// arithmetic takes numbers, upper, lower and substr take strings, substr counts characters from 1
+func applyFunction(function string, args []*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	for _, a := range args {
+		if a == nil {
+			return nil
+		}
+	}
+	switch function {
+	case "+", "-", "*", "/":
+		if args[0].N == nil || args[1].N == nil {
+			return nil
+		}
+		x, okX := new(big.Rat).SetString(*args[0].N)
+		y, okY := new(big.Rat).SetString(*args[1].N)
+		if !okX || !okY {
+			return nil
+		}
+		switch function {
+		case "+":
+			x.Add(x, y)
+		case "-":
+			x.Sub(x, y)
+		case "*":
+			x.Mul(x, y)
+		default:
+			if y.Sign() == 0 {
+				return nil
+			}
+			x.Quo(x, y)
+		}
+		return &dynamodb.AttributeValue{N: aws.String(formatNumber(x))}
+	case "upper", "lower":
+		if args[0].S == nil {
+			return nil
+		}
+		if function == "upper" {
+			return &dynamodb.AttributeValue{S: aws.String(strings.ToUpper(*args[0].S))}
+		}
+		return &dynamodb.AttributeValue{S: aws.String(strings.ToLower(*args[0].S))}
+	case "substr":
+		start, ok := integerOf(args[1])
+		if args[0].S == nil || !ok {
+			return nil
+		}
+		runes := []rune(*args[0].S)
+		from := start - 1
+		if from < 0 {
+			from = 0
+		} else if from > len(runes) {
+			from = len(runes)
+		}
+		to := len(runes)
+		if len(args) == 3 {
+			length, ok := integerOf(args[2])
+			if !ok || length < 0 {
+				return nil
+			}
+			if from+length < to {
+				to = from + length
+			}
+		}
+		return &dynamodb.AttributeValue{S: aws.String(string(runes[from:to]))}
+	case sqlparser.FunctionSize:
+		v := args[0]
+		size := -1
+		switch {
+		case v.S != nil:
+			size = len(*v.S)
+		case v.B != nil:
+			size = len(v.B)
+		case v.M != nil:
+			size = len(v.M)
+		case v.L != nil:
+			size = len(v.L)
+		case v.SS != nil:
+			size = len(v.SS)
+		case v.NS != nil:
+			size = len(v.NS)
+		case v.BS != nil:
+			size = len(v.BS)
+		}
+		if size < 0 {
+			return nil
+		}
+		return &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(size))}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package executors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func Test_planComputation(t *testing.T) {
+	tests := []struct {
+		name       string
+		sql        string
+		wantNeeded []string
+		wantSort   bool
+		wantErr    bool
+	}{
+		{name: "test planComputation reads only the attributes needed", sql: "SELECT name AS n, price * qty AS total, upper(name) FROM orders END", wantNeeded: []string{"name", "price", "qty"}},
+		{name: "test planComputation of CASE", sql: "SELECT CASE WHEN size(tags) > 1 THEN note ELSE 'none' END AS note FROM orders END", wantNeeded: []string{"tags", "note"}},
+		{name: "test planComputation with *", sql: "SELECT *, upper(name) AS n FROM orders END", wantNeeded: []string{"*"}},
+		{name: "test planComputation of literals only", sql: "SELECT 'x' AS x FROM orders END", wantNeeded: []string{"*"}},
+		{name: "test planComputation sorting by a computed column", sql: "SELECT name, price * qty AS total FROM orders ORDER BY total DESC, name END", wantNeeded: []string{"name", "price", "qty"}, wantSort: true},
+		{name: "test planComputation sorting by a path", sql: "SELECT DISTINCT status FROM orders ORDER BY ts END", wantNeeded: []string{"status"}},
+		{name: "test planComputation sorting by a computed column and a column not selected", sql: "SELECT price * qty AS total FROM orders ORDER BY total, ts END", wantErr: true},
+		{name: "test planComputation with an unknown function", sql: "SELECT trim(name) AS n FROM orders END", wantErr: true},
+		{name: "test planComputation with an unquoted string", sql: "SELECT CASE WHEN a = 1 THEN yes please END AS b FROM orders END", wantErr: true},
+		{name: "test planComputation with an invalid CASE", sql: "SELECT CASE WHEN a = 1 END AS b FROM orders END", wantErr: true},
+		{name: "test planComputation with too many arguments", sql: "SELECT upper(name, 1) AS n FROM orders END", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := planComputation(sqlparser.ParseSelect(tt.sql))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("planComputation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got.needed, tt.wantNeeded) {
+				t.Errorf("planComputation() needed = %v, want %v", got.needed, tt.wantNeeded)
+			}
+			if (len(got.orderBy) > 0) != tt.wantSort {
+				t.Errorf("planComputation() orderBy = %v, want sorted %v", got.orderBy, tt.wantSort)
+			}
+		})
+	}
+}
+
+func Test_computation_row(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{
+		"name":    {S: aws.String("Ada Lovelace")},
+		"price":   {N: aws.String("2.5")},
+		"qty":     {N: aws.String("3")},
+		"coins":   {N: aws.String("150")},
+		"tags":    {SS: aws.StringSlice([]string{"vip", "beta"})},
+		"address": {M: map[string]*dynamodb.AttributeValue{"city": {S: aws.String("London")}, "zip": {S: aws.String("N1")}}},
+	}
+	n := func(s string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{N: aws.String(s)} }
+	s := func(s string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{S: aws.String(s)} }
+	tests := []struct {
+		name   string
+		column string
+		want   map[string]*dynamodb.AttributeValue
+	}{
+		{name: "test row of a path", column: "address.city", want: map[string]*dynamodb.AttributeValue{"address": {M: map[string]*dynamodb.AttributeValue{"city": s("London")}}}},
+		{name: "test row of an aliased path", column: "address.city AS city", want: map[string]*dynamodb.AttributeValue{"city": s("London")}},
+		{name: "test row of arithmetic", column: "price * qty - 1 AS total", want: map[string]*dynamodb.AttributeValue{"total": n("6.5")}},
+		{name: "test row of a division", column: "coins / 4", want: map[string]*dynamodb.AttributeValue{"coins / 4": n("37.5")}},
+		{name: "test row of a division by zero", column: "coins / 0 AS x", want: map[string]*dynamodb.AttributeValue{}},
+		{name: "test row of arithmetic on a string", column: "name + 1 AS x", want: map[string]*dynamodb.AttributeValue{}},
+		{name: "test row of a missing attribute", column: "upper(nickname) AS x", want: map[string]*dynamodb.AttributeValue{}},
+		{name: "test row of upper and substr", column: "upper(substr(name, 5)) AS last", want: map[string]*dynamodb.AttributeValue{"last": s("LOVELACE")}},
+		{name: "test row of substr with a length", column: "lower(substr(name, 1, 3)) AS first", want: map[string]*dynamodb.AttributeValue{"first": s("ada")}},
+		{name: "test row of size", column: "size(tags) AS tagCount", want: map[string]*dynamodb.AttributeValue{"tagCount": n("2")}},
+		{name: "test row of CASE", column: "CASE WHEN coins > 100 AND contains(tags, 'vip') THEN 'rich' ELSE 'poor' END AS band", want: map[string]*dynamodb.AttributeValue{"band": s("rich")}},
+		{name: "test row of CASE with ELSE", column: "CASE WHEN coins > 1000 THEN 'rich' WHEN nickname IS MISSING THEN coins * 2 ELSE 0 END AS band", want: map[string]*dynamodb.AttributeValue{"band": n("300")}},
+		{name: "test row of CASE without ELSE", column: "CASE WHEN name LIKE 'Bob%' THEN 1 END AS bob", want: map[string]*dynamodb.AttributeValue{}},
+		{name: "test row of CASE with BETWEEN, IN and size", column: "CASE WHEN price BETWEEN 2 AND 3 AND qty IN (1, 3) AND size(tags) = 2 THEN 'yes' END AS x", want: map[string]*dynamodb.AttributeValue{"x": s("yes")}},
+		{name: "test row of CASE comparing a number to a string", column: "CASE WHEN coins > 'a' THEN 'yes' ELSE 'no' END AS x", want: map[string]*dynamodb.AttributeValue{"x": s("no")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comp, err := planComputation(sqlparser.SelectStatement{AttributesToGet: []string{tt.column}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := comp.row(item); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("row() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
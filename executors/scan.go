@@ -0,0 +1,162 @@
+package executors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/FrontMage/dynamo.cli/db"
+	"github.com/FrontMage/dynamo.cli/tables"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ScanWorkers is how many segments of a parallel Scan are read at the same time
+var ScanWorkers = 8
+
+// ParallelScanItems is the item count of a table above which a Scan reading every matching item
+// is split into ScanWorkers segments without PARALLEL, 0 never splits it
+var ParallelScanItems int64 = 100000
+
+// scanWithContext reads a page of a segment, the requests of a parallel Scan are canceled through ctx
+var scanWithContext = func(ctx aws.Context, input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	return db.DynamoDB.ScanWithContext(ctx, input)
+}
+
+// scanPage is a page of a segment of a Scan, or the error reading it, last is true for the last page of the segment
+type scanPage struct {
+	segment int64
+	output  *dynamodb.ScanOutput
+	err     error
+	last    bool
+}
+
+// scanWorkers is how many of the segments of a Scan are read at the same time
+func scanWorkers(segments int64) int64 {
+	if ScanWorkers < 1 {
+		return 1
+	} else if int64(ScanWorkers) > segments {
+		return segments
+	}
+	return int64(ScanWorkers)
+}
+
+// scanSegments reads the segments of a Scan with ScanWorkers goroutines, each one paging through a segment at a time,
+// and hands the pages to each in the order they arrive.
+// Reading stops once each returns false, on the first error or on Interrupt, the requests still running are canceled
+// and their workers are waited for
+func scanSegments(scanInput *dynamodb.ScanInput, segments int64, each func(*dynamodb.ScanOutput) bool) error {
+	workers := scanWorkers(segments)
+	segmentCh := make(chan int64, segments)
+	for segment := int64(0); segment < segments; segment++ {
+		segmentCh <- segment
+	}
+	close(segmentCh)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pages := make(chan scanPage)
+	// a worker tells it's finished without waiting for it to be heard
+	finished := make(chan struct{}, workers)
+	for i := int64(0); i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { finished <- struct{}{} }()
+			for segment := range segmentCh {
+				input := *scanInput
+				input.Segment, input.TotalSegments = aws.Int64(segment), aws.Int64(segments)
+				for {
+					output, err := scanWithContext(ctx, &input)
+					page := scanPage{segment: segment, output: output, err: err, last: err == nil && output.LastEvaluatedKey == nil}
+					select {
+					case pages <- page:
+					case <-ctx.Done():
+						return
+					}
+					if err != nil {
+						return
+					} else if page.last {
+						break
+					}
+					input.ExclusiveStartKey = output.LastEvaluatedKey
+				}
+			}
+		}()
+	}
+
+	scanned := make([]int64, segments)
+	var done int64
+	for remaining := workers; remaining > 0; {
+		select {
+		case page := <-pages:
+			if page.err != nil {
+				return page.err
+			}
+			if atomic.LoadInt32(&interrupted) == 1 {
+				return errors.New("Interrupted, the Scan is stopped")
+			}
+			scanned[page.segment] += aws.Int64Value(page.output.ScannedCount)
+			if page.last {
+				done++
+			}
+			Progress(fmt.Sprintf("segment %d/%d at %d items scanned, %d/%d segments done", page.segment+1, segments, scanned[page.segment], done, segments))
+			if !each(page.output) {
+				return nil
+			}
+		case <-finished:
+			remaining--
+		}
+	}
+	return nil
+}
+
+// parallelScan is scanWithFilterUntilLimit over segments read at the same time,
+// the items come in the order their pages arrive and the segments still read are canceled once limit items are collected
+func parallelScan(scanInput *dynamodb.ScanInput, segments int64, limit int64, keep func(map[string]*dynamodb.AttributeValue) bool) ([]map[string]*dynamodb.AttributeValue, error) {
+	list := []map[string]*dynamodb.AttributeValue{}
+	err := scanSegments(scanInput, segments, func(output *dynamodb.ScanOutput) bool {
+		for _, i := range output.Items {
+			if keep != nil && !keep(i) {
+				continue
+			}
+			if limit < 0 || int64(len(list)) < limit {
+				list = append(list, i)
+			}
+		}
+		return limit < 0 || int64(len(list)) < limit
+	})
+	return list, err
+}
+
+// scanSegmentsOf is the number of segments a Scan of a table is split into without PARALLEL,
+// 1 unless the table has more than ParallelScanItems items
+func scanSegmentsOf(tableName string) (int64, error) {
+	if ParallelScanItems <= 0 || ScanWorkers <= 1 {
+		return 1, nil
+	}
+	tableDesc, err := tables.GetTableDesc(&tableName)
+	if err != nil {
+		return 1, err
+	}
+	if briefTable(tableDesc.Table).itemCount <= ParallelScanItems {
+		return 1, nil
+	}
+	return int64(ScanWorkers), nil
+}
+
+// scan reads the items of a Scan plan, in parallel when it's split into segments,
+// which read pages as large as DynamoDB makes them when every matching item is read
+func (plan selectPlan) scan(scanInput *dynamodb.ScanInput, limit int64, keep func(map[string]*dynamodb.AttributeValue) bool) ([]map[string]*dynamodb.AttributeValue, error) {
+	if plan.segments > 1 {
+		if limit < 0 {
+			scanInput.Limit = nil
+		}
+		return parallelScan(scanInput, plan.segments, limit, keep)
+	}
+	return scanWithFilterUntilLimit(scanInput, limit, keep, []map[string]*dynamodb.AttributeValue{})
+}
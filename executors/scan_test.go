@@ -0,0 +1,96 @@
+package executors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func Test_scanWorkers(t *testing.T) {
+	defer func(workers int) { ScanWorkers = workers }(ScanWorkers)
+	tests := []struct {
+		name     string
+		workers  int
+		segments int64
+		want     int64
+	}{
+		{name: "test scanWorkers with fewer segments than workers", workers: 8, segments: 3, want: 3},
+		{name: "test scanWorkers with more segments than workers", workers: 8, segments: 20, want: 8},
+		{name: "test scanWorkers without workers", workers: 0, segments: 4, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ScanWorkers = tt.workers
+			if got := scanWorkers(tt.segments); got != tt.want {
+				t.Errorf("scanWorkers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_scanSegments(t *testing.T) {
+	defer func(scan func(aws.Context, *dynamodb.ScanInput) (*dynamodb.ScanOutput, error), workers int) {
+		scanWithContext, ScanWorkers = scan, workers
+	}(scanWithContext, ScanWorkers)
+	ScanWorkers = 4
+	ClearInterrupt()
+	errFirst := errors.New("first")
+	tests := []struct {
+		name      string
+		limit     int64
+		scan      func(segment int64) (*dynamodb.ScanOutput, error)
+		wantItems int
+		wantErr   error
+	}{
+		{
+			// segment 0 pages on forever, the others never answer, LIMIT has to stop all of them
+			name:  "test scanSegments stopped by LIMIT",
+			limit: 3,
+			scan: func(segment int64) (*dynamodb.ScanOutput, error) {
+				if segment != 0 {
+					return nil, nil
+				}
+				return &dynamodb.ScanOutput{
+					Items:            []map[string]*dynamodb.AttributeValue{{"id": {N: aws.String("1")}}},
+					LastEvaluatedKey: map[string]*dynamodb.AttributeValue{"id": {N: aws.String("1")}},
+				}, nil
+			},
+			wantItems: 3,
+		},
+		{
+			name:  "test scanSegments with an error",
+			limit: -1,
+			scan: func(segment int64) (*dynamodb.ScanOutput, error) {
+				if segment == 2 {
+					return nil, errFirst
+				}
+				return nil, nil
+			},
+			wantErr: errFirst,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canceled := make(chan int64, 4)
+			scanWithContext = func(ctx aws.Context, input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+				if output, err := tt.scan(*input.Segment); output != nil || err != nil {
+					return output, err
+				}
+				// a segment which does not answer until its request is canceled
+				<-ctx.Done()
+				canceled <- *input.Segment
+				return nil, ctx.Err()
+			}
+			items, err := parallelScan(&dynamodb.ScanInput{TableName: aws.String("user")}, 4, tt.limit, nil)
+			if err != tt.wantErr || len(items) != tt.wantItems {
+				t.Errorf("parallelScan() = %d items, %v, want %d items, %v", len(items), err, tt.wantItems, tt.wantErr)
+			}
+			// the workers are done once parallelScan returns
+			if len(canceled) != 3 {
+				t.Errorf("parallelScan() canceled %d segments, want 3", len(canceled))
+			}
+		})
+	}
+}
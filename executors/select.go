@@ -2,6 +2,7 @@ package executors
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/FrontMage/dynamo.cli/db"
@@ -22,8 +23,9 @@ type tableBrief struct {
 }
 
 type tableIndex struct {
-	name  string
-	field string
+	name     string
+	field    string
+	rangeKey string
 }
 
 const (
@@ -38,7 +40,7 @@ func getQueryMethod(globalSecondaryIndexes []tableIndex, hashKey string, conditi
 	relatedIndexName := ""
 	for _, index := range globalSecondaryIndexes {
 		for _, c := range conditions {
-			if c.Key == index.field && c.Operator == sqlparser.OpEq {
+			if c.Key == index.field && c.Operator == sqlparser.OpEq && c.Function == "" {
 				queryMethod = queryWithGlobalSecondaryIndex
 				relatedCondition = c
 				relatedIndexName = index.name
@@ -47,7 +49,7 @@ func getQueryMethod(globalSecondaryIndexes []tableIndex, hashKey string, conditi
 		}
 	}
 	for _, c := range conditions {
-		if c.Key == hashKey && c.Operator == sqlparser.OpEq {
+		if c.Key == hashKey && c.Operator == sqlparser.OpEq && c.Function == "" {
 			queryMethod = queryWithHashKey
 			relatedCondition = c
 			break
@@ -57,27 +59,58 @@ func getQueryMethod(globalSecondaryIndexes []tableIndex, hashKey string, conditi
 	return queryMethod, relatedCondition, relatedIndexName
 }
 
-func buildFilterExpression(conditions []sqlparser.Condition, indexToUse string) expression.ConditionBuilder {
+// buildFilterExpression ands the conditions, ok is false if there is none
+func buildFilterExpression(conditions []sqlparser.Condition) (expression.ConditionBuilder, bool) {
 	var filterExpression expression.ConditionBuilder
 	for idx, c := range conditions {
-		// filter expression can only contains fields that is not primary key or index
-		if !strings.HasPrefix(indexToUse, c.Key) {
-			if idx == 0 {
-				// if the value can be parse to number, use it as number
-				filterExpression = SwitchExpression(c)
-			} else {
-				filterExpression = filterExpression.
-					And(SwitchExpression(c))
-			}
+		if idx == 0 {
+			filterExpression = SwitchExpression(c)
+		} else {
+			filterExpression = filterExpression.
+				And(SwitchExpression(c))
+		}
+	}
+	return filterExpression, len(conditions) > 0
+}
+
+// sortKeyCondition turns a condition of the range key into a key condition,
+// ok is false if a query can't take it, like != or size(), which is left to the filter
+func sortKeyCondition(c sqlparser.Condition) (expression.KeyConditionBuilder, bool) {
+	if c.Function != "" {
+		return expression.KeyConditionBuilder{}, false
+	}
+	key := keyName(c.Key)
+	value := expression.Value(literalValue(c.Value))
+	switch c.Operator {
+	case "=":
+		return key.Equal(value), true
+	case ">":
+		return key.GreaterThan(value), true
+	case "<":
+		return key.LessThan(value), true
+	case ">=":
+		return key.GreaterThanEqual(value), true
+	case "<=":
+		return key.LessThanEqual(value), true
+	case sqlparser.OpBetween:
+		return key.Between(expression.Value(literalValue(c.Value)), expression.Value(literalValue(c.High))), true
+	case sqlparser.OpBeginsWith:
+		return key.BeginsWith(stringArgument(c.Value)), true
+	case sqlparser.OpLike:
+		switch kind, literal := parseLike(stringArgument(c.Value)); kind {
+		case likeEqual:
+			return key.Equal(expression.Value(literal)), true
+		case likeBeginsWith:
+			return key.BeginsWith(literal), true
 		}
 	}
-	return filterExpression
+	return expression.KeyConditionBuilder{}, false
 }
 
 func buildProjection(attributesToGet []string) expression.ProjectionBuilder {
 	params := []expression.NameBuilder{}
 	for _, a := range attributesToGet {
-		params = append(params, expression.Name(a))
+		params = append(params, pathName(a))
 	}
 	if len(params) > 1 {
 		return expression.NamesList(params[0], params[1:len(params)]...)
@@ -93,10 +126,16 @@ func briefTable(desc *dynamodb.TableDescription) tableBrief {
 		brief.keySchemas = append(brief.keySchemas, *s.AttributeName)
 	}
 	for _, i := range desc.GlobalSecondaryIndexes {
-		brief.globalSecondaryIndexes = append(brief.globalSecondaryIndexes, tableIndex{
+		index := tableIndex{
 			name:  *i.IndexName,
 			field: strings.Split(*i.IndexName, "-")[0],
-		})
+		}
+		for _, s := range i.KeySchema {
+			if *s.KeyType == dynamodb.KeyTypeRange {
+				index.rangeKey = *s.AttributeName
+			}
+		}
+		brief.globalSecondaryIndexes = append(brief.globalSecondaryIndexes, index)
 	}
 	brief.hashKey = *desc.KeySchema[0].AttributeName
 	if len(desc.KeySchema) > 1 {
@@ -105,173 +144,703 @@ func briefTable(desc *dynamodb.TableDescription) tableBrief {
 	return brief
 }
 
-func scan(stmt sqlparser.SelectStatement) (string, error) {
-	scanInput := &dynamodb.ScanInput{
-		TableName: &stmt.TableName,
-		Limit:     &stmt.Limit,
-	}
-	if stmt.AttributesToGet[0] != "*" {
-		scanInput.SetAttributesToGet(aws.StringSlice(stmt.AttributesToGet))
-	}
+// scanWithFilterUntilLimit pages through the scan until limit items are collected,
+// a negative limit collects all items, items not kept by keep are skipped unless it's nil
+func scanWithFilterUntilLimit(scanInput *dynamodb.ScanInput, limit int64, keep func(map[string]*dynamodb.AttributeValue) bool,
+	list []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
 	if result, err := db.DynamoDB.Scan(scanInput); err == nil {
-		return utils.FormatPrettyListOfMap(result.Items), nil
+		for _, i := range result.Items {
+			if keep != nil && !keep(i) {
+				continue
+			}
+			if limit < 0 || int64(len(list)) < limit {
+				list = append(list, i)
+			}
+		}
+		if (limit < 0 || int64(len(list)) < limit) && result.LastEvaluatedKey != nil {
+			scanInput.ExclusiveStartKey = result.LastEvaluatedKey
+			return scanWithFilterUntilLimit(scanInput, limit, keep, list)
+		} else {
+			return list, nil
+		}
 	} else {
-		return "", err
+		return list, err
 	}
 }
 
-func scanWithFilterUntilLimit(scanInput *dynamodb.ScanInput, limit int64,
-	list []map[string]*dynamodb.AttributeValue) (string, error) {
-	if result, err := db.DynamoDB.Scan(scanInput); err == nil {
+// queryUntilLimit pages through the query until limit items are collected,
+// a negative limit collects all items, items not kept by keep are skipped unless it's nil
+func queryUntilLimit(queryInput *dynamodb.QueryInput, limit int64, keep func(map[string]*dynamodb.AttributeValue) bool,
+	list []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
+	if result, err := db.DynamoDB.Query(queryInput); err == nil {
 		for _, i := range result.Items {
-			if int64(len(list)) < limit {
+			if keep != nil && !keep(i) {
+				continue
+			}
+			if limit < 0 || int64(len(list)) < limit {
 				list = append(list, i)
 			}
 		}
-		if int64(len(list)) < limit && result.LastEvaluatedKey != nil {
-			scanInput.ExclusiveStartKey = result.LastEvaluatedKey
-			return scanWithFilterUntilLimit(scanInput, limit, list)
+		if (limit < 0 || int64(len(list)) < limit) && result.LastEvaluatedKey != nil {
+			queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+			return queryUntilLimit(queryInput, limit, keep, list)
 		} else {
-			return utils.FormatPrettyListOfMap(list), nil
+			return list, nil
 		}
 	} else {
-		return "", err
+		return list, err
 	}
 }
 
-// TODO better structure
-// Select executes selectSQL string by parsing to dynamodb api
-func Select(selectSQL string) (string, error) {
-	stmt := sqlparser.ParseSelect(selectSQL)
-	if stmt.TableName == "" {
-		return "", errors.New("Can't utils.Find table name, check your inputs")
+// keyFromConditions builds the primary key from conditions,
+// ok is false if some key schema is not matched by an equal condition
+func keyFromConditions(keySchemas []string, conditions []sqlparser.Condition) (map[string]*dynamodb.AttributeValue, bool) {
+	key := map[string]*dynamodb.AttributeValue{}
+	for _, schema := range keySchemas {
+		for _, c := range conditions {
+			if c.Key == schema && c.Operator == sqlparser.OpEq && c.Function == "" {
+				key[c.Key] = literalValue(c.Value)
+				break
+			}
+		}
+		if key[schema] == nil {
+			return key, false
+		}
+	}
+	return key, true
+}
+
+// Access methods of a select plan, named after the request used
+const (
+	methodGetItem      = "GetItem"
+	methodBatchGetItem = "BatchGetItem"
+	methodQuery        = "Query"
+	methodScan         = "Scan"
+)
+
+// selectPlan is how the items of a select statement are read
+// selectPlan keys are the primary keys read by GetItem or BatchGetItem
+// selectPlan keyConditions are the hash key condition of a Query and the range key one if any
+// selectPlan filters are the FilterExpression, clientFilters among them are matched on the items read as well
+// selectPlan rangeKey is the range key of the table or index queried
+// selectPlan clientSort is true if the items are sorted by orderBy after reading, otherwise a Query reads them in order
+// selectPlan consistent is true for strongly consistent reads, segments is the number of segments of a parallel Scan
+type selectPlan struct {
+	method        string
+	tableName     string
+	indexName     string
+	keySchemas    []string
+	keys          []map[string]*dynamodb.AttributeValue
+	keyConditions []sqlparser.Condition
+	filters       []sqlparser.Condition
+	clientFilters []sqlparser.Condition
+	rangeKey      string
+	orderBy       []sqlparser.OrderBy
+	clientSort    bool
+	consistent    bool
+	segments      int64
+}
+
+// planSelect plans how the items are read by planRead and how they are sorted,
+// ORDER BY the range key of a Query sets ScanIndexForward, any other ORDER BY is sorted client-side
+func planSelect(stmt sqlparser.SelectStatement) (selectPlan, error) {
+	for _, o := range stmt.OrderBy {
+		if _, err := sqlparser.ParsePath(o.Key); err != nil {
+			return selectPlan{}, fmt.Errorf("Invalid ORDER BY %s, %v", o.Key, err)
+		}
+	}
+	plan, err := planRead(stmt)
+	if err != nil {
+		return plan, err
+	}
+	// PARALLEL splits a Scan, the other reads are not split.
+	// Without it a Scan of a large table is split too when it filters, sorts or reads every matching item,
+	// one of LIMIT items which are the first ones read is left to NEXT
+	filters := len(plan.filters) > 0 || len(plan.clientFilters) > 0
+	switch {
+	case stmt.Hints.Parallel > 1 && plan.method == methodScan:
+		plan.segments = stmt.Hints.Parallel
+	case stmt.Hints.Parallel > 1:
+		Warn(fmt.Sprintf("PARALLEL(%d) splits a Scan, %s is not split", stmt.Hints.Parallel, plan.method))
+	case stmt.Hints.Parallel == 0 && plan.method == methodScan && stmt.After == "" && (stmt.Limit < 0 || len(stmt.OrderBy) > 0 || filters):
+		if plan.segments, err = scanSegmentsOf(stmt.TableName); err != nil {
+			return plan, err
+		}
+	}
+	if len(stmt.OrderBy) == 0 || plan.method == methodGetItem {
+		return plan, nil
+	}
+	plan.orderBy = stmt.OrderBy
+	plan.clientSort = plan.method != methodQuery || len(stmt.OrderBy) > 1 || stmt.OrderBy[0].Key != plan.rangeKey
+	return plan, nil
+}
+
+// planRead picks GetItem if the full primary key is given, BatchGetItem for IN of keys,
+// Query if hash key or an index is given, otherwise Scan, unless USE_INDEX or FORCE_SCAN picks it.
+// CONSISTENT reads don't query global indexes, which are never consistent
+func planRead(stmt sqlparser.SelectStatement) (selectPlan, error) {
+	plan := selectPlan{method: methodScan, tableName: stmt.TableName, clientFilters: clientSideConditions(stmt.Conditions), consistent: stmt.Hints.Consistent}
+	if err := checkConditions(stmt.Conditions); err != nil {
+		return plan, err
+	}
+	if err := checkHints(stmt); err != nil {
+		return plan, err
+	}
+	for _, c := range plan.clientFilters {
+		if _, err := clientMatcher(c); err != nil {
+			return plan, err
+		}
+	}
+	if stmt.Hints.Index != "" || stmt.Hints.ForceScan {
+		return planHinted(plan, stmt)
 	}
 	if len(stmt.Conditions) == 0 {
-		return scan(stmt)
-	}
-
-	// get table info
-	if tableDesc, describeTableErr := tables.GetTableDesc(&stmt.TableName); describeTableErr == nil {
-
-		tableInfo := briefTable(tableDesc.Table)
-		conditionKeys := []string{}
-
-		isAbleToGet := true
-		for _, c := range stmt.Conditions {
-			conditionKeys = append(conditionKeys, c.Key)
-		}
-		for _, schema := range tableInfo.keySchemas {
-			schemaIdx := utils.FindIndex(conditionKeys, schema)
-			isAbleToGet = isAbleToGet && schemaIdx != -1 && stmt.Conditions[schemaIdx].Operator == sqlparser.OpEq
-		}
-		// if key schema is satisfied use get
-		if isAbleToGet {
-			// TODO unable to use builder for get, checkout on stackoverflow
-			key := map[string]*dynamodb.AttributeValue{}
-			for _, c := range stmt.Conditions {
-				switch tryParseInt(c.Value).(type) {
-				case string:
-					key[c.Key] = &dynamodb.AttributeValue{
-						S: aws.String(c.Value),
-					}
-				case int:
-					key[c.Key] = &dynamodb.AttributeValue{
-						N: aws.String(c.Value),
-					}
-				case int64:
-					key[c.Key] = &dynamodb.AttributeValue{
-						N: aws.String(c.Value),
-					}
-				}
+		return plan, nil
+	}
+	tableDesc, err := tables.GetTableDesc(&stmt.TableName)
+	if err != nil {
+		return plan, err
+	}
+	tableInfo := briefTable(tableDesc.Table)
+	plan.keySchemas = tableInfo.keySchemas
+
+	// IN of key attributes, along with = of the others, reads the items by their keys
+	for _, c := range stmt.Conditions {
+		if c.Operator == sqlparser.OpIn {
+			if keys, ok := keysFromConditions(tableInfo.keySchemas, stmt.Conditions); ok {
+				plan.method, plan.keys = methodBatchGetItem, keys
+				return plan, nil
 			}
-			getItemInput := &dynamodb.GetItemInput{
-				TableName: &stmt.TableName,
-				Key:       key,
+			break
+		}
+	}
+	// if key schema is satisfied and there is nothing else to filter, use get
+	if key, ok := keyFromConditions(tableInfo.keySchemas, stmt.Conditions); ok && len(key) == len(stmt.Conditions) {
+		plan.method, plan.keys = methodGetItem, []map[string]*dynamodb.AttributeValue{key}
+		return plan, nil
+	}
+	indexes := tableInfo.globalSecondaryIndexes
+	if plan.consistent {
+		indexes = nil
+	}
+	queryMethod, relatedCondition, indexToUse := getQueryMethod(indexes, tableInfo.hashKey, stmt.Conditions)
+	if queryMethod == unableToQuery {
+		plan.filters = stmt.Conditions
+		return plan, nil
+	}
+	plan.method, plan.keyConditions, plan.filters = methodQuery, []sqlparser.Condition{relatedCondition}, []sqlparser.Condition{}
+	plan.rangeKey = tableInfo.rangeKey
+	if queryMethod == queryWithGlobalSecondaryIndex {
+		plan.indexName = indexToUse
+		for _, index := range tableInfo.globalSecondaryIndexes {
+			if index.name == indexToUse {
+				plan.rangeKey = index.rangeKey
 			}
-			if stmt.AttributesToGet[0] != "*" {
-				getItemInput.SetAttributesToGet(aws.StringSlice(stmt.AttributesToGet))
+		}
+	}
+	// filter expression can only contain attributes which are not in the key condition,
+	// a condition of the range key becomes part of the key condition when it can
+	for _, c := range stmt.Conditions {
+		if c == relatedCondition {
+			continue
+		}
+		if queryMethod == queryWithHashKey && len(plan.keyConditions) == 1 && c.Key == tableInfo.rangeKey {
+			if _, ok := sortKeyCondition(c); ok {
+				plan.keyConditions = append(plan.keyConditions, c)
+				continue
 			}
-			if result, err := db.DynamoDB.GetItem(getItemInput); err == nil {
-				return utils.FormatPrettyMap(result.Item), err
-			} else {
-				return "", err
+		}
+		plan.filters = append(plan.filters, c)
+	}
+	return plan, nil
+}
+
+// matcher returns a function telling whether an item matches all client-side filters
+func (plan selectPlan) matcher() func(map[string]*dynamodb.AttributeValue) bool {
+	matchers := []func(map[string]*dynamodb.AttributeValue) bool{}
+	for _, c := range plan.clientFilters {
+		// checked by planSelect
+		match, _ := clientMatcher(c)
+		matchers = append(matchers, match)
+	}
+	return func(item map[string]*dynamodb.AttributeValue) bool {
+		for _, match := range matchers {
+			if !match(item) {
+				return false
 			}
-			// if key schema is not satisfied, see if it's able to query
+		}
+		return true
+	}
+}
+
+// explain describes the plan for EXPLAIN
+func (plan selectPlan) explain() string {
+	lines := []string{plan.method + " on " + plan.tableName}
+	if plan.indexName != "" {
+		lines[0] += " using index " + plan.indexName
+	}
+	join := func(conditions []sqlparser.Condition) string {
+		texts := []string{}
+		for _, c := range conditions {
+			texts = append(texts, c.String())
+		}
+		return strings.Join(texts, " AND ")
+	}
+	switch plan.method {
+	case methodGetItem:
+		lines = append(lines, "  key: "+utils.FormatKey(plan.keys[0]))
+	case methodBatchGetItem:
+		lines = append(lines, fmt.Sprintf("  keys: %d, %d a request", len(plan.keys), batchGetSize))
+	case methodQuery:
+		lines = append(lines, "  key condition: "+join(plan.keyConditions))
+	}
+	filters := []sqlparser.Condition{}
+	for _, c := range plan.filters {
+		if !isClientSide(c) {
+			filters = append(filters, c)
+		}
+	}
+	if len(filters) > 0 {
+		lines = append(lines, "  filter: "+join(filters))
+	}
+	if len(plan.clientFilters) > 0 {
+		lines = append(lines, "  client-side filter: "+join(plan.clientFilters)+", matched on the items read")
+	}
+	if plan.consistent {
+		lines = append(lines, "  consistent read")
+	}
+	if plan.segments > 1 {
+		lines = append(lines, fmt.Sprintf("  parallel: %d segments, %d read at the same time", plan.segments, scanWorkers(plan.segments)))
+	}
+	if len(plan.orderBy) > 0 {
+		orderBy := []string{}
+		for _, o := range plan.orderBy {
+			orderBy = append(orderBy, o.String())
+		}
+		if plan.clientSort {
+			lines = append(lines, fmt.Sprintf("  order: %s, sorted client-side, up to %d items", strings.Join(orderBy, ", "), SortLimit))
 		} else {
-			// build keyConditionExpression
-			queryMethod, relatedCondition, indexToUse := getQueryMethod(tableInfo.globalSecondaryIndexes, tableInfo.hashKey, stmt.Conditions)
-
-			// build filterExpression
-			filterExpression := buildFilterExpression(stmt.Conditions, indexToUse)
-
-			// build projection expression
-			projectionExpression := buildProjection(stmt.AttributesToGet)
-
-			// if it's able to query with index, use query
-			if queryMethod != unableToQuery {
-				keyConditionExpression := expression.Key(relatedCondition.Key).Equal(expression.Value(tryParseInt(relatedCondition.Value)))
-				builder := expression.NewBuilder().
-					WithKeyCondition(keyConditionExpression)
-				// try use filter expression, if it's empty do not use it
-				if len(stmt.Conditions) > 1 {
-					builder = builder.WithFilter(filterExpression)
-				}
-				if stmt.AttributesToGet[0] != "*" {
-					builder = builder.WithProjection(projectionExpression)
-				}
-				if expr, err := builder.Build(); err == nil {
-					queryInput := &dynamodb.QueryInput{
-						ExclusiveStartKey:         nil,
-						TableName:                 &stmt.TableName,
-						ExpressionAttributeNames:  expr.Names(),
-						ExpressionAttributeValues: expr.Values(),
-						KeyConditionExpression:    expr.KeyCondition(),
-						Limit: &stmt.Limit,
-					}
-					if stmt.AttributesToGet[0] != "*" {
-						queryInput.ProjectionExpression = expr.Projection()
-					}
-					if queryMethod == queryWithGlobalSecondaryIndex {
-						queryInput.IndexName = &indexToUse
-					}
-					if *(expr.Filter()) != "" {
-						queryInput.SetFilterExpression(*(expr.Filter()))
-					}
-					if result, err := db.DynamoDB.Query(queryInput); err == nil {
-						return utils.FormatPrettyListOfMap(result.Items), nil
-					} else {
-						return "", err
-					}
-				} else {
-					return "", err
+			lines = append(lines, "  order: "+orderBy[0]+", by the range key")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// readInput builds the input of a Query or Scan plan, the other one is nil
+func (plan selectPlan) readInput(attributesToGet []string) (*dynamodb.QueryInput, *dynamodb.ScanInput, error) {
+	builder := expression.NewBuilder()
+	if len(plan.filters) > 0 {
+		filterExpression, _ := buildFilterExpression(plan.filters)
+		builder = builder.WithFilter(filterExpression)
+	}
+	if attributesToGet[0] != "*" {
+		builder = builder.WithProjection(buildProjection(attributesToGet))
+	}
+	if plan.method == methodQuery {
+		keyConditionExpression := keyName(plan.keyConditions[0].Key).Equal(expression.Value(literalValue(plan.keyConditions[0].Value)))
+		if len(plan.keyConditions) > 1 {
+			sortKey, _ := sortKeyCondition(plan.keyConditions[1])
+			keyConditionExpression = keyConditionExpression.And(sortKey)
+		}
+		builder = builder.WithKeyCondition(keyConditionExpression)
+	}
+	// a Scan of every item has no expression to build
+	expr := expression.Expression{}
+	if len(plan.filters) > 0 || attributesToGet[0] != "*" || plan.method == methodQuery {
+		var err error
+		if expr, err = builder.Build(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if plan.method == methodQuery {
+		queryInput := &dynamodb.QueryInput{
+			ExclusiveStartKey:         nil,
+			TableName:                 &plan.tableName,
+			ExpressionAttributeNames:  expressionNames(expr),
+			ExpressionAttributeValues: expr.Values(),
+			KeyConditionExpression:    expr.KeyCondition(),
+			FilterExpression:          expr.Filter(),
+			ProjectionExpression:      expr.Projection(),
+		}
+		if plan.indexName != "" {
+			queryInput.IndexName = &plan.indexName
+		}
+		if len(plan.orderBy) > 0 && !plan.clientSort {
+			queryInput.ScanIndexForward = aws.Bool(!plan.orderBy[0].Desc)
+		}
+		if plan.consistent {
+			queryInput.ConsistentRead = aws.Bool(true)
+		}
+		return queryInput, nil, nil
+	}
+	scanInput := &dynamodb.ScanInput{
+		ExclusiveStartKey:         nil,
+		TableName:                 &plan.tableName,
+		ExpressionAttributeNames:  expressionNames(expr),
+		ExpressionAttributeValues: expr.Values(),
+		FilterExpression:          expr.Filter(),
+		ProjectionExpression:      expr.Projection(),
+		Limit:                     aws.Int64(100),
+	}
+	if plan.indexName != "" {
+		scanInput.IndexName = &plan.indexName
+	}
+	if plan.consistent {
+		scanInput.ConsistentRead = aws.Bool(true)
+	}
+	return nil, scanInput, nil
+}
+
+// selectItems returns items matched by the select statement, read as planSelect plans.
+// isSingleItem is true when GetItem is used.
+func selectItems(stmt sqlparser.SelectStatement) ([]map[string]*dynamodb.AttributeValue, bool, error) {
+	items, isSingleItem, _, err := selectItemsWith(stmt, nil)
+	return items, isSingleItem, err
+}
+
+// selectItemsWith is selectItems leaving out the items not kept by keep unless it's nil,
+// keep sees the items matched in the order they are read, before OFFSET and LIMIT.
+// resumeKey is the key of the last item when LIMIT stopped a Query or Scan which is not sorted client-side,
+// reading on after it gives the following items, it's nil otherwise
+func selectItemsWith(stmt sqlparser.SelectStatement, keep func(map[string]*dynamodb.AttributeValue) bool) (items []map[string]*dynamodb.AttributeValue, isSingleItem bool, resumeKey map[string]*dynamodb.AttributeValue, err error) {
+	if stmt.Offset < 0 {
+		return nil, false, nil, errors.New("OFFSET takes the number of items to skip, like LIMIT 10 OFFSET 20")
+	}
+	// nested paths come back as sub-documents, emulators may not project at all
+	defer func() {
+		if stmt.AttributesToGet[0] != "*" {
+			for idx := range items {
+				items[idx] = projectItem(items[idx], stmt.AttributesToGet)
+			}
+		}
+	}()
+	plan, err := planSelect(stmt)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	var exclusiveStartKey map[string]*dynamodb.AttributeValue
+	if stmt.After != "" {
+		if (plan.method != methodQuery && plan.method != methodScan) || plan.clientSort || plan.segments > 1 {
+			return nil, false, nil, errors.New("AFTER continues a Query or Scan, not one sorted client-side, split by PARALLEL or reading items by their keys")
+		}
+		if exclusiveStartKey, err = decodeCursor(stmt.After); err != nil {
+			return nil, false, nil, err
+		}
+	}
+	// client-side filters and sorting need their attributes, which are dropped by the projection afterwards if not asked for
+	attributesToGet := stmt.AttributesToGet
+	if attributesToGet[0] != "*" {
+		attributesToGet = append([]string{}, attributesToGet...)
+		for _, c := range plan.clientFilters {
+			for _, a := range conditionAttributes(c) {
+				if utils.FindIndex(attributesToGet, a) == -1 {
+					attributesToGet = append(attributesToGet, a)
 				}
-				// if it's not able to use query, try use scan with filter
-			} else {
-				builder := expression.NewBuilder().
-					WithFilter(filterExpression)
-				if stmt.AttributesToGet[0] != "*" {
-					builder = builder.WithProjection(projectionExpression)
+			}
+		}
+		for _, o := range plan.orderBy {
+			if utils.FindIndex(attributesToGet, o.Key) == -1 {
+				attributesToGet = append(attributesToGet, o.Key)
+			}
+		}
+	}
+	switch plan.method {
+	case methodBatchGetItem:
+		items, err := batchGetItems(stmt.TableName, plan.keySchemas, plan.keys, attributesToGet, plan.consistent)
+		if err == nil && keep != nil {
+			kept := []map[string]*dynamodb.AttributeValue{}
+			for _, item := range items {
+				if keep(item) {
+					kept = append(kept, item)
 				}
-				if expr, err := builder.Build(); err == nil {
-					scanInput := &dynamodb.ScanInput{
-						ExclusiveStartKey:         nil,
-						TableName:                 &stmt.TableName,
-						ExpressionAttributeNames:  expr.Names(),
-						ExpressionAttributeValues: expr.Values(),
-						FilterExpression:          expr.Filter(),
-						Limit:                     aws.Int64(100),
-					}
-					if stmt.AttributesToGet[0] != "*" {
-						scanInput.ProjectionExpression = expr.Projection()
-					}
-					return scanWithFilterUntilLimit(scanInput, stmt.Limit,
-						[]map[string]*dynamodb.AttributeValue{})
-				} else {
-					return "", err
+			}
+			items = kept
+		}
+		if err == nil && plan.clientSort {
+			sortItems(items, plan.orderBy, valueAt)
+		}
+		return limitItems(skipItems(items, stmt.Offset), stmt.Limit), false, nil, err
+	case methodGetItem:
+		getItemInput := &dynamodb.GetItemInput{
+			TableName: &stmt.TableName,
+			Key:       plan.keys[0],
+		}
+		if plan.consistent {
+			getItemInput.ConsistentRead = aws.Bool(true)
+		}
+		if stmt.AttributesToGet[0] != "*" {
+			expr, err := expression.NewBuilder().WithProjection(buildProjection(stmt.AttributesToGet)).Build()
+			if err != nil {
+				return nil, true, nil, err
+			}
+			getItemInput.ExpressionAttributeNames = expressionNames(expr)
+			getItemInput.ProjectionExpression = expr.Projection()
+		}
+		if result, err := db.DynamoDB.GetItem(getItemInput); err == nil {
+			if result.Item == nil || (keep != nil && !keep(result.Item)) || stmt.Offset > 0 {
+				return []map[string]*dynamodb.AttributeValue{}, true, nil, nil
+			}
+			return []map[string]*dynamodb.AttributeValue{result.Item}, true, nil, nil
+		} else {
+			return nil, true, nil, err
+		}
+	}
+
+	// sorting client-side reads every matching item, up to SortLimit of them
+	limit := stmt.Limit
+	if plan.clientSort {
+		limit = SortLimit + 1
+		Warn(fmt.Sprintf("ORDER BY %s is sorted client-side, every matching item is read first", orderByText(plan.orderBy)))
+	}
+	// reading on after the last item needs its key, segments of a parallel Scan have one each
+	var resumeAttributes []string
+	if !plan.clientSort && plan.segments <= 1 && stmt.Limit > 0 {
+		if resumeAttributes, err = keyAttributes(plan); err != nil {
+			return nil, false, nil, err
+		}
+		if attributesToGet[0] != "*" {
+			for _, a := range resumeAttributes {
+				if utils.FindIndex(attributesToGet, a) == -1 {
+					attributesToGet = append(attributesToGet, a)
 				}
 			}
 		}
+	}
+	match := plan.matcher()
+	if keep != nil {
+		matched := match
+		match = func(item map[string]*dynamodb.AttributeValue) bool {
+			return matched(item) && keep(item)
+		}
+	}
+	// OFFSET skips the first items matched, or the first ones after sorting
+	if stmt.Offset > 0 && !plan.clientSort {
+		matched, skipped := match, int64(0)
+		match = func(item map[string]*dynamodb.AttributeValue) bool {
+			if !matched(item) {
+				return false
+			} else if skipped < stmt.Offset {
+				skipped++
+				return false
+			}
+			return true
+		}
+	}
+	queryInput, scanInput, err := plan.readInput(attributesToGet)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	if queryInput != nil {
+		if stmt.Limit > 0 && !plan.clientSort {
+			queryInput.Limit = aws.Int64(stmt.Limit + stmt.Offset)
+		}
+		queryInput.ExclusiveStartKey = exclusiveStartKey
+		items, err = queryUntilLimit(queryInput, limit, match,
+			[]map[string]*dynamodb.AttributeValue{})
 	} else {
-		return "", describeTableErr
+		// every item read is kept, so a page of LIMIT items is enough
+		if stmt.Limit > 0 && !plan.clientSort && len(plan.filters) == 0 && keep == nil {
+			scanInput.Limit = aws.Int64(stmt.Limit + stmt.Offset)
+		}
+		scanInput.ExclusiveStartKey = exclusiveStartKey
+		items, err = plan.scan(scanInput, limit, match)
+	}
+	if err != nil {
+		return items, false, nil, err
+	}
+	if !plan.clientSort {
+		if resumeAttributes != nil && int64(len(items)) == stmt.Limit {
+			resumeKey = keyOf(items[len(items)-1], resumeAttributes)
+			// an item without its key can't be read on after
+			for _, v := range resumeKey {
+				if v == nil {
+					resumeKey = nil
+					break
+				}
+			}
+		}
+		return items, false, resumeKey, nil
+	}
+	if int64(len(items)) > SortLimit {
+		return nil, false, nil, fmt.Errorf("More than %d items to sort client-side, narrow WHERE, ORDER BY the range key of a query or raise sort_limit", SortLimit)
+	}
+	sortItems(items, plan.orderBy, valueAt)
+	return limitItems(skipItems(items, stmt.Offset), stmt.Limit), false, nil, nil
+}
+
+// skipItems drops the first offset items
+func skipItems(items []map[string]*dynamodb.AttributeValue, offset int64) []map[string]*dynamodb.AttributeValue {
+	if offset >= int64(len(items)) {
+		return items[len(items):]
+	}
+	return items[offset:]
+}
+
+// limitItems keeps the first limit items, all of them if limit is negative
+func limitItems(items []map[string]*dynamodb.AttributeValue, limit int64) []map[string]*dynamodb.AttributeValue {
+	if limit >= 0 && int64(len(items)) > limit {
+		return items[:limit]
+	}
+	return items
+}
+
+// Select executes selectSQL string by parsing to dynamodb api
+func Select(selectSQL string) (string, error) {
+	stmt := sqlparser.ParseSelect(selectSQL)
+	if stmt.TableName == "" {
+		return "", errors.New("Can't utils.Find table name, check your inputs")
+	}
+	if InReadOnlyTransaction() {
+		if err := checkHints(stmt); err != nil {
+			return "", err
+		}
+		switch {
+		case stmt.TableAlias != "":
+			return "", errors.New("JOIN and table aliases are not read in a transaction, COMMIT or ROLLBACK first")
+		case stmt.IsAggregate():
+			return "", errors.New("Aggregates are not read in a transaction, COMMIT or ROLLBACK first")
+		case isComputed(stmt):
+			return "", errors.New("DISTINCT, aliases and computed columns are not read in a transaction, COMMIT or ROLLBACK first")
+		case stmt.Offset != 0 || stmt.After != "":
+			return "", errors.New("OFFSET and AFTER are not read in a transaction, COMMIT or ROLLBACK first")
+		case hasSubquery(stmt.Conditions):
+			return "", errors.New("Subqueries are not read in a transaction, COMMIT or ROLLBACK first")
+		case stmt.Hints.Index != "" || stmt.Hints.ForceScan || stmt.Hints.Parallel > 0:
+			return "", errors.New("A transaction reads items by their primary key, USE_INDEX, FORCE_SCAN and PARALLEL are not read in it, COMMIT or ROLLBACK first")
+		}
+		return bufferRead(selectSQL, stmt)
+	}
+	return selectRows(stmt)
+}
+
+// selectRows reads and prints the rows of a select statement,
+// it keeps where the statement stopped for NEXT
+func selectRows(stmt sqlparser.SelectStatement) (string, error) {
+	stmt, rows, isSingleRow, resumeKey, err := queryRows(stmt)
+	if err != nil {
+		return "", err
+	}
+	lastCursor = nil
+	if resumeKey != nil {
+		lastCursor = &cursor{stmt: stmt, key: resumeKey}
+	}
+	if isSingleRow {
+		// HAVING may leave no row
+		if len(rows) == 0 {
+			return utils.FormatPrettyMap(nil), nil
+		}
+		return utils.FormatPrettyMap(rows[0]), nil
+	}
+	return utils.FormatPrettyListOfMap(rows), nil
+}
+
+// queryRows runs the subqueries of a select statement and reads its rows, however they are read.
+// The statement is returned with the values of its subqueries, isSingleRow is true for GetItem and aggregates without GROUP BY,
+// resumeKey is where the statement stopped as selectItemsWith returns it
+func queryRows(stmt sqlparser.SelectStatement) (sqlparser.SelectStatement, []map[string]*dynamodb.AttributeValue, bool, map[string]*dynamodb.AttributeValue, error) {
+	stmt, empty, err := resolveSubqueries(stmt)
+	if err != nil {
+		return stmt, nil, false, nil, err
+	}
+	var rows []map[string]*dynamodb.AttributeValue
+	var isSingleRow bool
+	var resumeKey map[string]*dynamodb.AttributeValue
+	switch {
+	case empty:
+		rows, isSingleRow, err = noRows(stmt)
+	case stmt.TableAlias != "":
+		rows, isSingleRow, err = joinItems(stmt)
+	case stmt.IsAggregate():
+		rows, isSingleRow, err = aggregateItems(stmt)
+	case isComputed(stmt):
+		rows, isSingleRow, resumeKey, err = computeItems(stmt)
+	default:
+		rows, isSingleRow, resumeKey, err = selectItemsWith(stmt, nil)
+	}
+	return stmt, rows, isSingleRow, resumeKey, err
+}
+
+// Explain shows how the items of a select statement would be read, without reading them
+func Explain(explainSQL string) (string, error) {
+	selectSQL := sqlparser.ExplainRegexp.ReplaceAllString(explainSQL, "")
+	if !sqlparser.SelectRegexp.MatchString(selectSQL) {
+		return "", errors.New("EXPLAIN only takes SELECT, like EXPLAIN SELECT * FROM user WHERE user_id=1")
+	}
+	stmt := sqlparser.ParseSelect(selectSQL)
+	if stmt.TableName == "" {
+		return "", errors.New("Can't utils.Find table name, check your inputs")
+	}
+	paging, err := explainPaging(stmt)
+	if err != nil {
+		return "", err
+	}
+	subqueries := ""
+	for _, c := range stmt.Conditions {
+		if c.Subquery != "" {
+			subqueries += "\n  subquery: " + c.String() + ", run first"
+		}
+	}
+	stmt, empty, err := resolveSubqueries(stmt)
+	if err != nil {
+		return "", err
+	} else if empty {
+		return "Nothing to read, a subquery returned no value so no item matches" + subqueries, nil
+	}
+	plan, err := explainStatement(stmt, paging)
+	if err != nil {
+		return "", err
+	}
+	return plan + subqueries, nil
+}
+
+// explainStatement describes how a SELECT without subqueries is read
+func explainStatement(stmt sqlparser.SelectStatement, paging string) (string, error) {
+	if stmt.TableAlias != "" {
+		return explainJoin(stmt, paging)
+	}
+	if stmt.IsAggregate() {
+		agg, err := planAggregation(stmt)
+		if err != nil {
+			return "", err
+		}
+		plan, err := planSelect(sqlparser.SelectStatement{TableName: stmt.TableName, Conditions: stmt.Conditions, Hints: stmt.Hints})
+		if err != nil {
+			return "", err
+		}
+		return plan.explain() + "\n" + agg.explain(plan) + paging, nil
+	}
+	if isComputed(stmt) {
+		comp, err := planComputation(stmt)
+		if err != nil {
+			return "", err
+		}
+		plan, err := planSelect(comp.read(stmt))
+		if err != nil {
+			return "", err
+		}
+		return plan.explain() + "\n" + comp.explain() + paging, nil
+	}
+	plan, err := planSelect(stmt)
+	if err != nil {
+		return "", err
+	}
+	return plan.explain() + paging, nil
+}
+
+// explainPaging describes OFFSET and AFTER for EXPLAIN, empty without them
+func explainPaging(stmt sqlparser.SelectStatement) (string, error) {
+	lines := ""
+	if stmt.Offset < 0 {
+		return "", errors.New("OFFSET takes the number of items to skip, like LIMIT 10 OFFSET 20")
+	} else if stmt.Offset > 0 {
+		lines += fmt.Sprintf("\n  offset: the first %d are skipped", stmt.Offset)
+	}
+	if stmt.After != "" {
+		key, err := decodeCursor(stmt.After)
+		if err != nil {
+			return "", err
+		}
+		lines += "\n  after: " + utils.FormatKey(key)
 	}
+	return lines, nil
 }
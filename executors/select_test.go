@@ -0,0 +1,172 @@
+package executors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func Test_keyFromConditions(t *testing.T) {
+	type args struct {
+		keySchemas []string
+		conditions []sqlparser.Condition
+	}
+	tests := []struct {
+		name   string
+		args   args
+		want   map[string]*dynamodb.AttributeValue
+		wantOk bool
+	}{
+		{
+			name: "test keyFromConditions with full key",
+			args: args{
+				keySchemas: []string{"user_id", "created"},
+				conditions: []sqlparser.Condition{
+					{Key: "created", Operator: "=", Value: "1520000000"},
+					{Key: "user_id", Operator: "=", Value: `"abc"`},
+				},
+			},
+			want: map[string]*dynamodb.AttributeValue{
+				"user_id": {S: aws.String("abc")},
+				"created": {N: aws.String("1520000000")},
+			},
+			wantOk: true,
+		},
+		{
+			name: "test keyFromConditions with range key compared by >",
+			args: args{
+				keySchemas: []string{"user_id", "created"},
+				conditions: []sqlparser.Condition{
+					{Key: "user_id", Operator: "=", Value: "9527"},
+					{Key: "created", Operator: ">", Value: "1520000000"},
+				},
+			},
+			want: map[string]*dynamodb.AttributeValue{
+				"user_id": {N: aws.String("9527")},
+			},
+			wantOk: false,
+		},
+		{
+			name: "test keyFromConditions with a single quoted key",
+			args: args{
+				keySchemas: []string{"user_id", "created"},
+				conditions: []sqlparser.Condition{
+					{Key: "user_id", Operator: "=", Value: "'a1'"},
+					{Key: "created", Operator: "=", Value: "'1520000000'"},
+				},
+			},
+			want: map[string]*dynamodb.AttributeValue{
+				"user_id": {S: aws.String("a1")},
+				"created": {S: aws.String("1520000000")},
+			},
+			wantOk: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk := keyFromConditions(tt.args.keySchemas, tt.args.conditions)
+			if !reflect.DeepEqual(got, tt.want) || gotOk != tt.wantOk {
+				t.Errorf("keyFromConditions() = %v, %v, want %v, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_sortKeyCondition(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition sqlparser.Condition
+		want      expression.KeyConditionBuilder
+		wantOk    bool
+	}{
+		{
+			name:      "test sortKeyCondition with a number",
+			condition: sqlparser.Condition{Key: "ts", Operator: ">", Value: "1003"},
+			want:      expression.Key("ts").GreaterThan(expression.Value(&dynamodb.AttributeValue{N: aws.String("1003")})),
+			wantOk:    true,
+		},
+		{
+			name:      "test sortKeyCondition with a single quoted string",
+			condition: sqlparser.Condition{Key: "day", Operator: "=", Value: "'2018-03-02'"},
+			want:      expression.Key("day").Equal(expression.Value(&dynamodb.AttributeValue{S: aws.String("2018-03-02")})),
+			wantOk:    true,
+		},
+		{
+			name:      "test sortKeyCondition with !=",
+			condition: sqlparser.Condition{Key: "ts", Operator: "!=", Value: "1003"},
+			wantOk:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk := sortKeyCondition(tt.condition)
+			if gotOk != tt.wantOk || (tt.wantOk && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("sortKeyCondition() = %v, %v, want %v, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_selectPlan_explain(t *testing.T) {
+	tests := []struct {
+		name string
+		plan selectPlan
+		want string
+	}{
+		{
+			name: "test explain of GetItem",
+			plan: selectPlan{method: methodGetItem, tableName: "user", keys: []map[string]*dynamodb.AttributeValue{{"user_id": {N: aws.String("1")}}}},
+			want: "GetItem on user\n  key: {\"user_id\":1}",
+		},
+		{
+			name: "test explain of Query with client-side filters",
+			plan: selectPlan{
+				method:    methodQuery,
+				tableName: "orders",
+				keyConditions: []sqlparser.Condition{
+					{Key: "userId", Operator: "=", Value: "1"},
+					{Key: "ts", Operator: sqlparser.OpBetween, Value: "1001", High: "1003"},
+				},
+				filters: []sqlparser.Condition{
+					{Key: "status", Operator: "=", Value: "pending"},
+					{Key: "region", Operator: sqlparser.OpILike, Value: "'N%'"},
+				},
+				clientFilters: []sqlparser.Condition{{Key: "region", Operator: sqlparser.OpILike, Value: "'N%'"}},
+			},
+			want: "Query on orders\n  key condition: userId = 1 AND ts BETWEEN 1001 AND 1003\n  filter: status = pending\n" +
+				"  client-side filter: region ILIKE 'N%', matched on the items read",
+		},
+		{
+			name: "test explain of Scan",
+			plan: selectPlan{method: methodScan, tableName: "user", filters: []sqlparser.Condition{{Key: "tags", Operator: ">", Value: "1", Function: sqlparser.FunctionSize}}},
+			want: "Scan on user\n  filter: size(tags) > 1",
+		},
+		{
+			name: "test explain of Query in the order of the range key",
+			plan: selectPlan{
+				method:        methodQuery,
+				tableName:     "orders",
+				keyConditions: []sqlparser.Condition{{Key: "userId", Operator: "=", Value: "1"}},
+				rangeKey:      "ts",
+				orderBy:       []sqlparser.OrderBy{{Key: "ts", Desc: true}},
+			},
+			want: "Query on orders\n  key condition: userId = 1\n  order: ts DESC, by the range key",
+		},
+		{
+			name: "test explain of Scan sorted client-side",
+			plan: selectPlan{method: methodScan, tableName: "user", orderBy: []sqlparser.OrderBy{{Key: "country"}, {Key: "coins", Desc: true}}, clientSort: true},
+			want: "Scan on user\n  order: country, coins DESC, sorted client-side, up to 10000 items",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.plan.explain(); got != tt.want {
+				t.Errorf("explain() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
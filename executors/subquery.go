@@ -0,0 +1,230 @@
+package executors
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/FrontMage/dynamo.cli/utils"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// SubqueryLimit is the most rows a subquery of WHERE returns, a subquery returning more is refused
+// rather than turned into a list of values
+var SubqueryLimit int64 = 1000
+
+// hasSubquery tells whether some condition has a subquery
+func hasSubquery(conditions []sqlparser.Condition) bool {
+	for _, c := range conditions {
+		if c.Subquery != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSubqueries runs the subqueries of WHERE before the statement and writes their values into it,
+// IN (SELECT ...) becomes IN of the values selected and EXISTS (SELECT ...) becomes IN of the values of the outer attributes
+// it correlates to by =, or it's dropped if it holds without them.
+// empty is true when a condition can't hold, like IN of no value, then no item matches
+func resolveSubqueries(stmt sqlparser.SelectStatement) (resolved sqlparser.SelectStatement, empty bool, err error) {
+	if !hasSubquery(stmt.Conditions) {
+		return stmt, false, nil
+	}
+	conditions := []sqlparser.Condition{}
+	for _, c := range stmt.Conditions {
+		if c.Subquery == "" {
+			conditions = append(conditions, c)
+			continue
+		}
+		inner := sqlparser.ParseSubquery(c.Subquery)
+		if inner.TableName == "" {
+			return stmt, false, fmt.Errorf("Invalid subquery %s, like user_id IN (SELECT userId FROM orders WHERE status='pending')", c.Subquery)
+		}
+		var attributes, columns []string
+		if c.Operator == sqlparser.OpExists {
+			if attributes, columns, err = correlate(stmt, &inner); err != nil {
+				return stmt, false, err
+			}
+			if len(attributes) == 0 {
+				// an EXISTS of its own holds for every item or for none
+				inner.Limit = 1
+				rows, err := subqueryRows(inner)
+				if err != nil {
+					return stmt, false, err
+				}
+				empty = empty || len(rows) == 0
+				continue
+			}
+		} else {
+			attributes, columns = c.InAttributes(), inner.AttributesToGet
+			if len(columns) != len(attributes) || columns[0] == "*" {
+				return stmt, false, fmt.Errorf("The subquery of %s selects a column for each attribute before IN, like user_id IN (SELECT userId FROM orders)", c)
+			}
+		}
+		rows, err := subqueryRows(inner)
+		if err != nil {
+			return stmt, false, err
+		}
+		list, err := valueList(rows, columns)
+		if err != nil {
+			return stmt, false, fmt.Errorf("Can't use the values of %s, %v", c, err)
+		}
+		if len(list) == 0 {
+			empty = true
+			continue
+		}
+		resolved := sqlparser.Condition{Key: attributes[0], Operator: sqlparser.OpIn, Value: "(" + strings.Join(list, ", ") + ")", NextLogicalOperator: c.NextLogicalOperator}
+		if len(attributes) > 1 {
+			resolved.Key = "(" + strings.Join(attributes, ", ") + ")"
+		}
+		conditions = append(conditions, resolved)
+	}
+	stmt.Conditions = conditions
+	return stmt, empty, nil
+}
+
+// correlate splits the conditions of the subquery of EXISTS into its own and the ones of = to attributes of the outer statement,
+// like userId = users.user_id, the subquery then selects the distinct values of its side of them.
+// Attributes of the outer statement start with its table name, or with its aliases if it has them
+func correlate(stmt sqlparser.SelectStatement, inner *sqlparser.SelectStatement) (attributes []string, columns []string, err error) {
+	qualifiers, innerAliases := []string{stmt.TableName}, []string{inner.TableName}
+	if inner.TableAlias != "" {
+		innerAliases = []string{inner.TableAlias}
+	}
+	if stmt.TableAlias != "" {
+		qualifiers = []string{stmt.TableAlias}
+		for _, j := range stmt.Joins {
+			qualifiers = append(qualifiers, j.Alias)
+		}
+	}
+	for _, j := range inner.Joins {
+		innerAliases = append(innerAliases, j.Alias)
+	}
+	// outer returns the attribute of the outer statement s refers to, ok is false if it's not one
+	outer := func(s string) (string, bool) {
+		for _, q := range qualifiers {
+			if !strings.HasPrefix(s, q+".") || utils.FindIndex(innerAliases, q) != -1 {
+				continue
+			}
+			if _, err := sqlparser.ParsePath(s[len(q)+1:]); err != nil {
+				return "", false
+			}
+			if stmt.TableAlias != "" {
+				return s, true
+			}
+			return s[len(q)+1:], true
+		}
+		return "", false
+	}
+	conditions := []sqlparser.Condition{}
+	for _, c := range inner.Conditions {
+		keyAttribute, keyIsOuter := outer(c.Key)
+		valueAttribute, valueIsOuter := outer(c.Value)
+		switch {
+		case !keyIsOuter && !valueIsOuter:
+			conditions = append(conditions, c)
+			continue
+		case c.Operator != sqlparser.OpEq || c.Function != "" || (keyIsOuter && valueIsOuter):
+			return nil, nil, fmt.Errorf("EXISTS correlates by = of an attribute of its own and one of the outer table, not %s", c)
+		case valueIsOuter:
+			attributes, columns = append(attributes, valueAttribute), append(columns, c.Key)
+		default:
+			attributes, columns = append(attributes, keyAttribute), append(columns, c.Value)
+		}
+	}
+	inner.Conditions = conditions
+	if len(columns) > 0 {
+		inner.AttributesToGet, inner.Distinct = columns, true
+	}
+	return attributes, columns, nil
+}
+
+// subqueryRows reads the rows of a subquery, up to SubqueryLimit of them
+func subqueryRows(inner sqlparser.SelectStatement) ([]map[string]*dynamodb.AttributeValue, error) {
+	capped := inner.Limit < 0 || inner.Limit > SubqueryLimit
+	if capped {
+		inner.Limit = SubqueryLimit + 1
+	}
+	_, rows, _, _, err := queryRows(inner)
+	if err != nil {
+		return nil, err
+	}
+	if capped && int64(len(rows)) > SubqueryLimit {
+		return nil, fmt.Errorf("More than %d rows of subquery %s, narrow its WHERE or raise subquery_limit", SubqueryLimit, inner.TableName)
+	}
+	return rows, nil
+}
+
+// valueList writes the values of columns of rows as the literals of an IN list, tuples for several columns,
+// rows missing a value or repeating one are left out
+func valueList(rows []map[string]*dynamodb.AttributeValue, columns []string) ([]string, error) {
+	list := []string{}
+	seen := map[string]bool{}
+	for _, row := range rows {
+		literals, values := []string{}, []string{}
+		for _, column := range columns {
+			v := columnValue(row, column)
+			if v == nil || v.NULL != nil {
+				break
+			}
+			literal, err := literalText(v)
+			if err != nil {
+				return nil, err
+			}
+			literals, values = append(literals, literal), append(values, joinValue(v))
+		}
+		id := strings.Join(values, "\x00")
+		if len(literals) < len(columns) || seen[id] {
+			continue
+		}
+		seen[id] = true
+		if len(literals) == 1 {
+			list = append(list, literals[0])
+		} else {
+			list = append(list, "("+strings.Join(literals, ", ")+")")
+		}
+	}
+	return list, nil
+}
+
+// columnValue is the value of a column of SELECT in a row, by its name or by its path
+func columnValue(row map[string]*dynamodb.AttributeValue, column string) *dynamodb.AttributeValue {
+	expression, alias := sqlparser.ParseColumn(column)
+	if alias != "" {
+		return row[alias]
+	}
+	if v, ok := row[expression]; ok {
+		return v
+	}
+	return valueAt(row, expression)
+}
+
+// literalText writes a value as a literal of WHERE, which takes strings and whole numbers
+func literalText(v *dynamodb.AttributeValue) (string, error) {
+	switch {
+	case v.N != nil:
+		if _, err := strconv.ParseInt(*v.N, 10, 64); err == nil {
+			return *v.N, nil
+		}
+	case v.S != nil && !strings.Contains(*v.S, "'"):
+		return "'" + *v.S + "'", nil
+	case v.S != nil && !strings.Contains(*v.S, `"`):
+		return `"` + *v.S + `"`, nil
+	}
+	return "", errors.New("subqueries select strings and whole numbers, not " + utils.FormatKey(map[string]*dynamodb.AttributeValue{"value": v}))
+}
+
+// noRows are the rows of a statement no item matches, aggregates without GROUP BY still have their row
+func noRows(stmt sqlparser.SelectStatement) ([]map[string]*dynamodb.AttributeValue, bool, error) {
+	if !stmt.IsAggregate() {
+		return []map[string]*dynamodb.AttributeValue{}, false, nil
+	}
+	agg, err := planAggregation(stmt)
+	if err != nil {
+		return nil, false, err
+	}
+	return newAccumulator(agg).groupRows(stmt), len(agg.groupBy) == 0, nil
+}
@@ -0,0 +1,131 @@
+package executors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func Test_correlate(t *testing.T) {
+	tests := []struct {
+		name           string
+		outer          string
+		subquery       string
+		wantAttributes []string
+		wantColumns    []string
+		wantCount      int
+		wantErr        bool
+	}{
+		{
+			name:           "test correlate",
+			outer:          "SELECT * FROM user WHERE user_id IN (1, 2) END",
+			subquery:       "SELECT * FROM orders WHERE userId = user.user_id AND status = 'pending'",
+			wantAttributes: []string{"user_id"},
+			wantColumns:    []string{"userId"},
+			wantCount:      1,
+		},
+		{
+			name:           "test correlate with aliases",
+			outer:          "SELECT u.name FROM user u END",
+			subquery:       "SELECT * FROM orders o WHERE u.user_id = o.userId AND u.email = o.email",
+			wantAttributes: []string{"u.user_id", "u.email"},
+			wantColumns:    []string{"o.userId", "o.email"},
+		},
+		{
+			name:      "test correlate without outer attributes",
+			outer:     "SELECT * FROM user END",
+			subquery:  "SELECT * FROM orders WHERE userId = 1",
+			wantCount: 1,
+		},
+		{
+			name:      "test correlate with the outer table shadowed",
+			outer:     "SELECT * FROM user END",
+			subquery:  "SELECT * FROM user WHERE user.user_id = 1",
+			wantCount: 1,
+		},
+		{
+			name:     "test correlate with another operator",
+			outer:    "SELECT * FROM user END",
+			subquery: "SELECT * FROM orders WHERE userId > user.user_id",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := sqlparser.ParseSubquery(tt.subquery)
+			attributes, columns, err := correlate(sqlparser.ParseSelect(tt.outer), &inner)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("correlate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(attributes, tt.wantAttributes) || !reflect.DeepEqual(columns, tt.wantColumns) || len(inner.Conditions) != tt.wantCount {
+				t.Errorf("correlate() = %v, %v and %v, want %v, %v and %d conditions", attributes, columns, inner.Conditions, tt.wantAttributes, tt.wantColumns, tt.wantCount)
+			}
+		})
+	}
+}
+
+func Test_valueList(t *testing.T) {
+	n := func(s string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{N: aws.String(s)} }
+	s := func(s string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{S: aws.String(s)} }
+	tests := []struct {
+		name    string
+		rows    []map[string]*dynamodb.AttributeValue
+		columns []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "test valueList",
+			rows:    []map[string]*dynamodb.AttributeValue{{"id": n("1")}, {"id": n("2")}, {"id": n("1")}, {"other": n("3")}},
+			columns: []string{"id"},
+			want:    []string{"1", "2"},
+		},
+		{
+			name:    "test valueList with quotes",
+			rows:    []map[string]*dynamodb.AttributeValue{{"name": s("a")}, {"name": s("it's")}},
+			columns: []string{"name"},
+			want:    []string{"'a'", `"it's"`},
+		},
+		{
+			name:    "test valueList with tuples and paths",
+			rows:    []map[string]*dynamodb.AttributeValue{{"o": {M: map[string]*dynamodb.AttributeValue{"userId": n("1"), "ts": n("1001")}}}},
+			columns: []string{"o.userId", "o.ts"},
+			want:    []string{"(1, 1001)"},
+		},
+		{
+			name:    "test valueList with an alias",
+			rows:    []map[string]*dynamodb.AttributeValue{{"id": n("1")}},
+			columns: []string{"user_id AS id"},
+			want:    []string{"1"},
+		},
+		{
+			name:    "test valueList with NULL",
+			rows:    []map[string]*dynamodb.AttributeValue{{"id": {NULL: aws.Bool(true)}}},
+			columns: []string{"id"},
+			want:    []string{},
+		},
+		{
+			name:    "test valueList with a decimal",
+			rows:    []map[string]*dynamodb.AttributeValue{{"id": n("1.5")}},
+			columns: []string{"id"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := valueList(tt.rows, tt.columns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("valueList() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("valueList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,21 +1,29 @@
 package executors
 
 import (
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 )
 
 var doubleQuoteReg = regexp.MustCompile("^\"(.*?)\"$")
+var decimalReg = regexp.MustCompile(`^-?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
 
 func tryParseInt(s string) interface{} {
 	if intValue, err := strconv.Atoi(s); err == nil {
 		return intValue
 	} else if int64Value, err := strconv.ParseInt(s, 10, 64); err == nil {
 		return int64Value
+	} else if decimalReg.MatchString(s) {
+		// a decimal is sent as it's written, a float64 would round it
+		return dynamodbattribute.Number(s)
 	} else if doubleQuoteReg.MatchString(s) {
 		return strings.Trim(s, "\"")
 	} else {
@@ -23,29 +31,77 @@ func tryParseInt(s string) interface{} {
 	}
 }
 
+// attributeValueOf converts a value in sql to dynamodb AttributeValue, numbers become N, others become S
+func attributeValueOf(s string) *dynamodb.AttributeValue {
+	switch v := tryParseInt(s).(type) {
+	case string:
+		return &dynamodb.AttributeValue{S: aws.String(v)}
+	default:
+		return &dynamodb.AttributeValue{N: aws.String(fmt.Sprint(v))}
+	}
+}
+
+// checkConditions rejects a predicate which can't be parsed rather than reading or writing with a wrong condition
+func checkConditions(conditions []sqlparser.Condition) error {
+	for _, c := range conditions {
+		if c.Operator == sqlparser.OpInvalid {
+			return fmt.Errorf("Invalid condition %s, like id IN (1, 2), ts BETWEEN 1 AND 3 or begins_with(name, 'a')", c.Value)
+		}
+	}
+	return nil
+}
+
+// stringArgument is a string argument of a predicate function, quotes are optional
+func stringArgument(s string) string {
+	if av := literalValue(s); av.S != nil {
+		return *av.S
+	}
+	return s
+}
+
 func SwitchExpression(condition sqlparser.Condition) expression.ConditionBuilder {
+	name := pathName(condition.Key)
+	// size(x) compares the size of x with the value
+	var operand expression.OperandBuilder = name
+	if condition.Function == sqlparser.FunctionSize {
+		operand = name.Size()
+	}
+	// a quoted string is unquoted like in SET and VALUES, so WHERE x='v' matches what SET x='v' wrote
+	value := expression.Value(literalValue(condition.Value))
 	switch condition.Operator {
 	case "=":
-		return expression.Name(condition.Key).
-			Equal(expression.Value(tryParseInt(condition.Value)))
+		return expression.Equal(operand, value)
 	case ">":
-		return expression.Name(condition.Key).
-			GreaterThan(expression.Value(tryParseInt(condition.Value)))
+		return expression.GreaterThan(operand, value)
 	case "<":
-		return expression.Name(condition.Key).
-			LessThan(expression.Value(tryParseInt(condition.Value)))
+		return expression.LessThan(operand, value)
 	case ">=":
-		return expression.Name(condition.Key).
-			GreaterThanEqual(expression.Value(tryParseInt(condition.Value)))
+		return expression.GreaterThanEqual(operand, value)
 	case "<=":
-		return expression.Name(condition.Key).
-			LessThanEqual(expression.Value(tryParseInt(condition.Value)))
+		return expression.LessThanEqual(operand, value)
 	case "!=":
-		return expression.Name(condition.Key).
-			NotEqual(expression.Value(tryParseInt(condition.Value)))
-	case " LIKE ":
-		return expression.Name(condition.Key).Contains(condition.Value)
+		return expression.NotEqual(operand, value)
+	case sqlparser.OpBetween:
+		return expression.Between(operand, expression.Value(literalValue(condition.Value)), expression.Value(literalValue(condition.High)))
+	case sqlparser.OpLike, sqlparser.OpILike, sqlparser.OpRegexp:
+		return likeExpression(condition)
+	case sqlparser.OpIn:
+		return inExpression(condition)
+	case sqlparser.OpIsMissing, sqlparser.OpAttributeNotExists:
+		return name.AttributeNotExists()
+	case sqlparser.OpIsNotMissing, sqlparser.OpAttributeExists:
+		return name.AttributeExists()
+	case sqlparser.OpIsNull:
+		return name.AttributeType(expression.Null)
+	case sqlparser.OpIsNotNull:
+		return name.AttributeExists().And(expression.Not(name.AttributeType(expression.Null)))
+	case sqlparser.OpAttributeType:
+		return name.AttributeType(expression.DynamoDBAttributeType(strings.ToUpper(stringArgument(condition.Value))))
+	case sqlparser.OpBeginsWith:
+		return name.BeginsWith(stringArgument(condition.Value))
+	case sqlparser.OpContains:
+		return name.Contains(stringArgument(condition.Value))
 	default:
-		return expression.Name(condition.Key).Equal(expression.Value(condition.Value))
+		return name.Equal(expression.Value(condition.Value))
 	}
 }
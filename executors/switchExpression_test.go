@@ -5,6 +5,9 @@ import (
 	"testing"
 
 	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 )
 
@@ -27,6 +30,11 @@ func Test_tryParseInt(t *testing.T) {
 			args: args{s: "hi"},
 			want: "hi",
 		},
+		{
+			name: "test tryParseInt with decimal",
+			args: args{s: "-1.50"},
+			want: dynamodbattribute.Number("-1.50"),
+		},
 		{
 			name: "test tryParseInt with quoted string",
 			args: args{s: `"123"`},
@@ -61,7 +69,7 @@ func TestSwitchExpression(t *testing.T) {
 					NextLogicalOperator: "AND",
 				},
 			},
-			want: expression.Name("user_id").Equal(expression.Value(9527)),
+			want: expression.Name("user_id").Equal(expression.Value(&dynamodb.AttributeValue{N: aws.String("9527")})),
 		},
 		{
 			name: "test SwitchExpression with >",
@@ -73,7 +81,7 @@ func TestSwitchExpression(t *testing.T) {
 					NextLogicalOperator: "AND",
 				},
 			},
-			want: expression.Name("user_id").GreaterThan(expression.Value(9527)),
+			want: expression.Name("user_id").GreaterThan(expression.Value(&dynamodb.AttributeValue{N: aws.String("9527")})),
 		},
 		{
 			name: "test SwitchExpression with <",
@@ -85,7 +93,7 @@ func TestSwitchExpression(t *testing.T) {
 					NextLogicalOperator: "AND",
 				},
 			},
-			want: expression.Name("user_id").LessThan(expression.Value(9527)),
+			want: expression.Name("user_id").LessThan(expression.Value(&dynamodb.AttributeValue{N: aws.String("9527")})),
 		},
 		{
 			name: "test SwitchExpression with >=",
@@ -97,7 +105,7 @@ func TestSwitchExpression(t *testing.T) {
 					NextLogicalOperator: "AND",
 				},
 			},
-			want: expression.Name("user_id").GreaterThanEqual(expression.Value(9527)),
+			want: expression.Name("user_id").GreaterThanEqual(expression.Value(&dynamodb.AttributeValue{N: aws.String("9527")})),
 		},
 		{
 			name: "test SwitchExpression with <=",
@@ -109,7 +117,7 @@ func TestSwitchExpression(t *testing.T) {
 					NextLogicalOperator: "AND",
 				},
 			},
-			want: expression.Name("user_id").LessThanEqual(expression.Value(9527)),
+			want: expression.Name("user_id").LessThanEqual(expression.Value(&dynamodb.AttributeValue{N: aws.String("9527")})),
 		},
 		{
 			name: "test SwitchExpression with !=",
@@ -121,10 +129,34 @@ func TestSwitchExpression(t *testing.T) {
 					NextLogicalOperator: "AND",
 				},
 			},
-			want: expression.Name("user_id").NotEqual(expression.Value(9527)),
+			want: expression.Name("user_id").NotEqual(expression.Value(&dynamodb.AttributeValue{N: aws.String("9527")})),
+		},
+		{
+			name: "test SwitchExpression with a single quoted string",
+			args: args{
+				condition: sqlparser.Condition{
+					Key:                 "country",
+					Value:               "'NZ'",
+					Operator:            "=",
+					NextLogicalOperator: "AND",
+				},
+			},
+			want: expression.Name("country").Equal(expression.Value(&dynamodb.AttributeValue{S: aws.String("NZ")})),
+		},
+		{
+			name: "test SwitchExpression with a single quoted number",
+			args: args{
+				condition: sqlparser.Condition{
+					Key:                 "zip",
+					Value:               "'0600'",
+					Operator:            ">=",
+					NextLogicalOperator: "AND",
+				},
+			},
+			want: expression.Name("zip").GreaterThanEqual(expression.Value(&dynamodb.AttributeValue{S: aws.String("0600")})),
 		},
 		{
-			name: "test SwitchExpression with LIKE",
+			name: "test SwitchExpression with LIKE without wildcards",
 			args: args{
 				condition: sqlparser.Condition{
 					Key:                 "user_id",
@@ -133,7 +165,7 @@ func TestSwitchExpression(t *testing.T) {
 					NextLogicalOperator: "AND",
 				},
 			},
-			want: expression.Name("user_id").Contains("9527"),
+			want: expression.Name("user_id").Equal(expression.Value("9527")),
 		},
 		{
 			name: "test SwitchExpression with default",
@@ -147,6 +179,48 @@ func TestSwitchExpression(t *testing.T) {
 			},
 			want: expression.Name("user_id").Equal(expression.Value("9527")),
 		},
+		{
+			name: "test SwitchExpression with BETWEEN",
+			args: args{
+				condition: sqlparser.Condition{Key: "name", Value: "'a'", High: "'m'", Operator: sqlparser.OpBetween},
+			},
+			want: expression.Name("name").Between(expression.Value(&dynamodb.AttributeValue{S: aws.String("a")}), expression.Value(&dynamodb.AttributeValue{S: aws.String("m")})),
+		},
+		{
+			name: "test SwitchExpression with size",
+			args: args{
+				condition: sqlparser.Condition{Key: "tags", Value: "3", Operator: ">", Function: sqlparser.FunctionSize},
+			},
+			want: expression.Name("tags").Size().GreaterThan(expression.Value(&dynamodb.AttributeValue{N: aws.String("3")})),
+		},
+		{
+			name: "test SwitchExpression with IS MISSING",
+			args: args{
+				condition: sqlparser.Condition{Key: "email", Operator: sqlparser.OpIsMissing},
+			},
+			want: expression.Name("email").AttributeNotExists(),
+		},
+		{
+			name: "test SwitchExpression with IS NOT NULL",
+			args: args{
+				condition: sqlparser.Condition{Key: "email", Operator: sqlparser.OpIsNotNull},
+			},
+			want: expression.Name("email").AttributeExists().And(expression.Not(expression.Name("email").AttributeType(expression.Null))),
+		},
+		{
+			name: "test SwitchExpression with attribute_type",
+			args: args{
+				condition: sqlparser.Condition{Key: "tags", Value: "'ss'", Operator: sqlparser.OpAttributeType},
+			},
+			want: expression.Name("tags").AttributeType(expression.StringSet),
+		},
+		{
+			name: "test SwitchExpression with begins_with",
+			args: args{
+				condition: sqlparser.Condition{Key: "name", Value: "'user0'", Operator: sqlparser.OpBeginsWith},
+			},
+			want: expression.Name("name").BeginsWith("user0"),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -156,3 +230,67 @@ func TestSwitchExpression(t *testing.T) {
 		})
 	}
 }
+
+func Test_checkConditions(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr string
+	}{
+		{
+			name: "test checkConditions with valid conditions",
+			sql:  "SELECT * FROM user WHERE user_id IN (1, 2) AND ts BETWEEN 1 AND 3 END",
+		},
+		{
+			name:    "test checkConditions with BETWEEN without AND",
+			sql:     "SELECT * FROM user WHERE user_id=1 AND ts BETWEEN 1 END",
+			wantErr: "Invalid condition ts BETWEEN 1, like id IN (1, 2), ts BETWEEN 1 AND 3 or begins_with(name, 'a')",
+		},
+		{
+			name:    "test checkConditions with a function of wrong arity",
+			sql:     "SELECT * FROM user WHERE begins_with(name) END",
+			wantErr: "Invalid condition begins_with(name), like id IN (1, 2), ts BETWEEN 1 AND 3 or begins_with(name, 'a')",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkConditions(sqlparser.ParseSelect(tt.sql).Conditions)
+			if (err == nil && tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
+				t.Errorf("checkConditions() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_setThenWhere(t *testing.T) {
+	tests := []struct {
+		name      string
+		updateSQL string
+	}{
+		{name: "test a single quoted string", updateSQL: "UPDATE users SET tier='gold' WHERE tier='gold' END"},
+		{name: "test a double quoted string", updateSQL: `UPDATE users SET tier="gold" WHERE tier="gold" END`},
+		{name: "test a quoted number", updateSQL: "UPDATE users SET zip='0600' WHERE zip='0600' END"},
+		{name: "test a number", updateSQL: "UPDATE users SET coins=100 WHERE coins=100 END"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// what SET writes is what WHERE of the same statement compares with
+			stmt := sqlparser.ParseUpdate(tt.updateSQL)
+			update, err := buildUpdate(stmt.UpdateExpressions)
+			if err != nil {
+				t.Fatalf("buildUpdate() error = %v", err)
+			}
+			expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(SwitchExpression(stmt.Conditions[0])).Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			values := []*dynamodb.AttributeValue{}
+			for _, v := range expr.Values() {
+				values = append(values, v)
+			}
+			if len(values) != 2 || !reflect.DeepEqual(values[0], values[1]) {
+				t.Errorf("SET and WHERE values = %v, want the same value", values)
+			}
+		})
+	}
+}
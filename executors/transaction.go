@@ -0,0 +1,472 @@
+package executors
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/FrontMage/dynamo.cli/db"
+	"github.com/FrontMage/dynamo.cli/journal"
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/FrontMage/dynamo.cli/tables"
+	"github.com/FrontMage/dynamo.cli/utils"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// maxTransactionItems is the most items a TransactWriteItems or TransactGetItems takes
+const maxTransactionItems = 100
+
+// transactionItem is a buffered write, condition check or read along with the statement it comes from
+type transactionItem struct {
+	sql   string
+	table string
+	key   map[string]*dynamodb.AttributeValue
+	item  *dynamodb.TransactWriteItem
+	// write builds item with another condition required along with the one of the statement,
+	// COMMIT requires the items it read for the journal to be unchanged
+	write func(condition *expression.ConditionBuilder) (*dynamodb.TransactWriteItem, error)
+	// updateExpressions are the actions of an UPDATE, the item after it is worked out of them for the journal
+	updateExpressions []sqlparser.UpdateExpression
+	// get is the point lookup of a SELECT in a read-only transaction, item is nil then
+	get *dynamodb.TransactGetItem
+}
+
+// transaction buffers the writes between BEGIN and COMMIT, or the reads of BEGIN READ ONLY
+type transaction struct {
+	// token is the ClientRequestToken of COMMIT, a retried COMMIT is not applied twice
+	token      string
+	readOnly   bool
+	statements []string
+	items      []transactionItem
+	// oldItems are read on the first COMMIT for the journal, nil before
+	oldItems []map[string]*dynamodb.AttributeValue
+	// sent are the items of the first COMMIT, a retried COMMIT sends the same ones with the same token
+	sent []*dynamodb.TransactWriteItem
+}
+
+// activeTransaction is the transaction started by BEGIN, nil outside a transaction
+var activeTransaction *transaction
+
+// InTransaction tells whether writes are buffered until COMMIT
+func InTransaction() bool {
+	return activeTransaction != nil
+}
+
+// HasBufferedWrites tells whether COMMIT would write, a read-only connection must not commit them
+func HasBufferedWrites() bool {
+	return activeTransaction != nil && !activeTransaction.readOnly && len(activeTransaction.items) > 0
+}
+
+// InReadOnlyTransaction tells whether SELECTs are buffered until COMMIT and writes are rejected
+func InReadOnlyTransaction() bool {
+	return activeTransaction != nil && activeTransaction.readOnly
+}
+
+// Begin starts a transaction, INSERT, UPDATE, DELETE and CHECK are buffered until COMMIT,
+// with READ ONLY SELECTs of a primary key are buffered instead and read together on COMMIT
+func Begin(beginSQL string) (string, error) {
+	stmt := sqlparser.ParseBegin(beginSQL)
+	if stmt.Mode == "" {
+		return "", errors.New("Usage: BEGIN [READ ONLY]")
+	}
+	if activeTransaction != nil {
+		return "", errors.New("Already in a transaction, COMMIT or ROLLBACK first")
+	}
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	activeTransaction = &transaction{token: token, readOnly: stmt.Mode == sqlparser.TransactionReadOnly}
+	if activeTransaction.readOnly {
+		return "Read-only transaction started, SELECTs are read together on COMMIT", nil
+	}
+	return "Transaction started, writes are buffered until COMMIT", nil
+}
+
+// newToken returns a random ClientRequestToken
+func newToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// Rollback discards the buffered writes, nothing has been sent yet
+func Rollback(rollbackSQL string) (string, error) {
+	if activeTransaction == nil {
+		return "", errors.New("No transaction to roll back")
+	}
+	count := len(activeTransaction.statements)
+	activeTransaction = nil
+	return fmt.Sprintf("Rolled back, %s discarded", formatStatements(count)), nil
+}
+
+// Check buffers a condition check of an item, the transaction is canceled if it's not met on COMMIT
+func Check(checkSQL string) (string, error) {
+	if activeTransaction == nil {
+		return "", errors.New("CHECK is only allowed in a transaction, use BEGIN to start one")
+	}
+	stmt := sqlparser.ParseCheck(checkSQL)
+	if stmt.TableName == "" {
+		return "", errors.New("Can't parse CHECK, use CHECK table WHERE key=1 AND coins >= 100")
+	}
+	if err := checkConditions(stmt.Conditions); err != nil {
+		return "", err
+	}
+	tableDesc, err := tables.GetTableDesc(&stmt.TableName)
+	if err != nil {
+		return "", err
+	}
+	tableInfo := briefTable(tableDesc.Table)
+	key, ok := keyFromConditions(tableInfo.keySchemas, stmt.Conditions)
+	if !ok {
+		return "", fmt.Errorf("CHECK needs the primary key %s in WHERE", strings.Join(tableInfo.keySchemas, ", "))
+	}
+	if clientSide := clientSideConditions(stmt.Conditions); len(clientSide) > 0 {
+		return "", fmt.Errorf("CHECK can't match %s, DynamoDB only evaluates LIKE 'abc', 'abc%%' and '%%abc%%'", clientSide[0])
+	}
+	expr, err := expression.NewBuilder().WithCondition(writeCondition(tableInfo.keySchemas, stmt.Conditions)).Build()
+	if err != nil {
+		return "", err
+	}
+	keys := []map[string]*dynamodb.AttributeValue{key}
+	return bufferWrites(checkSQL, stmt.TableName, keys, nil, func(key map[string]*dynamodb.AttributeValue, _ *expression.ConditionBuilder) (*dynamodb.TransactWriteItem, error) {
+		return &dynamodb.TransactWriteItem{ConditionCheck: &dynamodb.ConditionCheck{
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expressionNames(expr),
+			ExpressionAttributeValues: expr.Values(),
+			Key:                       key,
+			TableName:                 aws.String(stmt.TableName),
+		}}, nil
+	})
+}
+
+// writeExpression builds the expression of a buffered write, every condition given is required
+func writeExpression(update *expression.UpdateBuilder, conditions ...*expression.ConditionBuilder) (expression.Expression, error) {
+	var condition *expression.ConditionBuilder
+	for _, c := range conditions {
+		if c == nil {
+			continue
+		}
+		combined := *c
+		if condition != nil {
+			combined = condition.And(*c)
+		}
+		condition = &combined
+	}
+	builder := expression.NewBuilder()
+	if update != nil {
+		builder = builder.WithUpdate(*update)
+	}
+	if condition != nil {
+		builder = builder.WithCondition(*condition)
+	} else if update == nil {
+		// the zero expression has no condition
+		return expression.Expression{}, nil
+	}
+	return builder.Build()
+}
+
+// unchangedSince requires an item to have the attributes it was read with, or to be still missing
+func unchangedSince(key, item map[string]*dynamodb.AttributeValue) expression.ConditionBuilder {
+	if item != nil {
+		return itemEquals(item)
+	}
+	names := []string{}
+	for name := range key {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return expression.AttributeNotExists(attributeName(names[0]))
+}
+
+// bufferWrites adds the writes of a statement to the transaction, one item of each key,
+// write builds the item of a key with another condition or nil
+func bufferWrites(sql, tableName string, keys []map[string]*dynamodb.AttributeValue, updateExpressions []sqlparser.UpdateExpression,
+	write func(key map[string]*dynamodb.AttributeValue, condition *expression.ConditionBuilder) (*dynamodb.TransactWriteItem, error)) (string, error) {
+	if activeTransaction.readOnly {
+		return "", errors.New("The transaction is READ ONLY, ROLLBACK and BEGIN to write")
+	}
+	if len(keys) == 0 {
+		return "", errors.New("No item matches, nothing is buffered")
+	}
+	sql = strings.TrimSuffix(sql, " END")
+	items := []transactionItem{}
+	for _, key := range keys {
+		key := key
+		item, err := write(key, nil)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, transactionItem{
+			sql:               sql,
+			table:             tableName,
+			key:               key,
+			item:              item,
+			updateExpressions: updateExpressions,
+			write: func(condition *expression.ConditionBuilder) (*dynamodb.TransactWriteItem, error) {
+				return write(key, condition)
+			},
+		})
+	}
+	if err := activeTransaction.add(sql, items); err != nil {
+		return "", err
+	}
+	return formatCount(len(keys), "buffered") + ", COMMIT to write", nil
+}
+
+// bufferRead adds the point lookup of a SELECT to a read-only transaction
+func bufferRead(selectSQL string, stmt sqlparser.SelectStatement) (string, error) {
+	tableDesc, err := tables.GetTableDesc(&stmt.TableName)
+	if err != nil {
+		return "", err
+	}
+	tableInfo := briefTable(tableDesc.Table)
+	key, ok := keyFromConditions(tableInfo.keySchemas, stmt.Conditions)
+	if !ok || len(key) != len(stmt.Conditions) {
+		return "", fmt.Errorf("Only a lookup of the primary key %s is read in a transaction, like SELECT * FROM %s WHERE %s=1",
+			strings.Join(tableInfo.keySchemas, ", "), stmt.TableName, tableInfo.hashKey)
+	}
+	get := &dynamodb.Get{Key: key, TableName: aws.String(stmt.TableName)}
+	if stmt.AttributesToGet[0] != "*" {
+		expr, err := expression.NewBuilder().WithProjection(buildProjection(stmt.AttributesToGet)).Build()
+		if err != nil {
+			return "", err
+		}
+		get.ExpressionAttributeNames = expressionNames(expr)
+		get.ProjectionExpression = expr.Projection()
+	}
+	sql := strings.TrimSuffix(selectSQL, " END")
+	item := transactionItem{sql: sql, table: stmt.TableName, key: key, get: &dynamodb.TransactGetItem{Get: get}}
+	if err := activeTransaction.add(sql, []transactionItem{item}); err != nil {
+		return "", err
+	}
+	return formatCount(1, "buffered") + ", COMMIT to read", nil
+}
+
+// add appends the items of a statement, dynamodb rejects a transaction touching an item twice
+func (tx *transaction) add(sql string, items []transactionItem) error {
+	if len(tx.items)+len(items) > maxTransactionItems {
+		return fmt.Errorf("A transaction takes up to %d items, this would make it %d", maxTransactionItems, len(tx.items)+len(items))
+	}
+	touched := map[string]string{}
+	for _, item := range append(tx.items[:len(tx.items):len(tx.items)], items...) {
+		id := item.table + " " + utils.FormatKey(item.key)
+		if by, ok := touched[id]; ok {
+			return fmt.Errorf("%s %s is already in this transaction by %s, an item can only be touched once", item.table, utils.FormatKey(item.key), by)
+		}
+		touched[id] = item.sql
+	}
+	tx.items = append(tx.items, items...)
+	tx.statements = append(tx.statements, sql)
+	return nil
+}
+
+// Commit sends the buffered writes in a single TransactWriteItems, either all of them are written or none
+func Commit(commitSQL string) (string, error) {
+	tx := activeTransaction
+	if tx == nil {
+		return "", errors.New("No transaction to commit, use BEGIN to start one")
+	}
+	if len(tx.items) == 0 {
+		activeTransaction = nil
+		return "Nothing to commit", nil
+	}
+	if tx.readOnly {
+		return tx.commitReads()
+	}
+
+	// a retried COMMIT sends what was sent the first time, it may have been written already
+	if tx.sent == nil {
+		if err := tx.prepare(); err != nil {
+			return "", err
+		}
+	}
+	_, err := db.DynamoDB.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		ClientRequestToken: aws.String(tx.token),
+		TransactItems:      tx.sent,
+	})
+	if canceled, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		if tx.oldItems != nil {
+			if changed, err := tx.changedSinceRead(canceled); err == nil && changed {
+				// a new token, the items sent next time are not the same
+				if token, err := newToken(); err == nil {
+					tx.token, tx.oldItems, tx.sent = token, nil, nil
+					return "", errors.New("Transaction canceled as an item was changed meanwhile, nothing is written\nThe transaction is kept, COMMIT again to retry")
+				}
+			}
+		}
+		activeTransaction = nil
+		return "", tx.canceled(canceled)
+	} else if err != nil {
+		return "", fmt.Errorf("%s\nThe transaction is kept, COMMIT again to retry, it's not applied twice", err)
+	}
+	activeTransaction = nil
+
+	// the items read before the commit are the ones written over, what they became is worked out of the writes
+	if tx.oldItems != nil {
+		statement := journal.NextStatement()
+		sql := "BEGIN; " + strings.Join(tx.statements, "; ") + "; COMMIT"
+		for idx, item := range tx.items {
+			switch {
+			case item.item.ConditionCheck != nil:
+			case item.item.Put != nil:
+				recordWrite(statement, sql, item.table, item.key, tx.oldItems[idx], item.item.Put.Item)
+			case item.item.Delete != nil:
+				recordWrite(statement, sql, item.table, item.key, tx.oldItems[idx], nil)
+			default:
+				recordUpdate(statement, sql, item.table, item.key, tx.oldItems[idx], item.updateExpressions)
+			}
+		}
+	}
+	written, checked := 0, 0
+	for _, item := range tx.items {
+		if item.item.ConditionCheck != nil {
+			checked++
+		} else {
+			written++
+		}
+	}
+	result := "Committed, " + formatCount(written, "written")
+	if checked > 0 {
+		result += fmt.Sprintf(", %d checked", checked)
+	}
+	return result, nil
+}
+
+// commitReads reads the buffered lookups in a single TransactGetItems, a consistent snapshot of the items,
+// each item is shown after its SELECT in order
+func (tx *transaction) commitReads() (string, error) {
+	getItems := []*dynamodb.TransactGetItem{}
+	for _, item := range tx.items {
+		getItems = append(getItems, item.get)
+	}
+	result, err := db.DynamoDB.TransactGetItems(&dynamodb.TransactGetItemsInput{TransactItems: getItems})
+	if canceled, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		activeTransaction = nil
+		return "", tx.canceled(canceled)
+	} else if err != nil {
+		return "", fmt.Errorf("%s\nThe transaction is kept, COMMIT again to retry", err)
+	}
+	activeTransaction = nil
+
+	results := []string{}
+	for idx, item := range tx.items {
+		var found map[string]*dynamodb.AttributeValue
+		if idx < len(result.Responses) && len(result.Responses[idx].Item) > 0 {
+			found = result.Responses[idx].Item
+		}
+		results = append(results, item.sql+"\n"+utils.FormatPrettyMap(found))
+	}
+	return strings.Join(results, "\n\n"), nil
+}
+
+// prepare builds the items COMMIT sends, with the journal on the items are read first
+// and each write requires its item to be unchanged since, so the journal has exactly what was written over
+func (tx *transaction) prepare() error {
+	sent := []*dynamodb.TransactWriteItem{}
+	if !journal.Enabled() {
+		for _, item := range tx.items {
+			sent = append(sent, item.item)
+		}
+		tx.sent = sent
+		return nil
+	}
+	oldItems, err := tx.readItems()
+	if err != nil {
+		return err
+	}
+	for idx, item := range tx.items {
+		if item.item.ConditionCheck != nil {
+			sent = append(sent, item.item)
+			continue
+		}
+		unchanged := unchangedSince(item.key, oldItems[idx])
+		written, err := item.write(&unchanged)
+		if err != nil {
+			return err
+		}
+		sent = append(sent, written)
+	}
+	tx.oldItems, tx.sent = oldItems, sent
+	return nil
+}
+
+// readItems reads the items the transaction writes in a single TransactGetItems, a consistent snapshot of them,
+// nil for condition checks and missing items
+func (tx *transaction) readItems() ([]map[string]*dynamodb.AttributeValue, error) {
+	items := make([]map[string]*dynamodb.AttributeValue, len(tx.items))
+	getItems, indexes := []*dynamodb.TransactGetItem{}, []int{}
+	for idx, item := range tx.items {
+		if item.item.ConditionCheck == nil {
+			getItems = append(getItems, &dynamodb.TransactGetItem{Get: &dynamodb.Get{Key: item.key, TableName: aws.String(item.table)}})
+			indexes = append(indexes, idx)
+		}
+	}
+	if len(getItems) == 0 {
+		return items, nil
+	}
+	result, err := db.DynamoDB.TransactGetItems(&dynamodb.TransactGetItemsInput{TransactItems: getItems})
+	if err != nil {
+		return nil, err
+	}
+	for idx, response := range result.Responses {
+		if idx < len(indexes) && len(response.Item) > 0 {
+			items[indexes[idx]] = response.Item
+		}
+	}
+	return items, nil
+}
+
+// changedSinceRead tells whether an item whose condition failed is not the one read before the commit,
+// the transaction was canceled by a concurrent write rather than by a condition of its statements
+func (tx *transaction) changedSinceRead(canceled *dynamodb.TransactionCanceledException) (bool, error) {
+	current, err := tx.readItems()
+	if err != nil {
+		return false, err
+	}
+	for idx, reason := range canceled.CancellationReasons {
+		if idx < len(tx.items) && aws.StringValue(reason.Code) == "ConditionalCheckFailed" &&
+			tx.items[idx].item.ConditionCheck == nil && !sameItem(current[idx], tx.oldItems[idx]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// canceled maps the cancellation reasons back to the statements, they are in the order of the items
+func (tx *transaction) canceled(canceled *dynamodb.TransactionCanceledException) error {
+	lines := []string{"Transaction canceled, nothing is written:"}
+	if tx.readOnly {
+		lines[0] = "Transaction canceled, nothing is read:"
+	}
+	for idx, reason := range canceled.CancellationReasons {
+		code := aws.StringValue(reason.Code)
+		if code == "" || code == "None" || idx >= len(tx.items) {
+			continue
+		}
+		why := code
+		switch code {
+		case "ConditionalCheckFailed":
+			why = "condition not met"
+		case "TransactionConflict":
+			why = "another write to the item is in progress, try again"
+		default:
+			if message := aws.StringValue(reason.Message); message != "" {
+				why += ", " + message
+			}
+		}
+		item := tx.items[idx]
+		lines = append(lines, fmt.Sprintf("  %s\n    %s %s: %s", item.sql, item.table, utils.FormatKey(item.key), why))
+	}
+	if len(lines) == 1 {
+		lines = append(lines, "  "+canceled.Message())
+	}
+	return errors.New(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,125 @@
+package executors
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func Test_bufferWrites(t *testing.T) {
+	keyOf := func(id string) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{"user_id": {N: aws.String(id)}}
+	}
+	write := func(key map[string]*dynamodb.AttributeValue, _ *expression.ConditionBuilder) (*dynamodb.TransactWriteItem, error) {
+		return &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{Key: key, TableName: aws.String("user")}}, nil
+	}
+	activeTransaction = &transaction{}
+	defer func() { activeTransaction = nil }()
+
+	if got, err := bufferWrites("DELETE FROM user WHERE user_id=1 END", "user", []map[string]*dynamodb.AttributeValue{keyOf("1")}, nil, write); err != nil || got != "1 item buffered, COMMIT to write" {
+		t.Errorf("bufferWrites() = %v, %v", got, err)
+	}
+	if _, err := bufferWrites("DELETE FROM orders WHERE user_id=1 END", "orders", []map[string]*dynamodb.AttributeValue{keyOf("1")}, nil, write); err != nil {
+		t.Errorf("bufferWrites() of another table error = %v", err)
+	}
+	want := `user {"user_id":1} is already in this transaction by DELETE FROM user WHERE user_id=1, an item can only be touched once`
+	if _, err := bufferWrites("UPDATE user SET a=1 WHERE user_id=1 END", "user", []map[string]*dynamodb.AttributeValue{keyOf("2"), keyOf("1")}, nil, write); err == nil || err.Error() != want {
+		t.Errorf("bufferWrites() of the same item error = %v, want %v", err, want)
+	}
+	if len(activeTransaction.items) != 2 || len(activeTransaction.statements) != 2 {
+		t.Errorf("bufferWrites() buffered %d items of %d statements, want 2 of 2", len(activeTransaction.items), len(activeTransaction.statements))
+	}
+
+	activeTransaction.readOnly = true
+	if _, err := bufferWrites("DELETE FROM user WHERE user_id=3 END", "user", []map[string]*dynamodb.AttributeValue{keyOf("3")}, nil, write); err == nil {
+		t.Errorf("bufferWrites() in a read-only transaction should fail")
+	}
+}
+
+func Test_transaction_canceled(t *testing.T) {
+	tx := &transaction{items: []transactionItem{
+		{sql: "UPDATE user SET coins = coins - 10 WHERE user_id=1", table: "user", key: map[string]*dynamodb.AttributeValue{"user_id": {N: aws.String("1")}}},
+		{sql: "CHECK user WHERE user_id=2", table: "user", key: map[string]*dynamodb.AttributeValue{"user_id": {N: aws.String("2")}}},
+	}}
+	tests := []struct {
+		name     string
+		canceled *dynamodb.TransactionCanceledException
+		want     string
+	}{
+		{
+			name: "test canceled by a condition",
+			canceled: &dynamodb.TransactionCanceledException{CancellationReasons: []*dynamodb.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("ConditionalCheckFailed"), Message: aws.String("The conditional request failed")},
+			}},
+			want: "Transaction canceled, nothing is written:\n  CHECK user WHERE user_id=2\n    user {\"user_id\":2}: condition not met",
+		},
+		{
+			name: "test canceled by validation",
+			canceled: &dynamodb.TransactionCanceledException{CancellationReasons: []*dynamodb.CancellationReason{
+				{Code: aws.String("ValidationError"), Message: aws.String("Item size has exceeded the maximum allowed size")},
+				{Code: aws.String("None")},
+			}},
+			want: "Transaction canceled, nothing is written:\n  UPDATE user SET coins = coins - 10 WHERE user_id=1\n    user {\"user_id\":1}: ValidationError, Item size has exceeded the maximum allowed size",
+		},
+		{
+			name:     "test canceled without reasons",
+			canceled: &dynamodb.TransactionCanceledException{Message_: aws.String("Transaction is ongoing for the idempotency token")},
+			want:     "Transaction canceled, nothing is written:\n  Transaction is ongoing for the idempotency token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tx.canceled(tt.canceled); got.Error() != tt.want {
+				t.Errorf("canceled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeExpression(t *testing.T) {
+	statement := expression.Name("coins").GreaterThanEqual(expression.Value(10))
+	unchanged := unchangedSince(map[string]*dynamodb.AttributeValue{"user_id": {N: aws.String("1")}}, nil)
+	update := expression.Set(expression.Name("coins"), expression.Value(0))
+	tests := []struct {
+		name          string
+		update        *expression.UpdateBuilder
+		conditions    []*expression.ConditionBuilder
+		wantCondition string
+		wantUpdate    string
+	}{
+		{
+			name:          "test writeExpression with both conditions",
+			update:        &update,
+			conditions:    []*expression.ConditionBuilder{&statement, &unchanged},
+			wantCondition: "(#0 >= :0) AND (attribute_not_exists (#1))",
+			wantUpdate:    "SET #0 = :1\n",
+		},
+		{
+			name:          "test writeExpression without the condition of the statement",
+			conditions:    []*expression.ConditionBuilder{nil, &unchanged},
+			wantCondition: "attribute_not_exists (#0)",
+		},
+		{
+			name:       "test writeExpression without conditions",
+			conditions: []*expression.ConditionBuilder{nil, nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := writeExpression(tt.update, tt.conditions...)
+			if err != nil {
+				t.Errorf("writeExpression() error = %v", err)
+				return
+			}
+			if got := aws.StringValue(expr.Condition()); got != tt.wantCondition {
+				t.Errorf("writeExpression() condition = %q, want %q", got, tt.wantCondition)
+			}
+			if got := aws.StringValue(expr.Update()); got != tt.wantUpdate {
+				t.Errorf("writeExpression() update = %q, want %q", got, tt.wantUpdate)
+			}
+		})
+	}
+}
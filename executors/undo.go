@@ -0,0 +1,265 @@
+package executors
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/FrontMage/dynamo.cli/db"
+	"github.com/FrontMage/dynamo.cli/journal"
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/FrontMage/dynamo.cli/utils"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// Undo restores the items written by the last n statements of the journal, newest first,
+// an item modified since is left as it is
+func Undo(undoSQL string) (string, error) {
+	stmt := sqlparser.ParseUndo(undoSQL)
+	if stmt.Count < 1 {
+		return "", errors.New("Usage: UNDO [n], n is the number of statements to undo")
+	}
+	if InTransaction() {
+		return "", errors.New("UNDO is not allowed in a transaction, COMMIT or ROLLBACK first")
+	}
+	if !journal.Enabled() {
+		return "", errors.New("The journal is off, nothing to undo")
+	}
+	statements, err := journal.Statements()
+	if err != nil {
+		return "", err
+	}
+	toUndo := []journal.Statement{}
+	for idx := len(statements) - 1; idx >= 0 && len(toUndo) < stmt.Count; idx-- {
+		if !statements[idx].Undone {
+			toUndo = append(toUndo, statements[idx])
+		}
+	}
+	if len(toUndo) == 0 {
+		return "Nothing to undo", nil
+	}
+
+	items := 0
+	lines := []string{"This will undo:"}
+	for _, s := range toUndo {
+		items += len(s.Entries)
+		lines = append(lines, fmt.Sprintf("  %s (%s)", s.SQL, formatCount(len(s.Entries), "written")))
+	}
+	if items > 1 {
+		lines = append(lines, "Continue?")
+		if !Confirm(strings.Join(lines, "\n")) {
+			return "", errors.New("Canceled, no item is restored")
+		}
+	}
+
+	restored := 0
+	conflicts := []string{}
+	for _, s := range toUndo {
+		for idx := len(s.Entries) - 1; idx >= 0; idx-- {
+			entry := s.Entries[idx]
+			if err := restore(entry); err == nil {
+				restored++
+			} else if err == errModifiedSince || isConditionFailed(err) {
+				conflicts = append(conflicts, fmt.Sprintf("  %s %s: modified since, not restored", entry.Table, utils.FormatKey(entry.Key)))
+			} else {
+				conflicts = append(conflicts, fmt.Sprintf("  %s %s: %s", entry.Table, utils.FormatKey(entry.Key), err))
+			}
+		}
+		// a statement is undone once, items which could not be restored are listed below
+		if err := journal.Record(journal.Entry{Statement: journal.NextStatement(), Undone: s.ID}); err != nil {
+			return "", err
+		}
+	}
+	result := fmt.Sprintf("%s undone, %s", formatStatements(len(toUndo)), formatCount(restored, "restored"))
+	if len(conflicts) > 0 {
+		result += "\n" + strings.Join(conflicts, "\n")
+	}
+	return result, nil
+}
+
+// errModifiedSince tells that an item is not restored as it was written after the journaled write
+var errModifiedSince = errors.New("modified since")
+
+// restore writes back the item before a journaled write, only if the item is still what the write left
+func restore(entry journal.Entry) error {
+	if len(entry.Updated) > 0 || len(entry.Removed) > 0 {
+		return revertUpdate(entry)
+	}
+	var condition expression.ConditionBuilder
+	if entry.New == nil {
+		// the item was deleted, it must not be created again since
+		for name := range entry.Key {
+			condition = expression.AttributeNotExists(attributeName(name))
+			break
+		}
+	} else {
+		// a condition can't tell an attribute added since, the item is compared as a whole first
+		// and the condition makes sure the attributes it had are not changed in between
+		current, err := getItem(entry.Table, entry.Key)
+		if err != nil {
+			return err
+		}
+		if !sameItem(current, entry.New) {
+			return errModifiedSince
+		}
+		condition = itemEquals(entry.New)
+	}
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return err
+	}
+
+	if entry.Old == nil {
+		// the item was created by the write
+		_, err = db.DynamoDB.DeleteItem(&dynamodb.DeleteItemInput{
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expressionNames(expr),
+			ExpressionAttributeValues: expr.Values(),
+			Key:                       entry.Key,
+			TableName:                 &entry.Table,
+		})
+		return err
+	}
+	_, err = db.DynamoDB.PutItem(&dynamodb.PutItemInput{
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expressionNames(expr),
+		ExpressionAttributeValues: expr.Values(),
+		Item:                      entry.Old,
+		TableName:                 &entry.Table,
+	})
+	return err
+}
+
+// revertUpdate puts back the values the paths touched by an UPDATE had before it, only while they still hold
+// what the update left, attributes written since by other statements are kept
+func revertUpdate(entry journal.Entry) error {
+	expr, err := revertExpression(entry)
+	if err != nil {
+		return err
+	}
+	_, err = db.DynamoDB.UpdateItem(&dynamodb.UpdateItemInput{
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expressionNames(expr),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+		Key:                       entry.Key,
+		TableName:                 &entry.Table,
+	})
+	return err
+}
+
+// revertExpression is the update and condition of revertUpdate
+func revertExpression(entry journal.Entry) (expression.Expression, error) {
+	paths := append([]string{}, entry.Removed...)
+	for path := range entry.Updated {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	// the item must still exist, a missing path would match a deleted item as well
+	keyNames := []string{}
+	for name := range entry.Key {
+		keyNames = append(keyNames, name)
+	}
+	sort.Strings(keyNames)
+	condition := expression.AttributeExists(attributeName(keyNames[0]))
+	var update expression.UpdateBuilder
+	for _, path := range paths {
+		if value, ok := entry.Updated[path]; ok {
+			condition = condition.And(pathName(path).Equal(expression.Value(value)))
+		} else {
+			condition = condition.And(expression.AttributeNotExists(pathName(path)))
+		}
+		if old := valueAt(entry.Old, path); old != nil {
+			update = update.Set(pathName(path), expression.Value(old))
+		} else {
+			update = update.Remove(pathName(path))
+		}
+	}
+	return expression.NewBuilder().WithCondition(condition).WithUpdate(update).Build()
+}
+
+// sameItem tells whether two items have the same attributes with the same values,
+// numbers are compared by value and sets regardless of the order of their elements
+func sameItem(a, b map[string]*dynamodb.AttributeValue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if other, ok := b[name]; !ok || !equalValues(value, other) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalValues tells whether two values are equal as DynamoDB compares them
+func equalValues(a, b *dynamodb.AttributeValue) bool {
+	sameElements := func(x, y []*string, same func(x, y *string) bool) bool {
+		if len(x) != len(y) {
+			return false
+		}
+		for _, e := range x {
+			found := false
+			for _, f := range y {
+				if same(e, f) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	switch {
+	case a == nil || b == nil:
+		return a == b
+	case a.N != nil && b.N != nil:
+		return sameValue(a, b)
+	case a.M != nil && b.M != nil:
+		return sameItem(a.M, b.M)
+	case a.L != nil && b.L != nil:
+		if len(a.L) != len(b.L) {
+			return false
+		}
+		for idx := range a.L {
+			if !equalValues(a.L[idx], b.L[idx]) {
+				return false
+			}
+		}
+		return true
+	case a.SS != nil && b.SS != nil:
+		return sameElements(a.SS, b.SS, func(x, y *string) bool { return *x == *y })
+	case a.NS != nil && b.NS != nil:
+		return sameElements(a.NS, b.NS, func(x, y *string) bool {
+			return sameValue(&dynamodb.AttributeValue{N: x}, &dynamodb.AttributeValue{N: y})
+		})
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// itemEquals requires every attribute of an item to have the same value
+func itemEquals(item map[string]*dynamodb.AttributeValue) expression.ConditionBuilder {
+	names := []string{}
+	for name := range item {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	condition := attributeName(names[0]).Equal(expression.Value(item[names[0]]))
+	for _, name := range names[1:] {
+		condition = condition.And(attributeName(name).Equal(expression.Value(item[name])))
+	}
+	return condition
+}
+
+// formatStatements returns something like "1 statement" or "3 statements"
+func formatStatements(count int) string {
+	if count == 1 {
+		return "1 statement"
+	}
+	return fmt.Sprintf("%d statements", count)
+}
@@ -0,0 +1,96 @@
+package executors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/journal"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func Test_revertExpression(t *testing.T) {
+	n := func(s string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{N: aws.String(s)} }
+	entry := journal.Entry{
+		Key:     journal.Item{"user_id": n("1")},
+		Old:     journal.Item{"user_id": n("1"), "coins": n("100"), "note": {S: aws.String("x")}},
+		Updated: journal.Item{"coins": n("105"), "visits": n("1")},
+		Removed: []string{"note"},
+	}
+	got, err := revertExpression(entry)
+	if err != nil {
+		t.Fatalf("revertExpression() error = %v", err)
+	}
+	want, _ := expression.NewBuilder().
+		WithCondition(expression.AttributeExists(attributeName("user_id")).
+			And(expression.Name("coins").Equal(expression.Value(n("105")))).
+			And(expression.AttributeNotExists(expression.Name("note"))).
+			And(expression.Name("visits").Equal(expression.Value(n("1"))))).
+		WithUpdate(expression.Set(expression.Name("coins"), expression.Value(n("100"))).
+			Set(expression.Name("note"), expression.Value(&dynamodb.AttributeValue{S: aws.String("x")})).
+			Remove(expression.Name("visits"))).
+		Build()
+	if got.Condition() == nil || *got.Condition() != *want.Condition() || *got.Update() != *want.Update() ||
+		!reflect.DeepEqual(expressionNames(got), expressionNames(want)) || !reflect.DeepEqual(got.Values(), want.Values()) {
+		t.Errorf("revertExpression() = %s %s %v, want %s %s %v", *got.Condition(), *got.Update(), expressionNames(got),
+			*want.Condition(), *want.Update(), expressionNames(want))
+	}
+}
+
+func Test_sameItem(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{
+		"user_id": {N: aws.String("1")},
+		"price":   {N: aws.String("1.50")},
+		"roles":   {SS: []*string{aws.String("admin"), aws.String("dev")}},
+		"address": {M: map[string]*dynamodb.AttributeValue{"city": {S: aws.String("Auckland")}}},
+	}
+	tests := []struct {
+		name  string
+		other map[string]*dynamodb.AttributeValue
+		want  bool
+	}{
+		{
+			name: "test sameItem with numbers and sets written differently",
+			other: map[string]*dynamodb.AttributeValue{
+				"user_id": {N: aws.String("1")},
+				"price":   {N: aws.String("1.5")},
+				"roles":   {SS: []*string{aws.String("dev"), aws.String("admin")}},
+				"address": {M: map[string]*dynamodb.AttributeValue{"city": {S: aws.String("Auckland")}}},
+			},
+			want: true,
+		},
+		{
+			name: "test sameItem with an attribute added",
+			other: map[string]*dynamodb.AttributeValue{
+				"user_id": {N: aws.String("1")},
+				"price":   {N: aws.String("1.5")},
+				"roles":   {SS: []*string{aws.String("admin"), aws.String("dev")}},
+				"address": {M: map[string]*dynamodb.AttributeValue{"city": {S: aws.String("Auckland")}}},
+				"tier":    {S: aws.String("gold")},
+			},
+			want: false,
+		},
+		{
+			name: "test sameItem with a nested value changed",
+			other: map[string]*dynamodb.AttributeValue{
+				"user_id": {N: aws.String("1")},
+				"price":   {N: aws.String("1.5")},
+				"roles":   {SS: []*string{aws.String("admin"), aws.String("dev")}},
+				"address": {M: map[string]*dynamodb.AttributeValue{"city": {S: aws.String("Wellington")}}},
+			},
+			want: false,
+		},
+		{
+			name: "test sameItem with a deleted item",
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameItem(item, tt.other); got != tt.want {
+				t.Errorf("sameItem() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
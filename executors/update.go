@@ -2,8 +2,11 @@ package executors
 
 import (
 	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/FrontMage/dynamo.cli/db"
+	"github.com/FrontMage/dynamo.cli/journal"
 	"github.com/FrontMage/dynamo.cli/sqlparser"
 	"github.com/FrontMage/dynamo.cli/utils"
 	"github.com/aws/aws-sdk-go/aws"
@@ -17,57 +20,168 @@ func Update(updateSQL string) (string, error) {
 	if stmt.TableName == "" {
 		return "", errors.New("Can't utils.Find table name, check your inputs")
 	}
+	if len(stmt.Conditions) == 0 && !stmt.All {
+		return "", errors.New("UPDATE without WHERE is not allowed, use WHERE ALL to update every item")
+	}
 
-	key := map[string]*dynamodb.AttributeValue{}
-	for _, c := range stmt.Conditions {
-		switch tryParseInt(c.Value).(type) {
-		case string:
-			key[c.Key] = &dynamodb.AttributeValue{
-				S: aws.String(c.Value),
-			}
-		case int:
-			key[c.Key] = &dynamodb.AttributeValue{
-				N: aws.String(c.Value),
-			}
-		case int64:
-			key[c.Key] = &dynamodb.AttributeValue{
-				N: aws.String(c.Value),
-			}
-		}
+	updateExpr, err := buildUpdate(stmt.UpdateExpressions)
+	if err != nil {
+		return "", err
 	}
 
-	var updateExpr expression.UpdateBuilder
+	targets, err := findWriteTargets(stmt.TableName, stmt.Conditions)
+	if err != nil {
+		return "", err
+	}
+	if !confirmWrite("update", stmt.TableName, targets.keys) {
+		return "", errors.New("Canceled, no item is updated")
+	}
 
-	for idx, u := range stmt.UpdateExpressions {
-		if idx == 0 {
-			updateExpr = expression.Set(expression.Name(u.Key), expression.Value(tryParseInt(u.Value)))
-		} else {
-			updateExpr = updateExpr.Set(expression.Name(u.Key), expression.Value(tryParseInt(u.Value)))
+	if InTransaction() {
+		if stmt.ReturnValues != "" {
+			return "", errors.New("RETURNING is not supported in a transaction")
 		}
+		return bufferWrites(updateSQL, stmt.TableName, targets.keys, stmt.UpdateExpressions, func(key map[string]*dynamodb.AttributeValue, condition *expression.ConditionBuilder) (*dynamodb.TransactWriteItem, error) {
+			expr, err := writeExpression(&updateExpr, targets.condition, condition)
+			if err != nil {
+				return nil, err
+			}
+			return &dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+				ConditionExpression:       expr.Condition(),
+				ExpressionAttributeNames:  expressionNames(expr),
+				ExpressionAttributeValues: expr.Values(),
+				UpdateExpression:          expr.Update(),
+				Key:                       key,
+				TableName:                 &stmt.TableName,
+			}}, nil
+		})
 	}
-
-	if expr, err := expression.NewBuilder().WithUpdate(updateExpr).Build(); err == nil {
-		updateInput := &dynamodb.UpdateItemInput{
-			ExpressionAttributeNames:  expr.Names(),
-			ExpressionAttributeValues: expr.Values(),
-			UpdateExpression:          expr.Update(),
-			Key:                       key,
-			TableName:                 &stmt.TableName,
+	builder := expression.NewBuilder().WithUpdate(updateExpr)
+	if targets.condition != nil {
+		builder = builder.WithCondition(*targets.condition)
+	}
+	// a multi-item update only prints returned items when RETURNING is given
+	returnValues := stmt.ReturnValues
+	if returnValues == "" && len(targets.keys) == 1 {
+		returnValues = sqlparser.ReturnAllNew
+	} else if returnValues == "" {
+		returnValues = sqlparser.ReturnNone
+	}
+	statement := journal.NextStatement()
+	if expr, err := builder.Build(); err == nil {
+		// the journal needs the item before the update, UpdateItem returns it and the item after the update
+		// is read back when RETURNING asks for it, otherwise it's what UpdateItem returns
+		requested := returnValues
+		if journal.Enabled() {
+			requested = sqlparser.ReturnAllOld
+		}
+		updateItem := func(key map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+			updateInput := &dynamodb.UpdateItemInput{
+				ExpressionAttributeNames:  expressionNames(expr),
+				ExpressionAttributeValues: expr.Values(),
+				ConditionExpression:       expr.Condition(),
+				UpdateExpression:          expr.Update(),
+				Key:                       key,
+				TableName:                 &stmt.TableName,
+				ReturnValues:              aws.String(requested),
+			}
+			result, err := db.DynamoDB.UpdateItem(updateInput)
+			if err != nil || !journal.Enabled() {
+				return result.Attributes, err
+			}
+			oldItem := result.Attributes
+			if len(oldItem) == 0 {
+				oldItem = nil
+			}
+			recordUpdate(statement, updateSQL, stmt.TableName, key, oldItem, stmt.UpdateExpressions)
+			var newItem map[string]*dynamodb.AttributeValue
+			if returnValues == sqlparser.ReturnAllNew || returnValues == sqlparser.ReturnUpdatedNew {
+				if newItem, err = getItem(stmt.TableName, key); err != nil {
+					Warn(fmt.Sprintf("%s is updated but can't be read back: %s", utils.FormatKey(key), err))
+				}
+			}
+			return returnedAttributes(returnValues, oldItem, newItem, updatedPaths(stmt.UpdateExpressions)), nil
 		}
 
-		if len(stmt.AttributesToGet) == 0 {
-			updateInput.SetReturnValues("ALL_NEW")
-		} else {
-			// TODO support return value filter, by default dynamodb does not support that
-			updateInput.SetReturnValues("ALL_NEW")
+		// a single item update shows the returned item
+		if len(targets.keys) == 1 {
+			if returned, err := updateItem(targets.keys[0]); err == nil {
+				// nothing is returned for NONE, nor for OLD of an item the update created
+				if len(returned) == 0 {
+					return formatCount(1, "updated"), nil
+				}
+				return utils.FormatPrettyMap(projectItem(returned, stmt.AttributesToGet)), nil
+			} else if isConditionFailed(err) {
+				return "", conditionNotMet("updated", stmt.TableName, targets.keys[0])
+			} else {
+				return "", err
+			}
 		}
 
-		if result, err := db.DynamoDB.UpdateItem(updateInput); err == nil {
-			return utils.FormatPrettyMap(result.Attributes), nil
-		} else {
-			return "", err
+		returned := []map[string]*dynamodb.AttributeValue{}
+		var mutex sync.Mutex
+		summary := writeEach(targets.keys, "updated", func(key map[string]*dynamodb.AttributeValue) error {
+			attributes, err := updateItem(key)
+			if err == nil && returnValues != sqlparser.ReturnNone {
+				// the key tells which item the returned attributes belong to
+				item := projectItem(attributes, stmt.AttributesToGet)
+				for name, value := range key {
+					item[name] = value
+				}
+				mutex.Lock()
+				returned = append(returned, item)
+				mutex.Unlock()
+			}
+			return err
+		})
+		if len(summary.failures) > 0 {
+			return "", errors.New(summary.format("updated"))
 		}
+		if returnValues != sqlparser.ReturnNone {
+			return utils.FormatPrettyListOfMap(returned) + "\n" + summary.format("updated"), nil
+		}
+		return summary.format("updated"), nil
 	} else {
 		return "", err
 	}
 }
+
+// returnedAttributes picks what RETURNING asks for from the item before and after the update as UpdateItem does,
+// UPDATED returns the paths the update touched, even those set to the value they had
+func returnedAttributes(returnValues string, oldItem, newItem map[string]*dynamodb.AttributeValue, paths []string) map[string]*dynamodb.AttributeValue {
+	switch returnValues {
+	case sqlparser.ReturnNone:
+		return nil
+	case sqlparser.ReturnAllOld:
+		return oldItem
+	case sqlparser.ReturnUpdatedOld:
+		return projectItem(oldItem, paths)
+	case sqlparser.ReturnUpdatedNew:
+		return projectItem(newItem, paths)
+	default:
+		return newItem
+	}
+}
+
+// projectItem keeps the attributes given by RETURNING or SELECT, all of them for * or none given,
+// a document path like address.city keeps its sub-document as a ProjectionExpression does,
+// dynamodb has no projection for the returned item so it's done here
+func projectItem(item map[string]*dynamodb.AttributeValue, attributesToGet []string) map[string]*dynamodb.AttributeValue {
+	if len(attributesToGet) == 0 || attributesToGet[0] == "" || attributesToGet[0] == "*" {
+		projected := map[string]*dynamodb.AttributeValue{}
+		for name, value := range item {
+			projected[name] = value
+		}
+		return projected
+	}
+	paths := [][]sqlparser.PathElement{}
+	for _, a := range attributesToGet {
+		if elements, err := sqlparser.ParsePath(a); err == nil {
+			paths = append(paths, elements)
+		}
+	}
+	if projected := projectValue(&dynamodb.AttributeValue{M: item}, paths); projected != nil {
+		return projected.M
+	}
+	return map[string]*dynamodb.AttributeValue{}
+}
@@ -0,0 +1,262 @@
+package executors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// buildUpdate converts the SET, REMOVE, ADD and DELETE actions of an update statement to an update expression
+func buildUpdate(updateExpressions []sqlparser.UpdateExpression) (expression.UpdateBuilder, error) {
+	var updateExpr expression.UpdateBuilder
+	if len(updateExpressions) == 0 {
+		return updateExpr, errors.New("Nothing to update, use SET, REMOVE, ADD or DELETE")
+	}
+	for _, u := range updateExpressions {
+		if u.Key == "" || (u.Action != sqlparser.ActionRemove && u.Value == "") {
+			return updateExpr, fmt.Errorf("Can't parse %s %s", u.Action, u.Key)
+		}
+		name := pathName(u.Key)
+		switch u.Action {
+		case sqlparser.ActionSet:
+			operand, err := operandOf(sqlparser.ParseOperand(u.Value))
+			if err != nil {
+				return updateExpr, err
+			}
+			updateExpr = updateExpr.Set(name, operand)
+		case sqlparser.ActionRemove:
+			updateExpr = updateExpr.Remove(name)
+		case sqlparser.ActionAdd:
+			updateExpr = updateExpr.Add(name, expression.Value(literalValue(u.Value)))
+		case sqlparser.ActionDelete:
+			updateExpr = updateExpr.Delete(name, expression.Value(literalValue(u.Value)))
+		}
+	}
+	return updateExpr, nil
+}
+
+// operandOf converts a parsed SET value to an operand of the expression builder
+func operandOf(operand sqlparser.Operand) (expression.OperandBuilder, error) {
+	if operand.Path != "" {
+		return pathName(operand.Path), nil
+	}
+	if operand.Function == "" {
+		return expression.Value(literalValue(operand.Value)), nil
+	}
+
+	if len(operand.Args) != 2 {
+		return nil, fmt.Errorf("%s takes 2 arguments", operand.Function)
+	}
+	if operand.Function == "+" || operand.Function == "-" {
+		for _, arg := range operand.Args {
+			if arg.Function == "+" || arg.Function == "-" {
+				return nil, errors.New("A SET value takes a single + or -, quote it if it's a string")
+			}
+		}
+	}
+	left, err := operandOf(operand.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	right, err := operandOf(operand.Args[1])
+	if err != nil {
+		return nil, err
+	}
+	switch operand.Function {
+	case "+":
+		return expression.Plus(left, right), nil
+	case "-":
+		return expression.Minus(left, right), nil
+	case "list_append":
+		return expression.ListAppend(left, right), nil
+	case "if_not_exists":
+		if operand.Args[0].Path == "" {
+			return nil, errors.New("The first argument of if_not_exists must be an attribute")
+		}
+		return expression.IfNotExists(pathName(operand.Args[0].Path), right), nil
+	default:
+		return nil, fmt.Errorf("Unknown function %s, supports list_append and if_not_exists", operand.Function)
+	}
+}
+
+// literalValue converts a literal to dynamodb AttributeValue,
+// ['a', 1] is a list, <<'a', 'b'>> is a string set, <<1, 2>> is a number set, and 'a' is a string
+func literalValue(s string) *dynamodb.AttributeValue {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		list := []*dynamodb.AttributeValue{}
+		if inner := strings.TrimSpace(s[1 : len(s)-1]); inner != "" {
+			for _, e := range sqlparser.SplitTopLevel(inner, ",") {
+				list = append(list, literalValue(e))
+			}
+		}
+		return &dynamodb.AttributeValue{L: list}
+	}
+	if strings.HasPrefix(s, "<<") && strings.HasSuffix(s, ">>") {
+		numbers, strs := []*string{}, []*string{}
+		for _, e := range sqlparser.SplitTopLevel(s[2:len(s)-2], ",") {
+			av := literalValue(e)
+			if av.N != nil {
+				numbers = append(numbers, av.N)
+			} else {
+				strs = append(strs, av.S)
+			}
+		}
+		if len(strs) == 0 {
+			return &dynamodb.AttributeValue{NS: numbers}
+		}
+		for _, n := range numbers {
+			strs = append(strs, n)
+		}
+		return &dynamodb.AttributeValue{SS: strs}
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return &dynamodb.AttributeValue{S: aws.String(s[1 : len(s)-1])}
+	}
+	return attributeValueOf(s)
+}
+
+// updatedValues works out what the actions of an update leave at the paths they touch, applied to the item before it
+// as DynamoDB applies them, removed are the paths left without a value.
+// UpdateItem returns a single image, the journal keeps the one before the update and the values after it come from here
+func updatedValues(item map[string]*dynamodb.AttributeValue, updateExpressions []sqlparser.UpdateExpression) (map[string]*dynamodb.AttributeValue, []string, error) {
+	updated := map[string]*dynamodb.AttributeValue{}
+	removed := []string{}
+	for _, u := range updateExpressions {
+		var value *dynamodb.AttributeValue
+		switch u.Action {
+		case sqlparser.ActionSet:
+			if value = operandValue(sqlparser.ParseOperand(u.Value), item); value == nil {
+				return nil, nil, fmt.Errorf("Can't work out SET %s = %s", u.Key, u.Value)
+			}
+		case sqlparser.ActionAdd:
+			current, add := valueAt(item, u.Key), literalValue(u.Value)
+			if current == nil {
+				value = add
+			} else if add.N != nil {
+				value = applyFunction("+", []*dynamodb.AttributeValue{current, add})
+			} else {
+				value = combineSets(current, add, false)
+			}
+			if value == nil {
+				return nil, nil, fmt.Errorf("Can't work out ADD %s %s", u.Key, u.Value)
+			}
+		case sqlparser.ActionDelete:
+			// a set left empty is removed
+			if current := valueAt(item, u.Key); current != nil {
+				value = combineSets(current, literalValue(u.Value), true)
+			}
+		}
+		if value == nil {
+			removed = append(removed, u.Key)
+		} else {
+			updated[u.Key] = value
+		}
+	}
+	return updated, removed, nil
+}
+
+// operandValue computes the value of a SET action on the item before the update, nil if it can't be computed
+func operandValue(operand sqlparser.Operand, item map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if operand.Path != "" {
+		return valueAt(item, operand.Path)
+	}
+	if operand.Function == "" {
+		return literalValue(operand.Value)
+	}
+	if len(operand.Args) != 2 {
+		return nil
+	}
+	if operand.Function == "if_not_exists" {
+		if current := valueAt(item, operand.Args[0].Path); current != nil {
+			return current
+		}
+		return operandValue(operand.Args[1], item)
+	}
+	left, right := operandValue(operand.Args[0], item), operandValue(operand.Args[1], item)
+	switch operand.Function {
+	case "+", "-":
+		return applyFunction(operand.Function, []*dynamodb.AttributeValue{left, right})
+	case "list_append":
+		if left == nil || right == nil || left.L == nil || right.L == nil {
+			return nil
+		}
+		return &dynamodb.AttributeValue{L: append(append([]*dynamodb.AttributeValue{}, left.L...), right.L...)}
+	}
+	return nil
+}
+
+// combineSets adds the elements of b to the set a, or takes them out of it with remove, nil if a is left empty
+// or the sets are not of the same type, numbers are compared by value
+func combineSets(a, b *dynamodb.AttributeValue, remove bool) *dynamodb.AttributeValue {
+	combine := func(a, b []*string, same func(x, y string) bool) []*string {
+		combined := []*string{}
+		has := func(list []*string, x string) bool {
+			for _, y := range list {
+				if same(x, *y) {
+					return true
+				}
+			}
+			return false
+		}
+		for _, x := range a {
+			if !remove || !has(b, *x) {
+				combined = append(combined, x)
+			}
+		}
+		for _, y := range b {
+			if !remove && !has(a, *y) {
+				combined = append(combined, y)
+			}
+		}
+		return combined
+	}
+	var combined *dynamodb.AttributeValue
+	switch {
+	case a.SS != nil && b.SS != nil:
+		combined = &dynamodb.AttributeValue{SS: combine(a.SS, b.SS, func(x, y string) bool { return x == y })}
+	case a.NS != nil && b.NS != nil:
+		combined = &dynamodb.AttributeValue{NS: combine(a.NS, b.NS, func(x, y string) bool {
+			return sameValue(&dynamodb.AttributeValue{N: &x}, &dynamodb.AttributeValue{N: &y})
+		})}
+	default:
+		return nil
+	}
+	if len(combined.SS) == 0 && len(combined.NS) == 0 {
+		return nil
+	}
+	return combined
+}
+
+// updatedItem is the item after an update, the item before it with the values of updatedValues put at their paths
+func updatedItem(item, updated map[string]*dynamodb.AttributeValue, removed []string) map[string]*dynamodb.AttributeValue {
+	value := &dynamodb.AttributeValue{M: item}
+	if item == nil {
+		value.M = map[string]*dynamodb.AttributeValue{}
+	}
+	for path, v := range updated {
+		if elements, err := sqlparser.ParsePath(path); err == nil {
+			value = withValueAt(value, elements, v)
+		}
+	}
+	for _, path := range removed {
+		if elements, err := sqlparser.ParsePath(path); err == nil {
+			value = withValueAt(value, elements, nil)
+		}
+	}
+	return value.M
+}
+
+// updatedPaths are the paths the actions of an update touch, what UPDATED_OLD and UPDATED_NEW return
+func updatedPaths(updateExpressions []sqlparser.UpdateExpression) []string {
+	paths := []string{}
+	for _, u := range updateExpressions {
+		paths = append(paths, u.Key)
+	}
+	return paths
+}
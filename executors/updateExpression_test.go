@@ -0,0 +1,200 @@
+package executors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func Test_buildUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    expression.UpdateBuilder
+		wantErr bool
+	}{
+		{
+			name: "test buildUpdate with SET",
+			sql:  "UPDATE user SET tier=gold, coins = coins + 100 WHERE user_id=1 END",
+			want: expression.Set(expression.Name("tier"), expression.Value(&dynamodb.AttributeValue{S: aws.String("gold")})).
+				Set(expression.Name("coins"), expression.Plus(expression.Name("coins"),
+					expression.Value(&dynamodb.AttributeValue{N: aws.String("100")}))),
+		},
+		{
+			name: "test buildUpdate with if_not_exists and list_append",
+			sql:  "UPDATE user SET created = if_not_exists(created, 123), tags = list_append(tags, ['vip']) WHERE user_id=1 END",
+			want: expression.Set(expression.Name("created"), expression.IfNotExists(expression.Name("created"),
+				expression.Value(&dynamodb.AttributeValue{N: aws.String("123")}))).
+				Set(expression.Name("tags"), expression.ListAppend(expression.Name("tags"),
+					expression.Value(&dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{{S: aws.String("vip")}}}))),
+		},
+		{
+			name: "test buildUpdate with REMOVE, ADD and DELETE",
+			sql:  "UPDATE user REMOVE tempField ADD visits 1 DELETE roles <<'admin'>> WHERE user_id=1 END",
+			want: expression.Remove(expression.Name("tempField")).
+				Add(expression.Name("visits"), expression.Value(&dynamodb.AttributeValue{N: aws.String("1")})).
+				Delete(expression.Name("roles"), expression.Value(&dynamodb.AttributeValue{SS: []*string{aws.String("admin")}})),
+		},
+		{
+			name: "test buildUpdate with decimals and + without spaces",
+			sql:  "UPDATE user SET coins=coins+1.5 ADD score 0.25 WHERE user_id=1 END",
+			want: expression.Set(expression.Name("coins"), expression.Plus(expression.Name("coins"),
+				expression.Value(&dynamodb.AttributeValue{N: aws.String("1.5")}))).
+				Add(expression.Name("score"), expression.Value(&dynamodb.AttributeValue{N: aws.String("0.25")})),
+		},
+		{
+			name:    "test buildUpdate with unquoted date",
+			sql:     "UPDATE user SET joined = 2020-01-01 WHERE user_id=1 END",
+			wantErr: true,
+		},
+		{
+			name:    "test buildUpdate with unknown function",
+			sql:     "UPDATE user SET coins = size(coins) WHERE user_id=1 END",
+			wantErr: true,
+		},
+		{
+			name:    "test buildUpdate without action",
+			sql:     "UPDATE user WHERE user_id=1 END",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildUpdate(sqlparser.ParseUpdate(tt.sql).UpdateExpressions)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("buildUpdate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildUpdate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_literalValue(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want *dynamodb.AttributeValue
+	}{
+		{
+			name: "test literalValue with quoted string",
+			s:    "'9527'",
+			want: &dynamodb.AttributeValue{S: aws.String("9527")},
+		},
+		{
+			name: "test literalValue with list",
+			s:    "['a', 1, []]",
+			want: &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{
+				{S: aws.String("a")}, {N: aws.String("1")}, {L: []*dynamodb.AttributeValue{}},
+			}},
+		},
+		{
+			name: "test literalValue with number set",
+			s:    "<<1, 2>>",
+			want: &dynamodb.AttributeValue{NS: []*string{aws.String("1"), aws.String("2")}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := literalValue(tt.s); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("literalValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_updatedValues(t *testing.T) {
+	n := func(s string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{N: aws.String(s)} }
+	s := func(s string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{S: aws.String(s)} }
+	item := map[string]*dynamodb.AttributeValue{
+		"user_id": n("1"),
+		"coins":   n("100"),
+		"tags":    {L: []*dynamodb.AttributeValue{s("new")}},
+		"roles":   {SS: []*string{aws.String("admin"), aws.String("dev")}},
+		"address": {M: map[string]*dynamodb.AttributeValue{"city": s("Auckland")}},
+		"note":    s("x"),
+	}
+	tests := []struct {
+		name        string
+		sql         string
+		wantUpdated map[string]*dynamodb.AttributeValue
+		wantRemoved []string
+		wantErr     bool
+	}{
+		{
+			name: "test updatedValues with arithmetic and functions",
+			sql:  "UPDATE user SET coins = coins - 25, tags = list_append(tags, ['vip']), created = if_not_exists(created, 123), tier = gold WHERE user_id=1 END",
+			wantUpdated: map[string]*dynamodb.AttributeValue{
+				"coins":   n("75"),
+				"tags":    {L: []*dynamodb.AttributeValue{s("new"), s("vip")}},
+				"created": n("123"),
+				"tier":    s("gold"),
+			},
+			wantRemoved: []string{},
+		},
+		{
+			name: "test updatedValues reads the item before the update",
+			sql:  "UPDATE user SET coins = coins + 1, total = if_not_exists(total, coins) WHERE user_id=1 END",
+			wantUpdated: map[string]*dynamodb.AttributeValue{
+				"coins": n("101"),
+				"total": n("100"),
+			},
+			wantRemoved: []string{},
+		},
+		{
+			name: "test updatedValues with nested path, REMOVE, ADD and DELETE",
+			sql:  "UPDATE user SET address.city = Wellington REMOVE note ADD visits 1, coins 5 DELETE roles <<'admin', 'dev'>> WHERE user_id=1 END",
+			wantUpdated: map[string]*dynamodb.AttributeValue{
+				"address.city": s("Wellington"),
+				"visits":       n("1"),
+				"coins":        n("105"),
+			},
+			wantRemoved: []string{"note", "roles"},
+		},
+		{
+			name:    "test updatedValues with arithmetic of a missing attribute",
+			sql:     "UPDATE user SET coins = missing + 1 WHERE user_id=1 END",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, removed, err := updatedValues(item, sqlparser.ParseUpdate(tt.sql).UpdateExpressions)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("updatedValues() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && (!reflect.DeepEqual(updated, tt.wantUpdated) || !reflect.DeepEqual(removed, tt.wantRemoved)) {
+				t.Errorf("updatedValues() = %v, %v, want %v, %v", updated, removed, tt.wantUpdated, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func Test_updatedItem(t *testing.T) {
+	s := func(s string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{S: aws.String(s)} }
+	item := map[string]*dynamodb.AttributeValue{
+		"user_id": s("1"),
+		"address": {M: map[string]*dynamodb.AttributeValue{"city": s("Auckland"), "zip": s("1010")}},
+		"tags":    {L: []*dynamodb.AttributeValue{s("a"), s("b")}},
+	}
+	got := updatedItem(item, map[string]*dynamodb.AttributeValue{"address.city": s("Wellington"), "tier": s("gold")}, []string{"address.zip", "tags[0]"})
+	want := map[string]*dynamodb.AttributeValue{
+		"user_id": s("1"),
+		"address": {M: map[string]*dynamodb.AttributeValue{"city": s("Wellington")}},
+		"tags":    {L: []*dynamodb.AttributeValue{s("b")}},
+		"tier":    s("gold"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("updatedItem() = %v, want %v", got, want)
+	}
+	if *item["address"].M["city"].S != "Auckland" || len(item["tags"].L) != 2 {
+		t.Errorf("updatedItem() changed the item before the update: %v", item)
+	}
+}
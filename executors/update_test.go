@@ -0,0 +1,118 @@
+package executors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func Test_projectItem(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{
+		"user_id": {N: aws.String("1")},
+		"coins":   {N: aws.String("100")},
+		"tier":    {S: aws.String("gold")},
+		"address": {M: map[string]*dynamodb.AttributeValue{"city": {S: aws.String("Auckland")}, "street": {S: aws.String("Queen")}}},
+		"items": {L: []*dynamodb.AttributeValue{
+			{M: map[string]*dynamodb.AttributeValue{"sku": {S: aws.String("a1")}, "qty": {N: aws.String("1")}}},
+			{M: map[string]*dynamodb.AttributeValue{"sku": {S: aws.String("b2")}}},
+			{M: map[string]*dynamodb.AttributeValue{"sku": {S: aws.String("c3")}, "qty": {N: aws.String("3")}}},
+		}},
+	}
+	tests := []struct {
+		name            string
+		attributesToGet []string
+		want            map[string]*dynamodb.AttributeValue
+	}{
+		{
+			name:            "test projectItem without attributes",
+			attributesToGet: []string{""},
+			want:            item,
+		},
+		{
+			name:            "test projectItem with *",
+			attributesToGet: []string{"*"},
+			want:            item,
+		},
+		{
+			name:            "test projectItem with attributes",
+			attributesToGet: []string{"coins", "missing"},
+			want:            map[string]*dynamodb.AttributeValue{"coins": {N: aws.String("100")}},
+		},
+		{
+			name:            "test projectItem with nested paths",
+			attributesToGet: []string{"address.city", "items[2].sku", "items[0]", "address.zip", "items[9]"},
+			want: map[string]*dynamodb.AttributeValue{
+				"address": {M: map[string]*dynamodb.AttributeValue{"city": {S: aws.String("Auckland")}}},
+				"items": {L: []*dynamodb.AttributeValue{
+					{M: map[string]*dynamodb.AttributeValue{"sku": {S: aws.String("a1")}, "qty": {N: aws.String("1")}}},
+					{M: map[string]*dynamodb.AttributeValue{"sku": {S: aws.String("c3")}}},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := projectItem(item, tt.attributesToGet); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("projectItem() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_returnedAttributes(t *testing.T) {
+	oldItem := map[string]*dynamodb.AttributeValue{
+		"user_id": {N: aws.String("1")},
+		"coins":   {N: aws.String("100")},
+		"tier":    {S: aws.String("gold")},
+		"note":    {S: aws.String("removed")},
+	}
+	newItem := map[string]*dynamodb.AttributeValue{
+		"user_id": {N: aws.String("1")},
+		"coins":   {N: aws.String("200")},
+		"tier":    {S: aws.String("gold")},
+	}
+	// tier is set to the value it had, it's returned all the same
+	paths := []string{"coins", "tier", "note"}
+	tests := []struct {
+		name         string
+		returnValues string
+		want         map[string]*dynamodb.AttributeValue
+	}{
+		{
+			name:         "test returnedAttributes with ALL_NEW",
+			returnValues: sqlparser.ReturnAllNew,
+			want:         newItem,
+		},
+		{
+			name:         "test returnedAttributes with ALL_OLD",
+			returnValues: sqlparser.ReturnAllOld,
+			want:         oldItem,
+		},
+		{
+			name:         "test returnedAttributes with UPDATED_NEW",
+			returnValues: sqlparser.ReturnUpdatedNew,
+			want:         map[string]*dynamodb.AttributeValue{"coins": {N: aws.String("200")}, "tier": {S: aws.String("gold")}},
+		},
+		{
+			name:         "test returnedAttributes with UPDATED_OLD",
+			returnValues: sqlparser.ReturnUpdatedOld,
+			want: map[string]*dynamodb.AttributeValue{
+				"coins": {N: aws.String("100")}, "tier": {S: aws.String("gold")}, "note": {S: aws.String("removed")},
+			},
+		},
+		{
+			name:         "test returnedAttributes with NONE",
+			returnValues: sqlparser.ReturnNone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := returnedAttributes(tt.returnValues, oldItem, newItem, paths); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("returnedAttributes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
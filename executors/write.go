@@ -0,0 +1,307 @@
+package executors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/FrontMage/dynamo.cli/db"
+	"github.com/FrontMage/dynamo.cli/journal"
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/FrontMage/dynamo.cli/tables"
+	"github.com/FrontMage/dynamo.cli/utils"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// Confirm asks whether a write touching more than one item should go on,
+// it declines by default, main asks the user or assumes yes with --yes
+var Confirm = func(question string) bool {
+	return false
+}
+
+// Progress shows the progress of a long running statement, main shows it next to the spinner
+var Progress = func(message string) {}
+
+// Warn tells about a statement which may be slow or costly, main prints it before the result
+var Warn = func(message string) {}
+
+// WriteConcurrency is how many items a multi-item write sends at the same time
+var WriteConcurrency = 8
+
+// interrupted is set by Interrupt, multi-item writes stop sending requests when it's set
+var interrupted int32
+
+// Interrupt stops the running multi-item write, items not written yet are left untouched
+func Interrupt() {
+	atomic.StoreInt32(&interrupted, 1)
+}
+
+// ClearInterrupt is called before running a statement so that an earlier Interrupt does not stop it
+func ClearInterrupt() {
+	atomic.StoreInt32(&interrupted, 0)
+}
+
+// confirmKeysShown is how many keys are shown when confirming a multi-item write,
+// and how many failures are listed in the summary
+const confirmKeysShown = 5
+
+// writeTargets are the items an UPDATE or DELETE touches
+type writeTargets struct {
+	keys []map[string]*dynamodb.AttributeValue
+	// condition makes sure an item found by a query or scan still matches when it's written,
+	// it's nil when the conditions are exactly the primary key
+	condition *expression.ConditionBuilder
+}
+
+// findWriteTargets returns the keys of items matched by the conditions of an UPDATE or DELETE,
+// no request is made if the conditions are exactly the primary key, empty conditions match every item
+func findWriteTargets(tableName string, conditions []sqlparser.Condition) (writeTargets, error) {
+	if err := checkConditions(conditions); err != nil {
+		return writeTargets{}, err
+	}
+	if hasSubquery(conditions) {
+		return writeTargets{}, errors.New("Subqueries are only read by SELECT, select the keys first and write them by IN")
+	}
+	tableDesc, err := tables.GetTableDesc(&tableName)
+	if err != nil {
+		return writeTargets{}, err
+	}
+	tableInfo := briefTable(tableDesc.Table)
+	// client-side conditions are matched on the items read, so they are read first
+	if key, ok := keyFromConditions(tableInfo.keySchemas, conditions); ok && len(clientSideConditions(conditions)) == 0 {
+		if len(key) == len(conditions) {
+			return writeTargets{keys: []map[string]*dynamodb.AttributeValue{key}}, nil
+		}
+		// predicates besides the primary key, like AND version=7, are checked by the write itself
+		condition := writeCondition(tableInfo.keySchemas, conditions)
+		return writeTargets{keys: []map[string]*dynamodb.AttributeValue{key}, condition: &condition}, nil
+	}
+	items, _, err := selectItems(sqlparser.SelectStatement{
+		AttributesToGet: tableInfo.keySchemas,
+		TableName:       tableName,
+		Conditions:      conditions,
+		Limit:           -1,
+	})
+	condition := writeCondition(tableInfo.keySchemas, conditions)
+	targets := writeTargets{keys: []map[string]*dynamodb.AttributeValue{}, condition: &condition}
+	for _, item := range items {
+		targets.keys = append(targets.keys, keyOf(item, tableInfo.keySchemas))
+	}
+	return targets, err
+}
+
+// writeCondition requires an item to exist and to match the non-key conditions,
+// so that an item changed after the query or scan is skipped instead of written,
+// client-side conditions are only narrowed down as they were matched when the items were read
+func writeCondition(keySchemas []string, conditions []sqlparser.Condition) expression.ConditionBuilder {
+	condition := expression.AttributeExists(attributeName(keySchemas[0]))
+	for _, c := range conditions {
+		if utils.FindIndex(keySchemas, c.Key) == -1 {
+			condition = condition.And(SwitchExpression(c))
+		}
+	}
+	return condition
+}
+
+// keyOf returns the primary key attributes of an item
+func keyOf(item map[string]*dynamodb.AttributeValue, keySchemas []string) map[string]*dynamodb.AttributeValue {
+	key := map[string]*dynamodb.AttributeValue{}
+	for _, schema := range keySchemas {
+		key[schema] = item[schema]
+	}
+	return key
+}
+
+// confirmWrite shows how many items a write would touch with the first few keys and asks for confirmation,
+// writes touching a single item are not asked
+func confirmWrite(action, tableName string, keys []map[string]*dynamodb.AttributeValue) bool {
+	if len(keys) <= 1 {
+		return true
+	}
+	lines := []string{fmt.Sprintf("This will %s about %d items of %s, first keys:", action, len(keys), tableName)}
+	for idx, key := range keys {
+		if idx == confirmKeysShown {
+			lines = append(lines, "  ...")
+			break
+		}
+		lines = append(lines, "  "+utils.FormatKey(key))
+	}
+	lines = append(lines, "Continue?")
+	// ctrl+c while asking cancels the write as well
+	return Confirm(strings.Join(lines, "\n")) && atomic.LoadInt32(&interrupted) == 0
+}
+
+// formatCount returns something like "1 item updated" or "3 items updated"
+func formatCount(count int, action string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d item %s", count, action)
+	}
+	return fmt.Sprintf("%d items %s", count, action)
+}
+
+// conditionNotMet explains why a single item write was rejected by its condition with the current item,
+// UpdateItem and DeleteItem of the aws-sdk in use have no ReturnValuesOnConditionCheckFailure so it's read again
+func conditionNotMet(action, tableName string, key map[string]*dynamodb.AttributeValue) error {
+	item, err := getItem(tableName, key)
+	if err != nil {
+		return fmt.Errorf("Condition not met, the item is not %s", action)
+	}
+	if item == nil {
+		return fmt.Errorf("Condition not met, the item is not %s as it does not exist", action)
+	}
+	return fmt.Errorf("Condition not met, the item is not %s, current item:\n%s", action, utils.FormatPrettyMap(item))
+}
+
+// getItem reads an item with a consistent read, it returns nil if the item does not exist
+var getItem = func(tableName string, key map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	result, err := db.DynamoDB.GetItem(&dynamodb.GetItemInput{
+		TableName:      &tableName,
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil || len(result.Item) == 0 {
+		return nil, err
+	}
+	return result.Item, nil
+}
+
+// recordWrite journals an item before and after a write so that UNDO can restore it
+func recordWrite(statement int64, sql, tableName string, key, oldItem, newItem map[string]*dynamodb.AttributeValue) {
+	recordEntry(journal.Entry{Statement: statement, SQL: sql, Table: tableName, Key: key, Old: oldItem, New: newItem})
+}
+
+// recordUpdate journals an item before an update along with the values the update left at the paths it touched,
+// an item created by the update is journaled as written
+func recordUpdate(statement int64, sql, tableName string, key, oldItem map[string]*dynamodb.AttributeValue,
+	updateExpressions []sqlparser.UpdateExpression) {
+	updated, removed, err := updatedValues(oldItem, updateExpressions)
+	if err != nil {
+		notJournaled(key, err)
+		return
+	}
+	if oldItem == nil {
+		recordWrite(statement, sql, tableName, key, nil, updatedItem(key, updated, removed))
+		return
+	}
+	recordEntry(journal.Entry{Statement: statement, SQL: sql, Table: tableName, Key: key, Old: oldItem, Updated: updated, Removed: removed})
+}
+
+// recordEntry appends an entry to the journal, a journal which can't be written does not fail the write
+func recordEntry(entry journal.Entry) {
+	entry.SQL = strings.TrimSuffix(entry.SQL, " END")
+	if err := journal.Record(entry); err != nil {
+		notJournaled(entry.Key, err)
+	}
+}
+
+// journalFailures counts the items written but not journaled, writeEach reports those of its write
+var journalFailures int32
+
+// notJournaled warns that UNDO won't restore an item whose write is not journaled
+func notJournaled(key map[string]*dynamodb.AttributeValue, err error) {
+	atomic.AddInt32(&journalFailures, 1)
+	Warn(fmt.Sprintf("Unable to write the journal, UNDO won't restore %s: %s", utils.FormatKey(key), err))
+}
+
+// isConditionFailed tells whether a write was rejected by its ConditionExpression
+func isConditionFailed(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+	}
+	return false
+}
+
+// writeSummary counts the outcome of a multi-item write
+type writeSummary struct {
+	written int
+	// skipped items did not match the condition anymore when they were written
+	skipped int
+	// notWritten items were left untouched because the write was interrupted
+	notWritten int
+	// notJournaled items were written but UNDO won't restore them
+	notJournaled int
+	failures     []string
+}
+
+// format returns something like "8 items updated, 1 skipped as no longer matching, 1 failed" with the failures
+func (s writeSummary) format(action string) string {
+	counts := []string{formatCount(s.written, action)}
+	if s.skipped > 0 {
+		counts = append(counts, fmt.Sprintf("%d skipped as no longer matching", s.skipped))
+	}
+	if len(s.failures) > 0 {
+		counts = append(counts, fmt.Sprintf("%d failed", len(s.failures)))
+	}
+	if s.notWritten > 0 {
+		counts = append(counts, fmt.Sprintf("%d not written as interrupted", s.notWritten))
+	}
+	if s.notJournaled > 0 {
+		counts = append(counts, fmt.Sprintf("%d not journaled, UNDO won't restore them", s.notJournaled))
+	}
+	lines := []string{strings.Join(counts, ", ")}
+	for idx, failure := range s.failures {
+		if idx == confirmKeysShown {
+			lines = append(lines, "  ...")
+			break
+		}
+		lines = append(lines, "  "+failure)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeEach writes every key with WriteConcurrency requests at a time and reports the progress,
+// a failed item does not stop the others
+func writeEach(keys []map[string]*dynamodb.AttributeValue, action string,
+	write func(key map[string]*dynamodb.AttributeValue) error) writeSummary {
+	workers := WriteConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	keyCh := make(chan map[string]*dynamodb.AttributeValue, len(keys))
+	for _, key := range keys {
+		keyCh <- key
+	}
+	close(keyCh)
+
+	summary := writeSummary{}
+	journalFailuresBefore := atomic.LoadInt32(&journalFailures)
+	done := 0
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keyCh {
+				var err error
+				isInterrupted := atomic.LoadInt32(&interrupted) == 1
+				if !isInterrupted {
+					err = write(key)
+				}
+
+				mutex.Lock()
+				if isInterrupted {
+					summary.notWritten++
+				} else if err == nil {
+					summary.written++
+				} else if isConditionFailed(err) {
+					summary.skipped++
+				} else {
+					summary.failures = append(summary.failures, fmt.Sprintf("%s: %s", utils.FormatKey(key), err))
+				}
+				done++
+				Progress(fmt.Sprintf("%d/%d %s", done, len(keys), action))
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	summary.notJournaled = int(atomic.LoadInt32(&journalFailures) - journalFailuresBefore)
+	return summary
+}
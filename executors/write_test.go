@@ -0,0 +1,309 @@
+package executors
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/FrontMage/dynamo.cli/journal"
+	"github.com/FrontMage/dynamo.cli/sqlparser"
+	"github.com/FrontMage/dynamo.cli/tables"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func Test_confirmWrite(t *testing.T) {
+	keysOf := func(n int) []map[string]*dynamodb.AttributeValue {
+		keys := []map[string]*dynamodb.AttributeValue{}
+		for i := 0; i < n; i++ {
+			keys = append(keys, map[string]*dynamodb.AttributeValue{"user_id": {N: aws.String(fmt.Sprint(i))}})
+		}
+		return keys
+	}
+	tests := []struct {
+		name         string
+		keys         []map[string]*dynamodb.AttributeValue
+		answer       bool
+		want         bool
+		wantQuestion []string
+	}{
+		{
+			name: "test confirmWrite with single item",
+			keys: keysOf(1),
+			want: true,
+		},
+		{
+			name:         "test confirmWrite with multiple items declined",
+			keys:         keysOf(7),
+			answer:       false,
+			want:         false,
+			wantQuestion: []string{"update about 7 items of user", `{"user_id":0}`, `{"user_id":4}`, "..."},
+		},
+		{
+			name:         "test confirmWrite with multiple items confirmed",
+			keys:         keysOf(2),
+			answer:       true,
+			want:         true,
+			wantQuestion: []string{"update about 2 items of user", `{"user_id":1}`},
+		},
+	}
+	defer func(confirm func(string) bool) { Confirm = confirm }(Confirm)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question := ""
+			Confirm = func(q string) bool {
+				question = q
+				return tt.answer
+			}
+			if got := confirmWrite("update", "user", tt.keys); got != tt.want {
+				t.Errorf("confirmWrite() = %v, want %v", got, tt.want)
+			}
+			for _, want := range tt.wantQuestion {
+				if !strings.Contains(question, want) {
+					t.Errorf("confirmWrite() asked %q, want it to contain %q", question, want)
+				}
+			}
+		})
+	}
+}
+
+func Test_writeCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		keySchemas []string
+		conditions []sqlparser.Condition
+		want       expression.ConditionBuilder
+	}{
+		{
+			name:       "test writeCondition with key conditions only",
+			keySchemas: []string{"userId", "ts"},
+			conditions: []sqlparser.Condition{
+				{Key: "userId", Operator: "=", Value: "1"},
+				{Key: "ts", Operator: ">", Value: "1003"},
+			},
+			want: expression.AttributeExists(expression.Name("userId")),
+		},
+		{
+			name:       "test writeCondition with non-key conditions",
+			keySchemas: []string{"userId", "ts"},
+			conditions: []sqlparser.Condition{
+				{Key: "userId", Operator: "=", Value: "1"},
+				{Key: "status", Operator: "=", Value: "pending"},
+			},
+			want: expression.AttributeExists(expression.Name("userId")).
+				And(expression.Name("status").Equal(expression.Value(&dynamodb.AttributeValue{S: aws.String("pending")}))),
+		},
+		{
+			name:       "test writeCondition with the full key and several non-key conditions",
+			keySchemas: []string{"userId", "ts"},
+			conditions: []sqlparser.Condition{
+				{Key: "userId", Operator: "=", Value: "1"},
+				{Key: "ts", Operator: "=", Value: "1003"},
+				{Key: "version", Operator: "=", Value: "7"},
+				{Key: "coins", Operator: ">", Value: "100"},
+			},
+			want: expression.AttributeExists(expression.Name("userId")).
+				And(expression.Name("version").Equal(expression.Value(&dynamodb.AttributeValue{N: aws.String("7")}))).
+				And(expression.Name("coins").GreaterThan(expression.Value(&dynamodb.AttributeValue{N: aws.String("100")}))),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := writeCondition(tt.keySchemas, tt.conditions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("writeCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findWriteTargets(t *testing.T) {
+	defer tables.ClearCache()
+	tables.ClearCache()
+	tables.TableInfoCache["user"] = &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+		ItemCount: aws.Int64(0),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("userId"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			{AttributeName: aws.String("ts"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+		},
+	}}
+	key := map[string]*dynamodb.AttributeValue{"userId": {N: aws.String("1")}, "ts": {N: aws.String("1003")}}
+	versionCondition := expression.AttributeExists(expression.Name("userId")).
+		And(expression.Name("version").Equal(expression.Value(&dynamodb.AttributeValue{N: aws.String("7")})))
+	tests := []struct {
+		name       string
+		conditions []sqlparser.Condition
+		want       writeTargets
+	}{
+		{
+			name: "test findWriteTargets with the primary key",
+			conditions: []sqlparser.Condition{
+				{Key: "userId", Operator: "=", Value: "1"},
+				{Key: "ts", Operator: "=", Value: "1003"},
+			},
+			want: writeTargets{keys: []map[string]*dynamodb.AttributeValue{key}},
+		},
+		{
+			name: "test findWriteTargets with the primary key and a non-key condition",
+			conditions: []sqlparser.Condition{
+				{Key: "userId", Operator: "=", Value: "1"},
+				{Key: "ts", Operator: "=", Value: "1003"},
+				{Key: "version", Operator: "=", Value: "7"},
+			},
+			want: writeTargets{keys: []map[string]*dynamodb.AttributeValue{key}, condition: &versionCondition},
+		},
+		{
+			name: "test findWriteTargets with a single quoted key",
+			conditions: []sqlparser.Condition{
+				{Key: "userId", Operator: "=", Value: "'a1'"},
+				{Key: "ts", Operator: "=", Value: "1003"},
+			},
+			want: writeTargets{keys: []map[string]*dynamodb.AttributeValue{
+				{"userId": {S: aws.String("a1")}, "ts": {N: aws.String("1003")}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findWriteTargets("user", tt.conditions)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findWriteTargets() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_conditionNotMet(t *testing.T) {
+	defer func(get func(string, map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error)) {
+		getItem = get
+	}(getItem)
+	key := map[string]*dynamodb.AttributeValue{"userId": {N: aws.String("1")}}
+	tests := []struct {
+		name    string
+		item    map[string]*dynamodb.AttributeValue
+		err     error
+		wantErr string
+	}{
+		{
+			name:    "test conditionNotMet with the current item",
+			item:    map[string]*dynamodb.AttributeValue{"userId": {N: aws.String("1")}, "version": {N: aws.String("8")}},
+			wantErr: "Condition not met, the item is not updated, current item:\n",
+		},
+		{
+			name:    "test conditionNotMet with a missing item",
+			wantErr: "Condition not met, the item is not updated as it does not exist",
+		},
+		{
+			name:    "test conditionNotMet unable to read the item",
+			err:     errors.New("throttled"),
+			wantErr: "Condition not met, the item is not updated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getItem = func(tableName string, k map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+				if tableName != "user" || !reflect.DeepEqual(k, key) {
+					t.Errorf("getItem(%v, %v), want user and %v", tableName, k, key)
+				}
+				return tt.item, tt.err
+			}
+			err := conditionNotMet("updated", "user", key)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("conditionNotMet() = %v, want %q", err, tt.wantErr)
+			}
+			if tt.item != nil && !strings.Contains(err.Error(), "version") {
+				t.Errorf("conditionNotMet() = %v, want the current item", err)
+			}
+			if tt.item == nil && err.Error() != tt.wantErr {
+				t.Errorf("conditionNotMet() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_writeEach(t *testing.T) {
+	keys := []map[string]*dynamodb.AttributeValue{}
+	for i := 0; i < 20; i++ {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{"user_id": {N: aws.String(fmt.Sprint(i))}})
+	}
+	// the journal can't be written under a file
+	dir, err := ioutil.TempDir("", "dynamo.cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer func(path string, warn func(string)) { journal.Path, Warn = path, warn }(journal.Path, Warn)
+	journal.Path = filepath.Join(dir, "file", "journal.jsonl")
+	var warnings int32
+	Warn = func(message string) {
+		if strings.HasPrefix(message, "Unable to write the journal, UNDO won't restore {\"user_id\":") {
+			atomic.AddInt32(&warnings, 1)
+		}
+	}
+	tests := []struct {
+		name        string
+		write       func(key map[string]*dynamodb.AttributeValue) error
+		interrupt   bool
+		wantSummary string
+	}{
+		{
+			name:        "test writeEach with all items written",
+			write:       func(key map[string]*dynamodb.AttributeValue) error { return nil },
+			wantSummary: "20 items updated",
+		},
+		{
+			name: "test writeEach with skipped and failed items",
+			write: func(key map[string]*dynamodb.AttributeValue) error {
+				switch *key["user_id"].N {
+				case "3", "4":
+					return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+				case "5":
+					return errors.New("throttled")
+				}
+				return nil
+			},
+			wantSummary: "17 items updated, 2 skipped as no longer matching, 1 failed\n  {\"user_id\":5}: throttled",
+		},
+		{
+			name: "test writeEach with items not journaled",
+			write: func(key map[string]*dynamodb.AttributeValue) error {
+				if *key["user_id"].N == "3" || *key["user_id"].N == "4" {
+					recordWrite(1, "DELETE FROM user WHERE user_id="+*key["user_id"].N, "user", key, key, nil)
+				}
+				return nil
+			},
+			wantSummary: "20 items updated, 2 not journaled, UNDO won't restore them",
+		},
+		{
+			name:        "test writeEach interrupted",
+			write:       func(key map[string]*dynamodb.AttributeValue) error { return nil },
+			interrupt:   true,
+			wantSummary: "0 items updated, 20 not written as interrupted",
+		},
+	}
+	defer ClearInterrupt()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ClearInterrupt()
+			if tt.interrupt {
+				Interrupt()
+			}
+			if got := writeEach(keys, "updated", tt.write).format("updated"); got != tt.wantSummary {
+				t.Errorf("writeEach() = %q, want %q", got, tt.wantSummary)
+			}
+		})
+	}
+	if warnings != 2 {
+		t.Errorf("Warn() called %d times for the journal, want 2", warnings)
+	}
+}
@@ -0,0 +1,214 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Path is the append-only journal file, writes are not journaled when it's empty
+var Path = ""
+
+// Connection identifies the connected dynamodb, UNDO only restores items written through the same connection
+var Connection = ""
+
+var mutex sync.Mutex
+
+// Item is an item or a key, it's kept as DynamoDB JSON so that sets and numbers are restored as they were
+type Item map[string]*dynamodb.AttributeValue
+
+// MarshalJSON writes only the type of each attribute which is set, like {"user_id":{"N":"1"}}
+func (i Item) MarshalJSON() ([]byte, error) {
+	if i == nil {
+		return []byte("null"), nil
+	}
+	m := map[string]interface{}{}
+	for name, value := range i {
+		m[name] = compact(value)
+	}
+	return json.Marshal(m)
+}
+
+// compact drops the empty types of an AttributeValue, which json would write as null
+func compact(av *dynamodb.AttributeValue) interface{} {
+	switch {
+	case av == nil:
+		return nil
+	case av.S != nil:
+		return map[string]interface{}{"S": av.S}
+	case av.N != nil:
+		return map[string]interface{}{"N": av.N}
+	case av.B != nil:
+		return map[string]interface{}{"B": av.B}
+	case av.BOOL != nil:
+		return map[string]interface{}{"BOOL": av.BOOL}
+	case av.NULL != nil:
+		return map[string]interface{}{"NULL": av.NULL}
+	case av.SS != nil:
+		return map[string]interface{}{"SS": av.SS}
+	case av.NS != nil:
+		return map[string]interface{}{"NS": av.NS}
+	case av.BS != nil:
+		return map[string]interface{}{"BS": av.BS}
+	case av.L != nil:
+		list := []interface{}{}
+		for _, e := range av.L {
+			list = append(list, compact(e))
+		}
+		return map[string]interface{}{"L": list}
+	case av.M != nil:
+		return map[string]interface{}{"M": Item(av.M)}
+	}
+	return map[string]interface{}{}
+}
+
+// Entry is one item written by a statement, or an UNDO of a statement
+type Entry struct {
+	// Statement is shared by the items written by one statement
+	Statement  int64     `json:"statement"`
+	Time       time.Time `json:"time"`
+	Connection string    `json:"connection"`
+	SQL        string    `json:"sql,omitempty"`
+	Table      string    `json:"table,omitempty"`
+	Key        Item      `json:"key,omitempty"`
+	// Old is the item before the write, nil if the item did not exist
+	Old Item `json:"old,omitempty"`
+	// New is the item written by INSERT or created by UPDATE, nil if the item was deleted or updated
+	New Item `json:"new,omitempty"`
+	// Updated holds the paths an UPDATE set with the value it left, Removed the paths it left without a value,
+	// UNDO puts back their old value only while they still hold that
+	Updated Item     `json:"updated,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	// Undone is the statement an UNDO restored
+	Undone int64 `json:"undone,omitempty"`
+}
+
+// Statement is the journal of one statement
+type Statement struct {
+	ID      int64
+	SQL     string
+	Time    time.Time
+	Entries []Entry
+	Undone  bool
+}
+
+// NextStatement returns the id of a new statement, it's the time so that it keeps growing across sessions
+func NextStatement() int64 {
+	return time.Now().UnixNano()
+}
+
+// Enabled tells whether writes are journaled
+func Enabled() bool {
+	return Path != ""
+}
+
+// Record appends an entry to the journal
+func Record(entry Entry) error {
+	if !Enabled() {
+		return nil
+	}
+	entry.Time = time.Now()
+	entry.Connection = Connection
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if err := os.MkdirAll(filepath.Dir(Path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// read keeps what Statements has read of the journal, the journal is only appended to
+// so the next call reads only the entries added since
+var read struct {
+	path       string
+	connection string
+	offset     int64
+	line       int
+	statements []Statement
+	indexes    map[int64]int
+	undone     map[int64]bool
+}
+
+// Statements returns the journaled statements of the active connection, the oldest first
+func Statements() ([]Statement, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	f, err := os.Open(Path)
+	if os.IsNotExist(err) {
+		return []Statement{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if read.path != Path || read.connection != Connection || info.Size() < read.offset {
+		read.path, read.connection, read.offset, read.line = Path, Connection, 0, 0
+		read.statements, read.indexes, read.undone = []Statement{}, map[int64]int{}, map[int64]bool{}
+	}
+	if _, err := f.Seek(read.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// an entry holds up to two items of 400KB each, lines are read whole however long they are
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// a line without its newline is still being written, it's read by the next call
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		entry := Entry{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("Broken journal %s at line %d: %s", Path, read.line+1, err)
+		}
+		read.offset += int64(len(line))
+		read.line++
+		if entry.Connection != Connection {
+			continue
+		}
+		if entry.Undone != 0 {
+			read.undone[entry.Undone] = true
+			continue
+		}
+		if idx, ok := read.indexes[entry.Statement]; ok {
+			read.statements[idx].Entries = append(read.statements[idx].Entries, entry)
+		} else {
+			read.indexes[entry.Statement] = len(read.statements)
+			read.statements = append(read.statements, Statement{
+				ID:      entry.Statement,
+				SQL:     entry.SQL,
+				Time:    entry.Time,
+				Entries: []Entry{entry},
+			})
+		}
+	}
+	statements := make([]Statement, len(read.statements))
+	for idx, statement := range read.statements {
+		statement.Undone = read.undone[statement.ID]
+		statements[idx] = statement
+	}
+	return statements, nil
+}
@@ -0,0 +1,129 @@
+package journal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestItem_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		item Item
+		want string
+	}{
+		{
+			name: "test Item MarshalJSON",
+			item: Item{
+				"user_id": {N: aws.String("1")},
+				"roles":   {SS: []*string{aws.String("admin")}},
+				"tags":    {L: []*dynamodb.AttributeValue{}},
+				"address": {M: map[string]*dynamodb.AttributeValue{"city": {S: aws.String("Auckland")}}},
+			},
+			want: `{"address":{"M":{"city":{"S":"Auckland"}}},"roles":{"SS":["admin"]},"tags":{"L":[]},"user_id":{"N":"1"}}`,
+		},
+		{
+			name: "test Item MarshalJSON with nil",
+			item: nil,
+			want: "null",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.item.MarshalJSON()
+			if err != nil {
+				t.Errorf("MarshalJSON() error = %v", err)
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarshalJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatements(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynamo.cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	Path = filepath.Join(dir, "journal.jsonl")
+	defer func() { Path, Connection = "", "" }()
+
+	old := Item{"user_id": {N: aws.String("1")}, "roles": {SS: []*string{aws.String("admin")}}}
+	entries := []struct {
+		connection string
+		entry      Entry
+	}{
+		{"staging", Entry{Statement: 1, SQL: "DELETE FROM user WHERE user_id=1", Table: "user", Key: Item{"user_id": old["user_id"]}, Old: old}},
+		{"production", Entry{Statement: 2, SQL: "DELETE FROM user WHERE user_id=2", Table: "user"}},
+		{"staging", Entry{Statement: 3, SQL: "UPDATE user SET a=1 WHERE country=NZ", Table: "user"}},
+		{"staging", Entry{Statement: 3, SQL: "UPDATE user SET a=1 WHERE country=NZ", Table: "user"}},
+		{"staging", Entry{Statement: 4, Undone: 3}},
+	}
+	for _, e := range entries {
+		Connection = e.connection
+		if err := Record(e.entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	Connection = "staging"
+	statements, err := Statements()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, s := range statements {
+		got = append(got, strings.Join([]string{s.SQL, map[bool]string{true: "undone"}[s.Undone]}, "|"))
+	}
+	want := []string{"DELETE FROM user WHERE user_id=1|", "UPDATE user SET a=1 WHERE country=NZ|undone"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Statements() = %q, want %q", got, want)
+	}
+	if len(statements) == 2 && len(statements[1].Entries) != 2 {
+		t.Errorf("Statements() has %d entries for the update, want 2", len(statements[1].Entries))
+	}
+	if len(statements) > 0 && !reflect.DeepEqual(statements[0].Entries[0].Old, old) {
+		t.Errorf("Statements() old item = %v, want %v", statements[0].Entries[0].Old, old)
+	}
+}
+
+func TestStatements_large(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynamo.cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	Path, Connection = filepath.Join(dir, "journal.jsonl"), "staging"
+	defer func() { Path, Connection = "", "" }()
+
+	// two items of 400KB and more make an entry longer than a bufio.Scanner takes
+	big := Item{"user_id": {N: aws.String("1")}, "blob": {S: aws.String(strings.Repeat("x", 700*1024))}}
+	if err := Record(Entry{Statement: 1, SQL: "UPDATE user SET blob=x WHERE user_id=1", Table: "user", Old: big, New: big}); err != nil {
+		t.Fatal(err)
+	}
+	statements, err := Statements()
+	if err != nil || len(statements) != 1 || !reflect.DeepEqual(statements[0].Entries[0].Old, big) {
+		t.Fatalf("Statements() = %d statements, %v", len(statements), err)
+	}
+
+	// entries recorded since are read on the next call, along with the ones read before
+	if err := Record(Entry{Statement: 2, SQL: "DELETE FROM user WHERE user_id=1", Table: "user"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Record(Entry{Statement: 3, Undone: 1}); err != nil {
+		t.Fatal(err)
+	}
+	statements, err = Statements()
+	if err != nil || len(statements) != 2 || !statements[0].Undone || statements[1].Undone {
+		t.Errorf("Statements() = %+v, %v, want the update undone and the delete", statements, err)
+	}
+}
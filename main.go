@@ -6,13 +6,13 @@ import (
 	"os"
 	"os/signal"
 	"strings"
-	"time"
 
+	"github.com/FrontMage/dynamo.cli/config"
 	"github.com/FrontMage/dynamo.cli/db"
 	"github.com/FrontMage/dynamo.cli/executors"
 	"github.com/FrontMage/dynamo.cli/sqlparser"
 	"github.com/FrontMage/dynamo.cli/tables"
-	"github.com/briandowns/spinner"
+	"github.com/FrontMage/dynamo.cli/utils"
 	prompt "github.com/c-bata/go-prompt"
 	"golang.org/x/net/context"
 	cli "gopkg.in/urfave/cli.v2"
@@ -21,6 +21,9 @@ import (
 // TODO better suggest, suggest based on hash key and range key
 var tableNameSuggestions []prompt.Suggest
 
+// promptPrefix can be changed by repl.prefix in the config file
+var promptPrefix = ">>> "
+
 // Key bindings, reserved, might use them oneday
 var keyBindings = []prompt.KeyBind{
 	{
@@ -30,13 +33,45 @@ var keyBindings = []prompt.KeyBind{
 }
 
 func sqlRunner(sql string, resultCh chan string, errCh chan error) (chan string, chan error) {
-	if sqlparser.SelectRegexp.MatchString(sql) {
+	// reject writes before any request is built
+	if activeConnection.readOnly && sqlparser.IsWriteStatement(sql) {
+		errCh <- errors.New(`Connection is read-only, use \readonly off to allow writes`)
+		return resultCh, errCh
+	}
+	// COMMIT writes what was buffered before the connection was made read-only
+	if activeConnection.readOnly && sqlparser.CommitRegexp.MatchString(sql) && executors.HasBufferedWrites() {
+		errCh <- errors.New(`Connection is read-only, use \readonly off to commit the transaction or ROLLBACK`)
+		return resultCh, errCh
+	}
+	if executors.InReadOnlyTransaction() && sqlparser.IsWriteStatement(sql) {
+		errCh <- errors.New("The transaction is READ ONLY, ROLLBACK and BEGIN to write")
+		return resultCh, errCh
+	}
+	if metaCommandRegexp.MatchString(sql) {
+		if r, err := metaCommandRunner(sql); err == nil {
+			resultCh <- r
+		} else {
+			errCh <- err
+		}
+	} else if sqlparser.ExplainRegexp.MatchString(sql) {
+		if r, err := executors.Explain(sql + " END"); err == nil {
+			resultCh <- r
+		} else {
+			errCh <- err
+		}
+	} else if sqlparser.SelectRegexp.MatchString(sql) {
 		// add surfix "END" for rexexp matching
 		if r, err := executors.Select(sql + " END"); err == nil {
 			resultCh <- r
 		} else {
 			errCh <- err
 		}
+	} else if sqlparser.NextRegexp.MatchString(sql) {
+		if r, err := executors.Next(sql + " END"); err == nil {
+			resultCh <- r
+		} else {
+			errCh <- err
+		}
 	} else if sqlparser.DescRegexp.MatchString(sql) && sqlparser.TableRegexp.MatchString(sql) {
 		if r, err := executors.DescribeTable(sql + " END"); err == nil {
 			resultCh <- r
@@ -44,12 +79,53 @@ func sqlRunner(sql string, resultCh chan string, errCh chan error) (chan string,
 			errCh <- err
 		}
 	} else if sqlparser.UpdateRegexp.MatchString(sql) {
-		// TODO require WHERE field, update all seems not so safe?
 		if r, err := executors.Update(sql + " END"); err == nil {
 			resultCh <- r
 		} else {
 			errCh <- err
 		}
+	} else if sqlparser.DeleteRegexp.MatchString(sql) {
+		if r, err := executors.Delete(sql + " END"); err == nil {
+			resultCh <- r
+		} else {
+			errCh <- err
+		}
+	} else if sqlparser.InsertRegexp.MatchString(sql) {
+		if r, err := executors.Insert(sql + " END"); err == nil {
+			resultCh <- r
+		} else {
+			errCh <- err
+		}
+	} else if sqlparser.UndoRegexp.MatchString(sql) {
+		if r, err := executors.Undo(sql + " END"); err == nil {
+			resultCh <- r
+		} else {
+			errCh <- err
+		}
+	} else if sqlparser.BeginRegexp.MatchString(sql) {
+		if r, err := executors.Begin(sql + " END"); err == nil {
+			resultCh <- r
+		} else {
+			errCh <- err
+		}
+	} else if sqlparser.CommitRegexp.MatchString(sql) {
+		if r, err := executors.Commit(sql + " END"); err == nil {
+			resultCh <- r
+		} else {
+			errCh <- err
+		}
+	} else if sqlparser.RollbackRegexp.MatchString(sql) {
+		if r, err := executors.Rollback(sql + " END"); err == nil {
+			resultCh <- r
+		} else {
+			errCh <- err
+		}
+	} else if sqlparser.CheckRegexp.MatchString(sql) {
+		if r, err := executors.Check(sql + " END"); err == nil {
+			resultCh <- r
+		} else {
+			errCh <- err
+		}
 	} else {
 		resultCh <- ""
 		errCh <- nil
@@ -58,18 +134,26 @@ func sqlRunner(sql string, resultCh chan string, errCh chan error) (chan string,
 	return resultCh, errCh
 }
 
-// executor executes command and print the output.
+// executor executes command and print the output,
+// statements separated by ; run in order until one of them fails, like BEGIN; UPDATE ...; COMMIT
 func executor(in string) {
-	s := strings.TrimSpace(in)
-	s = strings.TrimSuffix(in, ";")
+	for _, s := range sqlparser.SplitTopLevel(in, ";") {
+		if !execute(strings.TrimSpace(s)) {
+			return
+		}
+	}
+}
+
+// execute runs a single statement and prints the output, it returns false if the statement failed or was canceled
+func execute(s string) bool {
 	if s == "" {
-		return
+		return true
 	} else if s == "quit" || s == "exit" {
 		os.Exit(0)
 	} else {
-		spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-		spin.Start()
-		defer spin.Stop()
+		startSpinner()
+		defer stopSpinner()
+		executors.ClearInterrupt()
 
 		ctx, cancel := context.WithCancel(context.Background())
 		sigCh := make(chan os.Signal, 1)
@@ -79,6 +163,7 @@ func executor(in string) {
 		go func() {
 			select {
 			case <-sigCh:
+				executors.Interrupt()
 				cancel()
 				return
 			}
@@ -92,20 +177,27 @@ func executor(in string) {
 		// so that new prompts won't popup
 		select {
 		case <-ctx.Done():
-			return
+			return false
 		case r := <-resultCh:
 			fmt.Println(r)
 		case e := <-errCh:
 			fmt.Println(e)
+			if e != nil {
+				return false
+			}
 		}
 	}
+	return true
 }
 
-func runPrompt(tablePrefix string) {
-	spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	spin.Start()
-	// load some table names for auto complete
-	tableNames, _ := db.ListTable([]*string{}, nil)
+// loadTableNames loads table names of the connected dynamodb for auto complete,
+// and warms up the table info cache in the background
+func loadTableNames(tablePrefix string) error {
+	tableNameSuggestions = []prompt.Suggest{}
+	tableNames, err := db.ListTable([]*string{}, nil)
+	if err != nil {
+		return err
+	}
 	for _, name := range tableNames {
 		// filter certain table name
 		if tablePrefix != "" {
@@ -132,11 +224,21 @@ func runPrompt(tablePrefix string) {
 			}(name)
 		}
 	}
-	spin.Stop()
+	return nil
+}
+
+func runPrompt() {
+	startSpinner()
+	err := loadTableNames(activeConnection.tablePrefix)
+	stopSpinner()
+	if err != nil {
+		fmt.Println("Unable to list tables:", err)
+	}
 	p := prompt.New(
 		executor,
 		completer,
-		prompt.OptionPrefix(">>> "),
+		prompt.OptionPrefix(promptPrefix),
+		prompt.OptionLivePrefix(livePrefix),
 		prompt.OptionTitle("DynamoDB prompt"),
 		prompt.OptionAddKeyBind(keyBindings...),
 	)
@@ -149,15 +251,73 @@ func completer(d prompt.Document) []prompt.Suggest {
 		{Text: "SELECT", Description: "keyword"},
 		{Text: "FROM", Description: "keyword"},
 		{Text: "WHERE", Description: "keyword"},
+		{Text: "DISTINCT", Description: "drop rows already seen"},
+		{Text: "AS", Description: "name a column"},
+		{Text: "CASE", Description: "CASE WHEN ... THEN ... ELSE ... END"},
+		{Text: "WHEN", Description: "keyword"},
+		{Text: "THEN", Description: "keyword"},
+		{Text: "ELSE", Description: "keyword"},
+		{Text: "ORDER BY", Description: "sort by the range key or client-side"},
+		{Text: "GROUP BY", Description: "aggregate by group"},
+		{Text: "HAVING", Description: "filter the groups"},
+		{Text: "COUNT", Description: "aggregate"},
+		{Text: "SUM", Description: "aggregate"},
+		{Text: "AVG", Description: "aggregate"},
+		{Text: "MIN", Description: "aggregate"},
+		{Text: "MAX", Description: "aggregate"},
+		{Text: "ASC", Description: "keyword"},
 		{Text: "LIMIT", Description: "keyword"},
 		{Text: "DESC", Description: "keyword"},
+		{Text: "JOIN", Description: "JOIN table alias ON a.x = b.y"},
+		{Text: "LEFT JOIN", Description: "JOIN keeping rows without a match"},
+		{Text: "ON", Description: "the attributes a JOIN matches"},
+		{Text: "EXISTS", Description: "EXISTS (SELECT ...) correlated by ="},
+		{Text: "CONSISTENT", Description: "hint a strongly consistent read, in /*+ */ or WITH ()"},
+		{Text: "USE_INDEX", Description: "hint USE_INDEX(index) to query an index"},
+		{Text: "FORCE_SCAN", Description: "hint to scan even with a key"},
+		{Text: "PARALLEL", Description: "hint PARALLEL(n) to scan n segments at once"},
+		{Text: "OFFSET", Description: "skip the first items"},
+		{Text: "AFTER", Description: "continue from a cursor token"},
+		{Text: "NEXT", Description: "read the following items of the last SELECT"},
 		{Text: "TABLE", Description: "keyword"},
-		{Text: "LIKE", Description: "keyword"},
+		{Text: "LIKE", Description: "% any text, _ any character"},
+		{Text: "ILIKE", Description: "LIKE ignoring case, matched client-side"},
+		{Text: "REGEXP", Description: "regular expression, matched client-side"},
+		{Text: "EXPLAIN", Description: "show how a SELECT reads items"},
 		{Text: "ALL", Description: "keyword"},
 		{Text: "AND", Description: "keyword"},
+		{Text: "IN", Description: "keyword"},
+		{Text: "BETWEEN", Description: "keyword"},
+		{Text: "IS MISSING", Description: "the attribute does not exist"},
+		{Text: "IS NULL", Description: "the attribute is NULL"},
+		{Text: "attribute_exists", Description: "function"},
+		{Text: "attribute_not_exists", Description: "function"},
+		{Text: "attribute_type", Description: "function"},
+		{Text: "begins_with", Description: "function"},
+		{Text: "contains", Description: "function"},
+		{Text: "size", Description: "function"},
+		{Text: "upper", Description: "function of a computed column"},
+		{Text: "lower", Description: "function of a computed column"},
+		{Text: "substr", Description: "function of a computed column"},
 		{Text: "UPDATE", Description: "keyword"},
 		{Text: "SET", Description: "keyword"},
-		{Text: "RETRUNING", Description: "keyword"},
+		{Text: "REMOVE", Description: "keyword"},
+		{Text: "ADD", Description: "keyword"},
+		{Text: "DELETE", Description: "keyword"},
+		{Text: "INSERT", Description: "keyword"},
+		{Text: "INTO", Description: "keyword"},
+		{Text: "VALUES", Description: "keyword"},
+		{Text: "UNDO", Description: "undo the last write"},
+		{Text: "RETURNING", Description: "keyword"},
+		{Text: "BEGIN", Description: "start a transaction"},
+		{Text: "READ ONLY", Description: "read keys in a transaction"},
+		{Text: "CHECK", Description: "check a condition in a transaction"},
+		{Text: "COMMIT", Description: "write the transaction"},
+		{Text: "ROLLBACK", Description: "discard the transaction"},
+		{Text: `\connect`, Description: "switch to a profile or region"},
+		{Text: `\readonly`, Description: "turn read-only mode on or off"},
+		{Text: `\journal`, Description: "list the writes UNDO can restore"},
+		{Text: `\cursor`, Description: "print where the last SELECT stopped"},
 	}
 
 	wordBefore := d.GetWordBeforeCursor()
@@ -180,6 +340,15 @@ func main() {
 	var secretAccessKey string
 	var region string
 	var tablePrefix string
+	var profileName string
+	var configPath string
+	var outputFormat string
+	var endpoint string
+	var roleARN string
+	var externalID string
+	var mfaSerial string
+	var readOnly bool
+	var assumeYes bool
 	app := &cli.App{
 		Name:    "dynamo.cli",
 		Usage:   "DynamoDB command line prompt",
@@ -213,12 +382,155 @@ func main() {
 				Aliases:     []string{"p"},
 				Destination: &tablePrefix,
 			},
+			&cli.StringFlag{
+				Name:        "profile",
+				Usage:       "specify the profile to use from the config file",
+				Destination: &profileName,
+			},
+			&cli.StringFlag{
+				Name:        "config",
+				Usage:       "specify the config file",
+				Value:       config.DefaultPath(),
+				Destination: &configPath,
+			},
+			&cli.StringFlag{
+				Name:        "endpoint-url",
+				Usage:       "specify a custom endpoint, e.g. http://localhost:8000 for DynamoDB Local",
+				Destination: &endpoint,
+			},
+			&cli.StringFlag{
+				Name:        "role-arn",
+				Usage:       "specify a role to assume",
+				Destination: &roleARN,
+			},
+			&cli.StringFlag{
+				Name:        "external-id",
+				Usage:       "specify the external id used to assume the role",
+				Destination: &externalID,
+			},
+			&cli.StringFlag{
+				Name:        "mfa-serial",
+				Usage:       "specify the MFA device, the token code is asked on start",
+				Destination: &mfaSerial,
+			},
+			&cli.BoolFlag{
+				Name:        "read-only",
+				Usage:       "reject UPDATE, DELETE, INSERT and DDL statements",
+				Destination: &readOnly,
+			},
+			&cli.BoolFlag{
+				Name:        "yes",
+				Usage:       "do not ask for confirmation before writes touching more than one item, for scripts",
+				Aliases:     []string{"y"},
+				Destination: &assumeYes,
+			},
+			&cli.StringFlag{
+				Name:        "output",
+				Usage:       "specify output format, one of " + strings.Join(utils.OutputFormats, ", "),
+				Aliases:     []string{"o"},
+				Destination: &outputFormat,
+			},
 		},
 		Action: func(c *cli.Context) error {
 			if !((accessKeyID == "" && secretAccessKey == "") || (accessKeyID != "" && secretAccessKey != "")) {
 				return errors.New("Must provide access key id and secret access key at the same time")
-			} else if _, err := db.GetDynamoSession(accessKeyID, secretAccessKey, region); err == nil {
-				runPrompt(tablePrefix)
+			}
+			loaded, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			conf = loaded
+			profile, err := conf.GetProfile(profileName)
+			if err != nil {
+				return err
+			}
+			// options given on the command line take precedence over the profile
+			if region == "" {
+				region = profile.Region
+			}
+			if tablePrefix == "" {
+				tablePrefix = profile.TablePrefix
+			}
+			if endpoint == "" {
+				endpoint = profile.Endpoint
+			}
+			if roleARN == "" {
+				roleARN = profile.RoleARN
+			}
+			if externalID == "" {
+				externalID = profile.ExternalID
+			}
+			if mfaSerial == "" {
+				mfaSerial = profile.MFASerial
+			}
+			if outputFormat == "" {
+				outputFormat = profile.OutputFormat
+			}
+			if outputFormat != "" {
+				if err := setOutputFormat(outputFormat); err != nil {
+					return err
+				}
+			}
+			if conf.REPL.Prefix != "" {
+				promptPrefix = conf.REPL.Prefix
+			}
+			if conf.REPL.DefaultLimit > 0 {
+				sqlparser.DefaultLimit = conf.REPL.DefaultLimit
+			}
+			if conf.REPL.WriteConcurrency > 0 {
+				executors.WriteConcurrency = conf.REPL.WriteConcurrency
+			}
+			if conf.REPL.SortLimit > 0 {
+				executors.SortLimit = conf.REPL.SortLimit
+			}
+			if conf.REPL.ScanWorkers > 0 {
+				executors.ScanWorkers = conf.REPL.ScanWorkers
+			}
+			if conf.REPL.ParallelScanItems > 0 {
+				executors.ParallelScanItems = conf.REPL.ParallelScanItems
+			}
+			if conf.REPL.SubqueryLimit > 0 {
+				executors.SubqueryLimit = conf.REPL.SubqueryLimit
+			}
+			executors.Progress = progress
+			executors.Warn = warn
+			// the token code is read like confirmations, with the spinner of a running statement paused
+			db.TokenProvider = func() (string, error) {
+				if code := ask("MFA token code: "); code != "" {
+					return code, nil
+				}
+				return "", errors.New("No MFA token code given")
+			}
+			forceReadOnly = readOnly
+			executors.Confirm = func(question string) bool {
+				if assumeYes {
+					return true
+				}
+				answer := strings.ToLower(ask(question + " [y/N] "))
+				return answer == "y" || answer == "yes"
+			}
+			activeConnection = connection{
+				profileName: conf.ResolveProfileName(profileName),
+				tablePrefix: tablePrefix,
+				readOnly:    readOnly || profile.ReadOnly,
+				opts: db.SessionOptions{
+					AccessKeyID:     accessKeyID,
+					SecretAccessKey: secretAccessKey,
+					Region:          region,
+					Profile:         profile.AWSProfile,
+					Endpoint:        endpoint,
+					RoleARN:         roleARN,
+					ExternalID:      externalID,
+					MFASerial:       mfaSerial,
+				},
+			}
+			db.CredentialsCacheDir = config.DefaultCredentialsCacheDir()
+			if conf.REPL.Journal == nil || *conf.REPL.Journal {
+				journalPath = config.DefaultJournalPath()
+			}
+			if client, err := db.GetDynamoSession(activeConnection.opts); err == nil {
+				useJournal(activeConnection, client)
+				runPrompt()
 				return nil
 			} else {
 				return err
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/FrontMage/dynamo.cli/executors"
+	"github.com/FrontMage/dynamo.cli/journal"
+)
+
+// metaCommandRegexp matches backslash commands which control the prompt itself, like \connect
+var metaCommandRegexp = regexp.MustCompile(`^\\[a-z]+`)
+
+// metaCommandRunner runs a backslash command
+func metaCommandRunner(cmd string) (string, error) {
+	tokens := strings.Fields(cmd)
+	switch tokens[0] {
+	case `\connect`:
+		if len(tokens) != 2 {
+			return "", errors.New(`Usage: \connect <profile|region>`)
+		}
+		return connect(tokens[1])
+	case `\readonly`:
+		if len(tokens) == 1 {
+			return fmt.Sprintf("read-only is %s", onOff(activeConnection.readOnly)), nil
+		} else if len(tokens) == 2 && tokens[1] == "on" {
+			activeConnection.readOnly = true
+			return "read-only is on", nil
+		} else if len(tokens) == 2 && tokens[1] == "off" {
+			return readOnlyOff()
+		}
+		return "", errors.New(`Usage: \readonly [on|off]`)
+	case `\journal`:
+		count := 10
+		if len(tokens) == 2 {
+			if n, err := strconv.Atoi(tokens[1]); err == nil && n > 0 {
+				count = n
+			} else {
+				return "", errors.New(`Usage: \journal [n]`)
+			}
+		}
+		return listJournal(count)
+	case `\cursor`:
+		if token, ok := executors.CursorToken(); ok {
+			return fmt.Sprintf("AFTER '%s'", token), nil
+		}
+		return "No cursor, the last SELECT read every item or can't be continued", nil
+	default:
+		return "", fmt.Errorf("Unknown command %s", tokens[0])
+	}
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// listJournal shows the last statements of the journal, 1 is the one UNDO restores first
+func listJournal(count int) (string, error) {
+	if !journal.Enabled() {
+		return "The journal is off", nil
+	}
+	statements, err := journal.Statements()
+	if err != nil {
+		return "", err
+	}
+	if len(statements) == 0 {
+		return "No write is journaled for " + journal.Connection, nil
+	}
+	lines := []string{}
+	for idx := len(statements) - 1; idx >= 0 && len(lines) < count; idx-- {
+		s := statements[idx]
+		items := "items"
+		if len(s.Entries) == 1 {
+			items = "item"
+		}
+		line := fmt.Sprintf("%3d  %s  %s (%d %s)", len(statements)-idx, s.Time.Format("2006-01-02 15:04:05"), s.SQL, len(s.Entries), items)
+		if s.Undone {
+			line += " undone"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// readOnlyOff allows writes again, it has to be confirmed by typing yes
+func readOnlyOff() (string, error) {
+	if !activeConnection.readOnly {
+		return "read-only is off", nil
+	}
+	activeConnection.readOnly = false
+	label := activeConnection.label()
+	activeConnection.readOnly = true
+	if ask(fmt.Sprintf("Writes will be sent to [%s], type yes to confirm: ", label)) != "yes" {
+		return "", errors.New("Not confirmed, read-only is still on")
+	}
+	activeConnection.readOnly = false
+	return "read-only is off", nil
+}
@@ -0,0 +1,103 @@
+package sqlparser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Names of the hints of SELECT
+const (
+	HintConsistent = "CONSISTENT"
+	HintUseIndex   = "USE_INDEX"
+	HintForceScan  = "FORCE_SCAN"
+	HintParallel   = "PARALLEL"
+)
+
+var hintCommentRegexp = regexp.MustCompile(`(?s)(/\*\+)(.*?)\*/`)
+var hintWithRegexp = regexp.MustCompile(`(?i)\s(WITH)\s*\(`)
+var hintWordsRegexp = regexp.MustCompile(`(?i)\b(USE|FORCE)\s+(INDEX|SCAN)\b`)
+var hintTokenRegexp = regexp.MustCompile(`(?i)[A-Z_]+(?:\s*\([^)]*\))?|[^\s,]+`)
+var hintRegexp = regexp.MustCompile(`(?i)^([A-Z_]+)(?:\s*\(\s*(.*?)\s*\))?$`)
+
+// Hints tell SELECT how to read, written as /*+ CONSISTENT USE_INDEX(email-index) */ after SELECT
+// or as WITH (CONSISTENT, PARALLEL(8)) at the end of the statement
+// Hints Index is the index queried, or scanned with ForceScan, Parallel is the number of segments of a Scan
+// Hints Unknown holds the hints not understood, a SELECT with them is refused
+type Hints struct {
+	Consistent bool
+	Index      string
+	ForceScan  bool
+	Parallel   int64
+	Unknown    []string
+}
+
+// String returns the hints as they are written in a comment
+func (h Hints) String() string {
+	hints := []string{}
+	if h.Consistent {
+		hints = append(hints, HintConsistent)
+	}
+	if h.Index != "" {
+		hints = append(hints, HintUseIndex+"("+h.Index+")")
+	}
+	if h.ForceScan {
+		hints = append(hints, HintForceScan)
+	}
+	if h.Parallel > 0 {
+		hints = append(hints, HintParallel+"("+strconv.FormatInt(h.Parallel, 10)+")")
+	}
+	return strings.Join(append(hints, h.Unknown...), " ")
+}
+
+// cutHints takes the hint comment and the trailing WITH (...) out of a select statement,
+// the rest of it is parsed as if they were not there
+func cutHints(sql string) (string, Hints) {
+	hints := Hints{}
+	if m := findTopLevel(hintCommentRegexp, sql); len(m) > 0 {
+		hints = parseHints(hints, hintTokenRegexp.FindAllString(hintWordsRegexp.ReplaceAllString(sql[m[0][3]:m[0][1]-2], "${1}_${2}"), -1))
+		sql = sql[:m[0][0]] + " " + sql[m[0][1]:]
+	}
+	if m := findTopLevel(hintWithRegexp, sql); len(m) > 0 {
+		last := m[len(m)-1]
+		if end := closingParen(sql, last[1]-1); end != -1 && strings.EqualFold(strings.TrimSpace(sql[end+1:]), "END") {
+			options := []string{}
+			for _, o := range SplitTopLevel(hintWordsRegexp.ReplaceAllString(sql[last[1]:end], "${1}_${2}"), ",") {
+				options = append(options, strings.TrimSpace(o))
+			}
+			hints = parseHints(hints, options)
+			sql = sql[:last[0]] + sql[end+1:]
+		}
+	}
+	return sql, hints
+}
+
+// parseHints adds hints like CONSISTENT or PARALLEL(8) to h
+func parseHints(h Hints, hints []string) Hints {
+	for _, hint := range hints {
+		m := hintRegexp.FindStringSubmatch(hint)
+		if m == nil {
+			if hint != "" {
+				h.Unknown = append(h.Unknown, hint)
+			}
+			continue
+		}
+		switch name, argument := strings.ToUpper(m[1]), m[2]; {
+		case name == HintConsistent && argument == "":
+			h.Consistent = true
+		case name == HintUseIndex && argument != "":
+			h.Index = argument
+		case name == HintForceScan && argument == "":
+			h.ForceScan = true
+		case name == HintParallel:
+			if segments, err := strconv.ParseInt(argument, 10, 64); err == nil && segments > 0 {
+				h.Parallel = segments
+			} else {
+				h.Unknown = append(h.Unknown, hint)
+			}
+		default:
+			h.Unknown = append(h.Unknown, hint)
+		}
+	}
+	return h
+}
@@ -0,0 +1,65 @@
+package sqlparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_cutHints(t *testing.T) {
+	tests := []struct {
+		name      string
+		sql       string
+		wantSQL   string
+		wantHints Hints
+	}{
+		{
+			name:      "test cutHints with a comment",
+			sql:       "SELECT /*+ CONSISTENT USE_INDEX(email-index) PARALLEL(8) */ * FROM user END",
+			wantSQL:   "SELECT   * FROM user END",
+			wantHints: Hints{Consistent: true, Index: "email-index", Parallel: 8},
+		},
+		{
+			name:      "test cutHints with WITH",
+			sql:       "SELECT * FROM user WHERE name = 'a WITH (b)' LIMIT 5 WITH (consistent, FORCE SCAN) END",
+			wantSQL:   "SELECT * FROM user WHERE name = 'a WITH (b)' LIMIT 5 END",
+			wantHints: Hints{Consistent: true, ForceScan: true},
+		},
+		{
+			name:      "test cutHints with words of two hints",
+			sql:       "SELECT /*+ USE INDEX ( email-index ), FORCE SCAN */ * FROM user END",
+			wantSQL:   "SELECT   * FROM user END",
+			wantHints: Hints{Index: "email-index", ForceScan: true},
+		},
+		{
+			name:      "test cutHints with unknown hints",
+			sql:       "SELECT /*+ FAST PARALLEL(x) */ * FROM user WITH (USE_INDEX) END",
+			wantSQL:   "SELECT   * FROM user END",
+			wantHints: Hints{Unknown: []string{"FAST", "PARALLEL(x)", "USE_INDEX"}},
+		},
+		{
+			name:    "test cutHints with a comment which is no hint",
+			sql:     "SELECT * FROM user WHERE name = '/*+ CONSISTENT */' END",
+			wantSQL: "SELECT * FROM user WHERE name = '/*+ CONSISTENT */' END",
+		},
+		{
+			name:    "test cutHints with WITH before the end",
+			sql:     "SELECT * FROM user WITH (CONSISTENT) LIMIT 5 END",
+			wantSQL: "SELECT * FROM user WITH (CONSISTENT) LIMIT 5 END",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSQL, gotHints := cutHints(tt.sql)
+			if gotSQL != tt.wantSQL || !reflect.DeepEqual(gotHints, tt.wantHints) {
+				t.Errorf("cutHints() = %q, %+v, want %q, %+v", gotSQL, gotHints, tt.wantSQL, tt.wantHints)
+			}
+		})
+	}
+}
+
+func TestHints_String(t *testing.T) {
+	h := Hints{Consistent: true, Index: "email-index", ForceScan: true, Parallel: 4, Unknown: []string{"FAST"}}
+	if got, want := h.String(), "CONSISTENT USE_INDEX(email-index) FORCE_SCAN PARALLEL(4) FAST"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package sqlparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kinds of JOIN, JOIN alone is an INNER JOIN and LEFT OUTER JOIN is a LEFT JOIN
+const (
+	JoinInner = "INNER"
+	JoinLeft  = "LEFT"
+)
+
+var joinRegexp = regexp.MustCompile(`(?i)\s((?:INNER\s+|LEFT\s+(?:OUTER\s+)?)?JOIN)\s`)
+var joinTableRegexp = regexp.MustCompile(`(?is)^(\S+)(?:\s+(?:AS\s+)?(\S+))?$`)
+var joinOnRegexp = regexp.MustCompile(`(?is)^(\S+)(?:\s+(?:AS\s+)?(\S+?))?\s+ON\s+(.+)$`)
+
+// Join is a table joined by JOIN, like LEFT JOIN users u ON o.userId = u.id
+// Join Alias is the table name unless it's given
+// Join On holds the conditions of ON, TableName is empty if the JOIN is not understood
+type Join struct {
+	Kind      string
+	TableName string
+	Alias     string
+	On        []Condition
+}
+
+// String returns the JOIN as it is written
+func (j Join) String() string {
+	on := []string{}
+	for _, c := range j.On {
+		on = append(on, c.String())
+	}
+	return j.Kind + " JOIN " + j.TableName + " " + j.Alias + " ON " + strings.Join(on, " AND ")
+}
+
+// tableAndAlias parses a table with an optional alias, like orders o or orders AS o
+func tableAndAlias(s string) (string, string) {
+	m := joinTableRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return "", ""
+	}
+	if m[2] == "" {
+		return m[1], m[1]
+	}
+	return m[1], m[2]
+}
+
+// parseFrom splits what follows FROM into the driving table, its alias and the tables joined to it,
+// without JOIN the alias is empty unless it's given
+func parseFrom(from string) (string, string, []Join) {
+	matches := findTopLevel(joinRegexp, from)
+	if len(matches) == 0 {
+		if tableName, alias := tableAndAlias(from); alias != tableName {
+			return tableName, alias, nil
+		}
+		return from, "", nil
+	}
+	tableName, alias := tableAndAlias(from[:matches[0][0]])
+	joins := []Join{}
+	for idx, m := range matches {
+		end := len(from)
+		if idx+1 < len(matches) {
+			end = matches[idx+1][0]
+		}
+		j := Join{Kind: JoinInner}
+		if strings.HasPrefix(strings.ToUpper(from[m[2]:m[3]]), JoinLeft) {
+			j.Kind = JoinLeft
+		}
+		if on := joinOnRegexp.FindStringSubmatch(strings.TrimSpace(from[m[1]:end])); on != nil {
+			j.TableName, j.Alias = on[1], on[2]
+			if j.Alias == "" {
+				j.Alias = j.TableName
+			}
+			j.On = []Condition{}
+			for _, c := range splitConditions(on[3]) {
+				j.On = append(j.On, switchCondition(c, "AND"))
+			}
+		}
+		joins = append(joins, j)
+	}
+	return tableName, alias, joins
+}
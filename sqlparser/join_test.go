@@ -0,0 +1,53 @@
+package sqlparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseFrom(t *testing.T) {
+	on := func(key, value string) Condition {
+		return Condition{Key: key, Operator: OpEq, Value: value, NextLogicalOperator: "AND"}
+	}
+	tests := []struct {
+		name          string
+		from          string
+		wantTableName string
+		wantAlias     string
+		wantJoins     []Join
+	}{
+		{name: "test parseFrom without JOIN", from: "orders", wantTableName: "orders"},
+		{name: "test parseFrom with JOIN", from: "orders o JOIN users u ON o.userId = u.id", wantTableName: "orders", wantAlias: "o",
+			wantJoins: []Join{{Kind: JoinInner, TableName: "users", Alias: "u", On: []Condition{on("o.userId", "u.id")}}}},
+		{name: "test parseFrom with AS and LEFT OUTER JOIN", from: "orders AS o left outer join users AS u ON u.id = o.userId AND u.region = o.region",
+			wantTableName: "orders", wantAlias: "o",
+			wantJoins: []Join{{Kind: JoinLeft, TableName: "users", Alias: "u", On: []Condition{on("u.id", "o.userId"), on("u.region", "o.region")}}}},
+		{name: "test parseFrom without aliases", from: "orders INNER JOIN users ON orders.userId = users.id", wantTableName: "orders", wantAlias: "orders",
+			wantJoins: []Join{{Kind: JoinInner, TableName: "users", Alias: "users", On: []Condition{on("orders.userId", "users.id")}}}},
+		{name: "test parseFrom with two JOINs", from: "orders o JOIN users u ON o.userId = u.id LEFT JOIN items i ON o.sku = i.sku",
+			wantTableName: "orders", wantAlias: "o",
+			wantJoins: []Join{
+				{Kind: JoinInner, TableName: "users", Alias: "u", On: []Condition{on("o.userId", "u.id")}},
+				{Kind: JoinLeft, TableName: "items", Alias: "i", On: []Condition{on("o.sku", "i.sku")}},
+			}},
+		{name: "test parseFrom without ON", from: "orders o JOIN users u", wantTableName: "orders", wantAlias: "o", wantJoins: []Join{{Kind: JoinInner}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tableName, alias, joins := parseFrom(tt.from)
+			if tableName != tt.wantTableName || alias != tt.wantAlias {
+				t.Errorf("parseFrom() = %q, %q, want %q, %q", tableName, alias, tt.wantTableName, tt.wantAlias)
+			}
+			if !reflect.DeepEqual(joins, tt.wantJoins) {
+				t.Errorf("parseFrom() joins = %+v, want %+v", joins, tt.wantJoins)
+			}
+		})
+	}
+}
+
+func TestJoin_String(t *testing.T) {
+	_, _, joins := parseFrom("orders o left join users u ON o.userId=u.id")
+	if got, want := joins[0].String(), "LEFT JOIN users u ON o.userId = u.id"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
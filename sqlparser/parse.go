@@ -7,34 +7,77 @@ import (
 )
 
 var SelectRegexp = regexp.MustCompile(`(?i)^(SELECT) `)
-var FromRegexp = regexp.MustCompile("(?i) ?(FROM) ?")
-var WhereRegexp = regexp.MustCompile("(?i) ?(WHERE) ?")
-var LimitRegexp = regexp.MustCompile("(?i) ?(LIMIT) ?")
-var EndRegexp = regexp.MustCompile("(?i) ?(END) ?")
-var UpdateRegexp = regexp.MustCompile(" ?(UPDATE) ?")
-var setRegexp = regexp.MustCompile("(?i) ?(SET) ?")
-var returningRegexp = regexp.MustCompile("(?i) ?(RETRUNING) ?")
+
+// keywords are whole words, so that a value like pending or a table like calendar keeps its END
+var FromRegexp = regexp.MustCompile(`(?i) ?\b(FROM)\b ?`)
+var WhereRegexp = regexp.MustCompile(`(?i) ?\b(WHERE)\b ?`)
+var LimitRegexp = regexp.MustCompile(`(?i) ?\b(LIMIT)\b ?`)
+var EndRegexp = regexp.MustCompile(`(?i) ?\b(END)\b ?`)
+var UpdateRegexp = regexp.MustCompile(` ?\b(UPDATE)\b ?`)
+var setRegexp = regexp.MustCompile(`(?i) ?\b(SET)\b ?`)
+var returningRegexp = regexp.MustCompile(`(?i) ?\b(RETURNING|RETRUNING)\b ?`)
+var orderByRegexp = regexp.MustCompile(`(?i) ?\b(ORDER\s+BY)\b ?`)
+var groupByRegexp = regexp.MustCompile(`(?i) ?\b(GROUP\s+BY)\b ?`)
+var havingRegexp = regexp.MustCompile(`(?i) ?\b(HAVING)\b ?`)
 var DescRegexp = regexp.MustCompile(`(?i)^(DESC) `)
-var TableRegexp = regexp.MustCompile("(?i) ?(TABLE) ?")
+var DeleteRegexp = regexp.MustCompile(`(?i)^(DELETE) `)
+var InsertRegexp = regexp.MustCompile(`(?i)^(INSERT) `)
+var UndoRegexp = regexp.MustCompile(`(?i)^(UNDO)\b`)
+var BeginRegexp = regexp.MustCompile(`(?i)^\s*(BEGIN)\b`)
+var CommitRegexp = regexp.MustCompile(`(?i)^\s*(COMMIT)\s*$`)
+var RollbackRegexp = regexp.MustCompile(`(?i)^\s*(ROLLBACK)\s*$`)
+var CheckRegexp = regexp.MustCompile(`(?i)^(CHECK) `)
+var ExplainRegexp = regexp.MustCompile(`(?i)^\s*(EXPLAIN)\s+`)
+var NextRegexp = regexp.MustCompile(`(?i)^\s*(NEXT)\b`)
+
+// writeRegexp matches statements which change items or tables
+var writeRegexp = regexp.MustCompile(`(?i)^\s*(UPDATE|DELETE|INSERT|PUT|REPLACE|CREATE|DROP|ALTER|TRUNCATE|UNDO)\b`)
+var TableRegexp = regexp.MustCompile(`(?i) ?\b(TABLE)\b ?`)
 
 var KeywordRegexps = []*regexp.Regexp{
 	SelectRegexp, FromRegexp, WhereRegexp, LimitRegexp,
 	UpdateRegexp, setRegexp, returningRegexp, EndRegexp,
-	TableRegexp,
+	TableRegexp, orderByRegexp, groupByRegexp, havingRegexp,
 }
 
-var selectStmtRegexp = regexp.MustCompile("(?i)(SELECT )(.*?)( FROM)")
-var FromStmtRegexp = regexp.MustCompile("(?i)(FROM )(.*?)(( WHERE)|( LIMIT)|( END))")
+var selectListRegexp = regexp.MustCompile(`(?i)^\s*SELECT\s+(DISTINCT\s+)?`)
+var fromClauseRegexp = regexp.MustCompile(`(?i)\s(FROM)\s`)
+var FromStmtRegexp = regexp.MustCompile(`(?i)(FROM )(.*?)(( WHERE)|( GROUP\s+BY)|( HAVING)|( ORDER\s+BY)|( LIMIT)|( OFFSET)|( AFTER)|( END))`)
 var WhereStmtRegexp = regexp.MustCompile("(?i)(WHERE )(.*?)(( LIMIT)|( END))")
 var LimitStmtRegexp = regexp.MustCompile("(?i)(LIMIT )(.*?)( END)")
 
-var updateStmtRegexp = regexp.MustCompile("(?i)(UPDATE )(.*?)( SET)")
-var setStmtRegexp = regexp.MustCompile("(?i)(SET )(.*?)(( WHERE)|( RETRUNING)|( END))")
-var whereStmtRegexpForUpdate = regexp.MustCompile("(?i)(WHERE )(.*?)(( RETRUNING)|( END))")
-var returningStmtRegexp = regexp.MustCompile("(?i)(RETRUNING )(.*?)( END)")
+// whereRegexp and whereEndRegexp find the WHERE clause outside of quotes and brackets
+var whereRegexp = regexp.MustCompile(`(?i)(?:^|\s)(WHERE)\s`)
+var whereEndRegexp = regexp.MustCompile(`(?i)\s(GROUP\s+BY|HAVING|ORDER\s+BY|LIMIT|OFFSET|AFTER|RETURNING|RETRUNING|END)\b`)
+var andRegexp = regexp.MustCompile(`(?i)\s(AND)\s`)
+
+// the clauses after WHERE, each one ends where a later one starts
+var groupByClauseRegexp = regexp.MustCompile(`(?i)\s(GROUP\s+BY)\s`)
+var groupByEndRegexp = regexp.MustCompile(`(?i)\s(HAVING|ORDER\s+BY|LIMIT|OFFSET|AFTER|END)\b`)
+var havingClauseRegexp = regexp.MustCompile(`(?i)\s(HAVING)\s`)
+var havingEndRegexp = regexp.MustCompile(`(?i)\s(ORDER\s+BY|LIMIT|OFFSET|AFTER|END)\b`)
+var orderByClauseRegexp = regexp.MustCompile(`(?i)\s(ORDER\s+BY)\s`)
+var orderByEndRegexp = regexp.MustCompile(`(?i)\s(LIMIT|OFFSET|AFTER|END)\b`)
+
+// LIMIT, OFFSET and AFTER come last, in any order
+var limitClauseRegexp = regexp.MustCompile(`(?i)\s(LIMIT)\s`)
+var limitEndRegexp = regexp.MustCompile(`(?i)\s(OFFSET|AFTER|END)\b`)
+var offsetClauseRegexp = regexp.MustCompile(`(?i)\s(OFFSET)\s`)
+var offsetEndRegexp = regexp.MustCompile(`(?i)\s(LIMIT|AFTER|END)\b`)
+var afterClauseRegexp = regexp.MustCompile(`(?i)\s(AFTER)\s`)
+var afterEndRegexp = regexp.MustCompile(`(?i)\s(LIMIT|OFFSET|END)\b`)
+var aggregateRegexp = regexp.MustCompile(`(?i)^(COUNT|SUM|AVG|MIN|MAX)\s*\(\s*(.*?)\s*\)$`)
+var directionRegexp = regexp.MustCompile(`(?i)^(.*?)\s+(ASC|DESC)$`)
+var returningStmtRegexp = regexp.MustCompile("(?i)((RETURNING )|(RETRUNING ))(.*?)( END)")
 
 var TableStmtRegexp = regexp.MustCompile("(?i)(TABLE )(.*?)( END)")
 
+var insertStmtRegexp = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+(\S+)\s*\((.*?)\)\s*VALUES\s*(.*?)\s*END$`)
+var nextStmtRegexp = regexp.MustCompile(`(?i)^\s*NEXT\s*(\d*)\s*END$`)
+var undoStmtRegexp = regexp.MustCompile(`(?i)^\s*UNDO\s*(\d*)\s*END$`)
+var beginStmtRegexp = regexp.MustCompile(`(?i)^\s*BEGIN(\s+READ\s+(ONLY|WRITE))?\s*END$`)
+var checkStmtRegexp = regexp.MustCompile(`(?is)^\s*CHECK\s+(\S+)\s+WHERE\s+(.*?)\s*END$`)
+
 const (
 	OpEq   = "="
 	opGt   = ">"
@@ -42,44 +85,209 @@ const (
 	opGtEq = ">="
 	opLtEq = "<="
 	opNeq  = "!="
-	opLike = " LIKE "
-	// TODO support between
+	// OpLike takes % and _ wildcards, OpILike does the same ignoring case and OpRegexp takes a regular expression
+	OpLike   = " LIKE "
+	OpILike  = " ILIKE "
+	OpRegexp = " REGEXP "
+	// OpIn is handled before the others as its list may contain any of them
+	OpIn = " IN "
+	// OpBetween has the lower bound in Value and the upper one in High
+	OpBetween      = " BETWEEN "
+	OpIsMissing    = " IS MISSING"
+	OpIsNotMissing = " IS NOT MISSING"
+	OpIsNull       = " IS NULL"
+	OpIsNotNull    = " IS NOT NULL"
+	// OpExists holds when the SELECT in Subquery returns a row
+	OpExists = "EXISTS"
+	// OpInvalid is a predicate which can't be parsed, Value holds it as written
+	OpInvalid = "INVALID"
+)
+
+// Predicate functions are operators of their own, the first argument is the Key and the second one the Value
+const (
+	OpAttributeExists    = "attribute_exists"
+	OpAttributeNotExists = "attribute_not_exists"
+	OpAttributeType      = "attribute_type"
+	OpBeginsWith         = "begins_with"
+	OpContains           = "contains"
+	// FunctionSize compares the size of the Key instead of its value, like size(tags) > 3
+	FunctionSize = "size"
+)
+
+var ops = []string{opGtEq, opLtEq, opNeq, OpEq, opGt, opLt}
+
+// predicateArities is how many arguments each predicate function takes
+var predicateArities = map[string]int{
+	OpAttributeExists:    1,
+	OpAttributeNotExists: 1,
+	OpAttributeType:      2,
+	OpBeginsWith:         2,
+	OpContains:           2,
+}
+
+var inRegexp = regexp.MustCompile(`(?i)\s(IN)\s`)
+var betweenRegexp = regexp.MustCompile(`(?i)\s(BETWEEN)\s`)
+var likeRegexp = regexp.MustCompile(`(?i)\s(LIKE|ILIKE|REGEXP)\s`)
+var isRegexp = regexp.MustCompile(`(?i)^(.*?)\s+IS\s+(NOT\s+)?(MISSING|NULL)\s*$`)
+var predicateRegexp = regexp.MustCompile(`^\s*(\w+)\s*\(`)
+
+// DefaultLimit is the limit of a select statement without LIMIT
+var DefaultLimit int64 = 1
+
+// Modes of a transaction given by BEGIN
+const (
+	TransactionReadWrite = "READ WRITE"
+	TransactionReadOnly  = "READ ONLY"
 )
 
-var ops = []string{opGtEq, opLtEq, opNeq, OpEq, opGt, opLt, opLike}
+// ReturnValues of UpdateItem given by RETURNING
+const (
+	ReturnNone       = "NONE"
+	ReturnAllOld     = "ALL_OLD"
+	ReturnAllNew     = "ALL_NEW"
+	ReturnUpdatedOld = "UPDATED_OLD"
+	ReturnUpdatedNew = "UPDATED_NEW"
+)
 
 // SelectStatement holds all key information parsed from a sql select statement
-// SelectStatement AttributesToGet is the part between SELECT and FROM
-// SelectStatement Conditions is the part between WHERE and LIMIT or END
+// SelectStatement AttributesToGet is the part between SELECT and FROM, each may be a computed column with an alias
+// SelectStatement Distinct is true for SELECT DISTINCT
+// SelectStatement TableAlias is the alias of TableName, with JOIN it's its name if there is none,
+// a statement with TableAlias reads rows holding each item under the alias of its table
+// SelectStatement Joins are the tables joined by JOIN, nil without JOIN
+// SelectStatement Conditions is the part between WHERE and ORDER BY, LIMIT or END
+// SelectStatement GroupBy is the part between GROUP BY and HAVING, ORDER BY, LIMIT or END
+// SelectStatement Having is the part between HAVING and ORDER BY, LIMIT or END
+// SelectStatement OrderBy is the part between ORDER BY and LIMIT or END
+// SelectStatement Limit is -1 for LIMIT ALL, and for aggregates without LIMIT
+// SelectStatement Offset is the number of items skipped, -1 if OFFSET is not a number
+// SelectStatement After is the cursor token of AFTER '<token>', where a previous SELECT stopped
+// SelectStatement Hints are the hints of /*+ ... */ or WITH (...)
 type SelectStatement struct {
 	AttributesToGet []string
+	Distinct        bool
 	TableName       string
+	TableAlias      string
+	Joins           []Join
 	Conditions      []Condition
+	GroupBy         []string
+	Having          []Condition
+	OrderBy         []OrderBy
 	Limit           int64
+	Offset          int64
+	After           string
+	Hints           Hints
+}
+
+// Aggregate functions of SELECT
+const (
+	AggregateCount = "COUNT"
+	AggregateSum   = "SUM"
+	AggregateAvg   = "AVG"
+	AggregateMin   = "MIN"
+	AggregateMax   = "MAX"
+)
+
+// Aggregate is an aggregate function of SELECT or HAVING like COUNT(*) or SUM(price), Key is * for COUNT(*)
+type Aggregate struct {
+	Function string
+	Key      string
+}
+
+// IsAggregate tells whether the statement aggregates items, by GROUP BY, HAVING or an aggregate function
+func (stmt SelectStatement) IsAggregate() bool {
+	if stmt.GroupBy != nil || stmt.Having != nil {
+		return true
+	}
+	for _, a := range stmt.AttributesToGet {
+		if expression, _ := ParseColumn(a); isAggregate(expression) {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderBy is an attribute of ORDER BY, ascending unless Desc
+type OrderBy struct {
+	Key  string
+	Desc bool
 }
 
 // UpdateStatement holds all key information parsed from a sql select statement
-// UpdateStatement AttributesToGet is the part between RETRUNING and END
-// UpdateStatement Conditions is the part between WHERE and RETRUNING or END
+// UpdateStatement AttributesToGet is the part between RETURNING and END, RETRUNING is accepted as well
+// UpdateStatement ReturnValues is the ReturnValues of UpdateItem given by RETURNING, empty without RETURNING
+// UpdateStatement Conditions is the part between WHERE and RETURNING or END
+// UpdateStatement All is true for WHERE ALL, which updates every item of the table
 type UpdateStatement struct {
 	AttributesToGet   []string
+	ReturnValues      string
 	UpdateExpressions []UpdateExpression
 	TableName         string
 	Conditions        []Condition
+	All               bool
 }
 
+// DeleteStatement holds all key information parsed from a sql delete statement
+// DeleteStatement Conditions is the part between WHERE and END
+// DeleteStatement All is true for WHERE ALL, which deletes every item of the table
+type DeleteStatement struct {
+	TableName  string
+	Conditions []Condition
+	All        bool
+}
+
+// UpdateExpression is one action of the SET, REMOVE, ADD or DELETE clause of an update statement
+// UpdateExpression Value is what follows = in SET, or the attribute name in ADD and DELETE
 type UpdateExpression struct {
-	Key   string
-	Value string
+	Action string
+	Key    string
+	Value  string
 }
 
+// Condition is one predicate of WHERE
+// Condition High is the upper bound of BETWEEN
+// Condition Function is FunctionSize if the size of Key is compared, empty otherwise
+// Condition Subquery is the SELECT of IN (SELECT ...) or EXISTS (SELECT ...), the values it returns are not in Value
 type Condition struct {
 	Key                 string
 	Operator            string
 	Value               string
+	High                string
+	Function            string
+	Subquery            string
 	NextLogicalOperator string
 }
 
+// InsertStatement holds all key information parsed from a sql insert statement
+// InsertStatement Attributes is the list after the table name, each of Rows holds the values in the same order
+type InsertStatement struct {
+	TableName  string
+	Attributes []string
+	Rows       [][]string
+}
+
+// UndoStatement holds the number of statements UNDO restores, 1 by default
+type UndoStatement struct {
+	Count int
+}
+
+// NextStatement holds the number of items NEXT reads, 0 for the LIMIT of the SELECT it continues
+type NextStatement struct {
+	Limit int64
+}
+
+// BeginStatement holds the mode of a transaction, READ WRITE by default, empty if it's not a BEGIN
+type BeginStatement struct {
+	Mode string
+}
+
+// CheckStatement holds a condition check of a transaction, like CHECK user WHERE user_id=1 AND coins >= 100
+// CheckStatement Conditions must have the full primary key of an item
+type CheckStatement struct {
+	TableName  string
+	Conditions []Condition
+}
+
 // DescTableStatement holds all key information parsed from a sql describe table statement
 // DescTableStatement which is the table name between TALBE and END
 type DescTableStatement struct {
@@ -95,64 +303,427 @@ func killAllKeyWords(s string) string {
 
 func parseAttributesToGet(attributesToGetStr string) []string {
 	attributesToGet := []string{}
-	for _, t := range strings.Split(attributesToGetStr, ",") {
-		if strings.Contains(t, ",") {
-			for _, s := range strings.Split(t, ",") {
-				attributesToGet = append(attributesToGet, strings.TrimSpace(s))
+	// a comma in backticks is part of a name
+	for _, t := range SplitTopLevel(attributesToGetStr, ",") {
+		attributesToGet = append(attributesToGet, strings.TrimSpace(t))
+	}
+	return attributesToGet
+}
+
+// clauseOf returns what follows the keyword matched by start up to the one matched by end,
+// keywords in quotes are values, ok is false if there is no such clause
+func clauseOf(sql string, start, end *regexp.Regexp) (string, bool) {
+	m := findTopLevel(start, sql)
+	if len(m) == 0 {
+		return "", false
+	}
+	clause := sql[m[0][1]:]
+	if e := findTopLevel(end, clause); len(e) > 0 {
+		clause = clause[:e[0][0]]
+	}
+	return strings.TrimSpace(clause), true
+}
+
+// selectList returns the items between SELECT and FROM, whether they are DISTINCT and the statement from FROM on,
+// keywords of CASE or in quotes are part of the items
+func selectList(sql string) (string, bool, string) {
+	m := selectListRegexp.FindStringSubmatchIndex(sql)
+	if m == nil {
+		return "", false, sql
+	}
+	rest := sql[m[1]:]
+	from := findTopLevel(fromClauseRegexp, rest)
+	if len(from) == 0 {
+		return "", false, sql
+	}
+	return strings.TrimSpace(rest[:from[0][0]]), m[2] != -1, rest[from[0][0]:]
+}
+
+// whereClause returns what follows WHERE up to GROUP BY, HAVING, ORDER BY, LIMIT, RETURNING or END
+func whereClause(sql string) string {
+	clause, _ := clauseOf(sql, whereRegexp, whereEndRegexp)
+	return clause
+}
+
+// parseGroupBy returns the attributes of the GROUP BY clause, nil without GROUP BY
+func parseGroupBy(sql string) []string {
+	clause, ok := clauseOf(sql, groupByClauseRegexp, groupByEndRegexp)
+	if !ok {
+		return nil
+	}
+	return parseAttributesToGet(clause)
+}
+
+// parseHaving returns the conditions of the HAVING clause, nil without HAVING
+func parseHaving(sql string) []Condition {
+	clause, ok := clauseOf(sql, havingClauseRegexp, havingEndRegexp)
+	if !ok {
+		return nil
+	}
+	having := []Condition{}
+	for _, c := range splitConditions(clause) {
+		having = append(having, switchCondition(c, "AND"))
+	}
+	return having
+}
+
+func isAggregate(s string) bool {
+	_, ok := ParseAggregate(s)
+	return ok
+}
+
+// ParseAggregate parses an aggregate function like count(*) or SUM(price), ok is false if s is not one
+func ParseAggregate(s string) (Aggregate, bool) {
+	m := aggregateRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Aggregate{}, false
+	}
+	return Aggregate{Function: strings.ToUpper(m[1]), Key: m[2]}, true
+}
+
+// String returns the aggregate as COUNT(*) or SUM(price) whichever way it's written
+func (a Aggregate) String() string {
+	return a.Function + "(" + a.Key + ")"
+}
+
+// parseOrderBy returns the attributes of the ORDER BY clause, like ORDER BY country, coins DESC,
+// nil without ORDER BY
+func parseOrderBy(sql string) []OrderBy {
+	clause, ok := clauseOf(sql, orderByClauseRegexp, orderByEndRegexp)
+	if !ok {
+		return nil
+	}
+	orderBy := []OrderBy{}
+	for _, item := range SplitTopLevel(clause, ",") {
+		o := OrderBy{Key: strings.TrimSpace(item)}
+		if d := directionRegexp.FindStringSubmatch(o.Key); d != nil {
+			o.Key, o.Desc = strings.TrimSpace(d[1]), strings.ToUpper(d[2]) == "DESC"
+		}
+		orderBy = append(orderBy, o)
+	}
+	return orderBy
+}
+
+// String returns the attribute as it is written in ORDER BY
+func (o OrderBy) String() string {
+	if o.Desc {
+		return o.Key + " DESC"
+	}
+	return o.Key
+}
+
+// splitConditions splits a WHERE clause by AND, the AND of BETWEEN a AND b is kept in its condition
+func splitConditions(conditionStr string) []string {
+	conditions := []string{}
+	start, between := 0, false
+	for _, m := range findTopLevel(andRegexp, conditionStr) {
+		segment := conditionStr[start:m[0]]
+		if !between && len(findTopLevel(betweenRegexp, segment)) > 0 {
+			between = true
+			continue
+		}
+		conditions = append(conditions, strings.TrimSpace(segment))
+		start, between = m[1], false
+	}
+	return append(conditions, strings.TrimSpace(conditionStr[start:]))
+}
+
+// closingParen returns the index of the parenthesis closing the one at open, -1 if there is none
+func closingParen(s string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
 			}
-		} else {
-			attributesToGet = append(attributesToGet, strings.TrimSpace(t))
 		}
 	}
-	return attributesToGet
+	return -1
+}
+
+// switchFunction parses a predicate function like begins_with(name, 'ab') or a size comparison like size(tags) > 3,
+// ok is false if condition does not start with a function
+func switchFunction(condition, nextLogicalOperator string) (Condition, bool) {
+	m := predicateRegexp.FindStringSubmatchIndex(condition)
+	if m == nil {
+		return Condition{}, false
+	}
+	name := strings.ToLower(condition[m[2]:m[3]])
+	if name == "exists" {
+		subquery, ok := subqueryOf(condition[m[1]-1:])
+		if !ok {
+			return invalidCondition(condition, nextLogicalOperator), true
+		}
+		return Condition{Operator: OpExists, Subquery: subquery, NextLogicalOperator: nextLogicalOperator}, true
+	}
+	arity, isPredicate := predicateArities[name]
+	if !isPredicate && name != FunctionSize {
+		return Condition{}, false
+	}
+	invalid := invalidCondition(condition, nextLogicalOperator)
+	end := closingParen(condition, m[1]-1)
+	if end == -1 {
+		return invalid, true
+	}
+	args := SplitTopLevel(condition[m[1]:end], ",")
+	for idx := range args {
+		args[idx] = strings.TrimSpace(args[idx])
+	}
+	rest := strings.TrimSpace(condition[end+1:])
+	if name == FunctionSize {
+		c := switchCondition(args[0]+" "+rest, nextLogicalOperator)
+		switch c.Operator {
+		case OpEq, opGt, opLt, opGtEq, opLtEq, opNeq, OpBetween:
+			if len(args) == 1 && c.Key == args[0] && c.Function == "" {
+				c.Function = FunctionSize
+				return c, true
+			}
+		}
+		return invalid, true
+	}
+	if rest != "" || len(args) != arity || args[0] == "" {
+		return invalid, true
+	}
+	c := Condition{Key: args[0], Operator: name, NextLogicalOperator: nextLogicalOperator}
+	if arity == 2 {
+		c.Value = args[1]
+	}
+	return c, true
 }
 
 func switchCondition(condition, nextLogicalOperator string) Condition {
 	// TODO support OR
 	// TODO last one does not need AND
+	if c, ok := switchFunction(condition, nextLogicalOperator); ok {
+		return c
+	}
+	if m := isRegexp.FindStringSubmatch(condition); m != nil && strings.TrimSpace(m[1]) != "" {
+		op := " IS "
+		if m[2] != "" {
+			op += "NOT "
+		}
+		return Condition{
+			Key:                 strings.TrimSpace(m[1]),
+			Operator:            op + strings.ToUpper(m[3]),
+			NextLogicalOperator: nextLogicalOperator,
+		}
+	}
+	if m := findTopLevel(betweenRegexp, condition); len(m) > 0 {
+		bounds := findTopLevel(andRegexp, condition[m[0][1]:])
+		if len(bounds) == 1 {
+			rest := condition[m[0][1]:]
+			c := Condition{
+				Key:                 strings.TrimSpace(condition[:m[0][0]]),
+				Operator:            OpBetween,
+				Value:               strings.TrimSpace(rest[:bounds[0][0]]),
+				High:                strings.TrimSpace(rest[bounds[0][1]:]),
+				NextLogicalOperator: nextLogicalOperator,
+			}
+			if c.Key != "" && c.Value != "" && c.High != "" {
+				return c
+			}
+		}
+		return invalidCondition(condition, nextLogicalOperator)
+	}
+	if m := findTopLevel(inRegexp, condition); len(m) > 0 {
+		c := Condition{
+			Key:                 strings.TrimSpace(condition[:m[0][0]]),
+			Operator:            OpIn,
+			Value:               strings.TrimSpace(condition[m[0][1]:]),
+			NextLogicalOperator: nextLogicalOperator,
+		}
+		if subquery, ok := subqueryOf(c.Value); ok && c.Key != "" {
+			c.Value, c.Subquery = "", subquery
+			return c
+		}
+		if _, _, ok := c.inList(); ok {
+			return c
+		}
+		return invalidCondition(condition, nextLogicalOperator)
+	}
+	// a pattern may contain any of ops
+	if m := findTopLevel(likeRegexp, condition); len(m) > 0 {
+		return Condition{
+			Key:                 strings.TrimSpace(condition[:m[0][0]]),
+			Operator:            " " + strings.ToUpper(condition[m[0][2]:m[0][3]]) + " ",
+			Value:               strings.TrimSpace(condition[m[0][1]:]),
+			NextLogicalOperator: nextLogicalOperator,
+		}
+	}
 	for _, op := range ops {
-		if strings.Contains(condition, op) {
-			tokens := strings.Split(condition, op)
+		if idx := indexTopLevel(condition, op); idx != -1 {
 			return Condition{
-				Key:                 strings.TrimSpace(tokens[0]),
+				Key:                 strings.TrimSpace(condition[:idx]),
 				Operator:            op,
-				Value:               strings.TrimSpace(tokens[1]),
+				Value:               strings.TrimSpace(condition[idx+len(op):]),
 				NextLogicalOperator: nextLogicalOperator,
 			}
 		}
 	}
-	return Condition{
-		Key:                 "",
-		Operator:            "=",
-		Value:               "",
-		NextLogicalOperator: nextLogicalOperator,
+	return invalidCondition(condition, nextLogicalOperator)
+}
+
+// invalidCondition keeps a predicate which can't be parsed so that the statement is rejected naming it
+func invalidCondition(condition, nextLogicalOperator string) Condition {
+	return Condition{Operator: OpInvalid, Value: strings.TrimSpace(condition), NextLogicalOperator: nextLogicalOperator}
+}
+
+// subqueryOf returns the SELECT of a subquery in parentheses like (SELECT id FROM users), ok is false if s is not one
+func subqueryOf(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") || closingParen(s, 0) != len(s)-1 {
+		return "", false
 	}
+	subquery := strings.TrimSpace(s[1 : len(s)-1])
+	return subquery, SelectRegexp.MatchString(subquery)
+}
+
+// String returns the condition as it is written in WHERE
+func (c Condition) String() string {
+	if c.Operator == OpInvalid {
+		return c.Value
+	}
+	if c.Operator == OpExists {
+		return "EXISTS (" + c.Subquery + ")"
+	} else if c.Subquery != "" {
+		return c.Key + c.Operator + "(" + c.Subquery + ")"
+	}
+	key := c.Key
+	if c.Function != "" {
+		key = c.Function + "(" + c.Key + ")"
+	}
+	switch {
+	case predicateArities[c.Operator] == 1:
+		return c.Operator + "(" + key + ")"
+	case predicateArities[c.Operator] == 2:
+		return c.Operator + "(" + key + ", " + c.Value + ")"
+	case c.Operator == OpBetween:
+		return key + OpBetween + c.Value + " AND " + c.High
+	case strings.HasPrefix(c.Operator, " "):
+		return key + c.Operator + c.Value
+	}
+	return key + " " + c.Operator + " " + c.Value
+}
+
+// InList returns the attributes and the rows of values of an IN condition, each row has a value of every attribute,
+// id IN (1, 2) is [id] [[1] [2]] and (pk, sk) IN ((1, 'a'), (2, 'b')) is [pk sk] [[1 'a'] [2 'b']]
+func (c Condition) InList() ([]string, [][]string) {
+	keys, rows, _ := c.inList()
+	return keys, rows
+}
+
+// InAttributes returns the attributes before IN, (pk, sk) IN ... is [pk sk]
+func (c Condition) InAttributes() []string {
+	inner, ok := unwrapTuple(c.Key)
+	if !ok {
+		return []string{c.Key}
+	}
+	keys := []string{}
+	for _, k := range SplitTopLevel(inner, ",") {
+		keys = append(keys, strings.TrimSpace(k))
+	}
+	return keys
+}
+
+// unwrapTuple returns what is inside the parentheses of s, ok is false if s is not in parentheses
+func unwrapTuple(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return s, false
+	}
+	return s[1 : len(s)-1], true
+}
+
+// inList is InList, ok is false if the list is empty or a row does not match the attributes
+func (c Condition) inList() ([]string, [][]string, bool) {
+	keys := c.InAttributes()
+	list, ok := unwrapTuple(c.Value)
+	if !ok || strings.TrimSpace(list) == "" {
+		return nil, nil, false
+	}
+	rows := [][]string{}
+	for _, item := range SplitTopLevel(list, ",") {
+		row := []string{strings.TrimSpace(item)}
+		if len(keys) > 1 {
+			tuple, ok := unwrapTuple(item)
+			if !ok {
+				return nil, nil, false
+			}
+			row = []string{}
+			for _, v := range SplitTopLevel(tuple, ",") {
+				row = append(row, strings.TrimSpace(v))
+			}
+		}
+		if len(row) != len(keys) || row[0] == "" {
+			return nil, nil, false
+		}
+		rows = append(rows, row)
+	}
+	return keys, rows, true
+}
+
+// IsWriteStatement tells whether the statement changes items or tables, i.e. not allowed in read-only mode
+func IsWriteStatement(sql string) bool {
+	return writeRegexp.MatchString(sql)
 }
 
 // ParseSelect parse a select sql statement to go struct
 // ParseSelect can only parse select, other statement will go wrong
 func ParseSelect(selectSQL string) SelectStatement {
 	// TODO trim all space
-	attributesToGetStr := killAllKeyWords(selectStmtRegexp.FindString(selectSQL))
-	tableName := killAllKeyWords(FromStmtRegexp.FindString(selectSQL))
-	conditionStr := killAllKeyWords(WhereStmtRegexp.FindString(selectSQL))
-	limitStr := killAllKeyWords(LimitStmtRegexp.FindString(selectSQL))
-	// TODO support OFFSET
+	selectSQL, hints := cutHints(selectSQL)
+	attributesToGetStr, distinct, fromSQL := selectList(selectSQL)
+	var tableName, tableAlias string
+	var joins []Join
+	if m := FromStmtRegexp.FindStringSubmatch(fromSQL); m != nil {
+		tableName, tableAlias, joins = parseFrom(strings.TrimSpace(m[2]))
+	}
+	conditionStr := whereClause(selectSQL)
+	limitStr, _ := clauseOf(selectSQL, limitClauseRegexp, limitEndRegexp)
+	offsetStr, hasOffset := clauseOf(selectSQL, offsetClauseRegexp, offsetEndRegexp)
+	after, _ := clauseOf(selectSQL, afterClauseRegexp, afterEndRegexp)
 
 	// TODO match OR
-	conditions := strings.Split(conditionStr, " AND ")
+	conditions := splitConditions(conditionStr)
 	stmt := SelectStatement{
 		AttributesToGet: parseAttributesToGet(attributesToGetStr),
+		Distinct:        distinct,
 		TableName:       tableName,
+		TableAlias:      tableAlias,
+		Joins:           joins,
 		Conditions:      []Condition{},
+		GroupBy:         parseGroupBy(selectSQL),
+		Having:          parseHaving(selectSQL),
+		OrderBy:         parseOrderBy(selectSQL),
+		After:           after,
+		Hints:           hints,
+	}
+	if len(after) >= 2 && after[0] == '\'' && after[len(after)-1] == '\'' {
+		stmt.After = after[1 : len(after)-1]
 	}
-	// if there is a limit statement, use it instead default 1
+	if offset, err := strconv.ParseInt(offsetStr, 10, 64); err == nil && offset >= 0 {
+		stmt.Offset = offset
+	} else if hasOffset {
+		stmt.Offset = -1
+	}
+	// if there is a limit statement, use it instead DefaultLimit, which does not apply to the groups of aggregates
 	if limit, err := strconv.Atoi(limitStr); err == nil {
 		stmt.Limit = int64(limit)
-	} else if limitStr == "ALL" {
+	} else if strings.ToUpper(limitStr) == "ALL" || (limitStr == "" && stmt.IsAggregate()) {
 		stmt.Limit = -1
 	} else {
-		stmt.Limit = 1
+		stmt.Limit = DefaultLimit
 	}
 	if conditionStr != "" {
 		for _, c := range conditions {
@@ -162,35 +733,158 @@ func ParseSelect(selectSQL string) SelectStatement {
 	return stmt
 }
 
+// ParseSubquery parses the SELECT of a subquery, which has no END and reads every row without LIMIT
+func ParseSubquery(subquery string) SelectStatement {
+	selectSQL := subquery + " END"
+	stmt := ParseSelect(selectSQL)
+	if _, ok := clauseOf(selectSQL, limitClauseRegexp, limitEndRegexp); !ok {
+		stmt.Limit = -1
+	}
+	return stmt
+}
+
 // ParseUpdate parse an update SQL string to UpdateStatement, mainly just extract tokens
 func ParseUpdate(updateSQL string) UpdateStatement {
-	attributesToGetStr := killAllKeyWords(returningStmtRegexp.FindString(updateSQL))
-	updateStr := killAllKeyWords(setStmtRegexp.FindString(updateSQL))
-	tableName := killAllKeyWords(updateStmtRegexp.FindString(updateSQL))
-	conditionStr := killAllKeyWords(whereStmtRegexpForUpdate.FindString(updateSQL))
+	tableName, updateStr, rest := splitUpdate(updateSQL)
+	returnValues, attributesToGetStr := parseReturning(rest)
+	conditionStr := whereClause(rest)
 	stmt := UpdateStatement{
 		AttributesToGet:   parseAttributesToGet(attributesToGetStr),
+		ReturnValues:      returnValues,
 		TableName:         tableName,
 		Conditions:        []Condition{},
-		UpdateExpressions: []UpdateExpression{},
+		UpdateExpressions: parseUpdateExpressions(updateStr),
 	}
-	if updateStr != "" {
-		for _, u := range strings.Split(updateStr, ",") {
-			tokens := strings.Split(u, "=")
-			stmt.UpdateExpressions = append(stmt.UpdateExpressions, UpdateExpression{
-				Key:   tokens[0],
-				Value: tokens[1],
-			})
+	if strings.ToUpper(conditionStr) == "ALL" {
+		stmt.All = true
+	} else if conditionStr != "" {
+		for _, c := range splitConditions(conditionStr) {
+			stmt.Conditions = append(stmt.Conditions, switchCondition(c, "AND"))
 		}
 	}
-	if conditionStr != "" {
-		for _, c := range strings.Split(conditionStr, " AND ") {
+	return stmt
+}
+
+// parseReturning returns the ReturnValues and the attributes of a RETURNING clause like
+// RETURNING NONE, RETURNING OLD *, RETURNING NEW a,b or RETURNING UPDATED OLD a,
+// which returns all attributes of the new item by default
+func parseReturning(updateSQL string) (string, string) {
+	m := returningStmtRegexp.FindStringSubmatch(updateSQL)
+	if m == nil {
+		return "", ""
+	}
+	tokens := strings.Fields(m[4])
+	if len(tokens) > 0 && strings.ToUpper(tokens[0]) == "NONE" {
+		return ReturnNone, ""
+	}
+	scope, version := "ALL", "NEW"
+	for len(tokens) > 0 {
+		word := strings.ToUpper(tokens[0])
+		if word == "ALL" || word == "UPDATED" {
+			scope = word
+		} else if word == "OLD" || word == "NEW" {
+			version = word
+		} else {
+			break
+		}
+		tokens = tokens[1:]
+	}
+	return scope + "_" + version, strings.Join(tokens, " ")
+}
+
+// ParseDelete parse a delete SQL string to DeleteStatement
+func ParseDelete(deleteSQL string) DeleteStatement {
+	tableName := killAllKeyWords(FromStmtRegexp.FindString(deleteSQL))
+	conditionStr := whereClause(deleteSQL)
+	stmt := DeleteStatement{
+		TableName:  tableName,
+		Conditions: []Condition{},
+	}
+	if strings.ToUpper(conditionStr) == "ALL" {
+		stmt.All = true
+	} else if conditionStr != "" {
+		for _, c := range splitConditions(conditionStr) {
 			stmt.Conditions = append(stmt.Conditions, switchCondition(c, "AND"))
 		}
 	}
 	return stmt
 }
 
+// ParseInsert parse an insert SQL string like INSERT INTO user (user_id, name) VALUES (1, 'a'), (2, 'b')
+func ParseInsert(insertSQL string) InsertStatement {
+	m := insertStmtRegexp.FindStringSubmatch(insertSQL)
+	if m == nil {
+		return InsertStatement{}
+	}
+	stmt := InsertStatement{
+		TableName:  m[1],
+		Attributes: parseAttributesToGet(m[2]),
+		Rows:       [][]string{},
+	}
+	for _, row := range SplitTopLevel(m[3], ",") {
+		row = strings.TrimSpace(row)
+		values := []string{}
+		if strings.HasPrefix(row, "(") && strings.HasSuffix(row, ")") {
+			for _, v := range SplitTopLevel(row[1:len(row)-1], ",") {
+				values = append(values, strings.TrimSpace(v))
+			}
+		}
+		stmt.Rows = append(stmt.Rows, values)
+	}
+	return stmt
+}
+
+// ParseUndo parse UNDO or UNDO n, Count is 0 if it's neither
+func ParseUndo(undoSQL string) UndoStatement {
+	m := undoStmtRegexp.FindStringSubmatch(undoSQL)
+	if m == nil {
+		return UndoStatement{}
+	}
+	if m[1] == "" {
+		return UndoStatement{Count: 1}
+	}
+	count, _ := strconv.Atoi(m[1])
+	return UndoStatement{Count: count}
+}
+
+// ParseNext parse NEXT or NEXT n, ok is false if it's neither
+func ParseNext(nextSQL string) (NextStatement, bool) {
+	m := nextStmtRegexp.FindStringSubmatch(nextSQL)
+	if m == nil {
+		return NextStatement{}, false
+	}
+	limit, _ := strconv.ParseInt(m[1], 10, 64)
+	return NextStatement{Limit: limit}, true
+}
+
+// ParseBegin parse BEGIN, BEGIN READ WRITE or BEGIN READ ONLY
+func ParseBegin(beginSQL string) BeginStatement {
+	m := beginStmtRegexp.FindStringSubmatch(beginSQL)
+	if m == nil {
+		return BeginStatement{}
+	}
+	if strings.ToUpper(m[2]) == "ONLY" {
+		return BeginStatement{Mode: TransactionReadOnly}
+	}
+	return BeginStatement{Mode: TransactionReadWrite}
+}
+
+// ParseCheck parse a CHECK statement of a transaction, TableName is empty if it's not one
+func ParseCheck(checkSQL string) CheckStatement {
+	m := checkStmtRegexp.FindStringSubmatch(checkSQL)
+	if m == nil {
+		return CheckStatement{}
+	}
+	stmt := CheckStatement{
+		TableName:  m[1],
+		Conditions: []Condition{},
+	}
+	for _, c := range splitConditions(m[2]) {
+		stmt.Conditions = append(stmt.Conditions, switchCondition(c, "AND"))
+	}
+	return stmt
+}
+
 // ParseDescTable parse a describe table SQL string to DescTableStatement, extract table name
 func ParseDescTable(descTableSQL string) DescTableStatement {
 	return DescTableStatement{
@@ -19,6 +19,11 @@ func Test_killAllKeyWords(t *testing.T) {
 			args: args{s: "SELECT * FROM user LIMIT 10 END"},
 			want: "*user10",
 		},
+		{
+			name: "test killAllKeyWords in words",
+			args: args{s: "FROM calendar WHERE status=pending END"},
+			want: "calendarstatus=pending",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -43,6 +48,11 @@ func Test_parseAttributesToGet(t *testing.T) {
 			args: args{attributesToGetStr: "user_id,age,name"},
 			want: []string{"user_id", "age", "name"},
 		},
+		{
+			name: "test parseAttributesToGet with nested paths",
+			args: args{attributesToGetStr: "address.city, `a,b`, items[0]"},
+			want: []string{"address.city", "`a,b`", "items[0]"},
+		},
 		{
 			name: "test parseAttributesToGet without ,",
 			args: args{attributesToGetStr: "user_id"},
@@ -88,12 +98,91 @@ func Test_switchCondition(t *testing.T) {
 				nextLogicalOperator: "AND",
 			},
 			want: Condition{
-				Key:                 "",
-				Operator:            "=",
-				Value:               "",
+				Operator:            OpInvalid,
+				Value:               "user_id+123",
 				NextLogicalOperator: "AND",
 			},
 		},
+		{
+			name: "test switchCondition with BETWEEN",
+			args: args{condition: "ts between 1001 and 1003", nextLogicalOperator: "AND"},
+			want: Condition{Key: "ts", Operator: OpBetween, Value: "1001", High: "1003", NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with BETWEEN without AND",
+			args: args{condition: "ts BETWEEN 1001", nextLogicalOperator: "AND"},
+			want: Condition{Operator: OpInvalid, Value: "ts BETWEEN 1001", NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with IS NOT MISSING",
+			args: args{condition: "email is not missing", nextLogicalOperator: "AND"},
+			want: Condition{Key: "email", Operator: OpIsNotMissing, NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with IS NULL",
+			args: args{condition: "email IS NULL", nextLogicalOperator: "AND"},
+			want: Condition{Key: "email", Operator: OpIsNull, NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with a predicate function",
+			args: args{condition: "Begins_With(name, 'a, (b')", nextLogicalOperator: "AND"},
+			want: Condition{Key: "name", Operator: OpBeginsWith, Value: "'a, (b'", NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with a predicate function of one argument",
+			args: args{condition: "attribute_exists(email)", nextLogicalOperator: "AND"},
+			want: Condition{Key: "email", Operator: OpAttributeExists, NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with a predicate function of wrong arity",
+			args: args{condition: "attribute_type(email)", nextLogicalOperator: "AND"},
+			want: Condition{Operator: OpInvalid, Value: "attribute_type(email)", NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with size",
+			args: args{condition: "size(tags) >= 3", nextLogicalOperator: "AND"},
+			want: Condition{Key: "tags", Operator: ">=", Value: "3", Function: FunctionSize, NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with size BETWEEN",
+			args: args{condition: "size(tags) BETWEEN 1 AND 3", nextLogicalOperator: "AND"},
+			want: Condition{Key: "tags", Operator: OpBetween, Value: "1", High: "3", Function: FunctionSize, NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with LIKE of a pattern containing =",
+			args: args{condition: "note like '%a=b%'", nextLogicalOperator: "AND"},
+			want: Condition{Key: "note", Operator: OpLike, Value: "'%a=b%'", NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with a nested path and an escaped name",
+			args: args{condition: "`a=b`.items[0].sku = 'x=1'", nextLogicalOperator: "AND"},
+			want: Condition{Key: "`a=b`.items[0].sku", Operator: "=", Value: "'x=1'", NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with REGEXP",
+			args: args{condition: "name REGEXP '^user(1|2)'", nextLogicalOperator: "AND"},
+			want: Condition{Key: "name", Operator: OpRegexp, Value: "'^user(1|2)'", NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with size IS NULL",
+			args: args{condition: "size(tags) IS NULL", nextLogicalOperator: "AND"},
+			want: Condition{Operator: OpInvalid, Value: "size(tags) IS NULL", NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with IN without parentheses",
+			args: args{condition: "user_id IN 1, 2", nextLogicalOperator: "AND"},
+			want: Condition{Operator: OpInvalid, Value: "user_id IN 1, 2", NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with IN (SELECT ...)",
+			args: args{condition: "userId IN (SELECT user_id FROM user WHERE country='NZ')", nextLogicalOperator: "AND"},
+			want: Condition{Key: "userId", Operator: OpIn, Subquery: "SELECT user_id FROM user WHERE country='NZ'", NextLogicalOperator: "AND"},
+		},
+		{
+			name: "test switchCondition with EXISTS",
+			args: args{condition: "EXISTS ( SELECT * FROM orders WHERE userId = user.user_id )", nextLogicalOperator: "AND"},
+			want: Condition{Operator: OpExists, Subquery: "SELECT * FROM orders WHERE userId = user.user_id", NextLogicalOperator: "AND"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -193,6 +282,152 @@ func TestParseSelect(t *testing.T) {
 				Limit: -1,
 			},
 		},
+		{
+			name: "test parseSelect with hints",
+			args: args{selectSQL: `SELECT /*+ CONSISTENT */ name FROM user WHERE user_id=1 LIMIT ALL WITH (PARALLEL(4)) END`},
+			want: SelectStatement{
+				AttributesToGet: []string{"name"},
+				TableName:       "user",
+				Conditions:      []Condition{{Key: "user_id", Value: "1", Operator: "=", NextLogicalOperator: "AND"}},
+				Limit:           -1,
+				Hints:           Hints{Consistent: true, Parallel: 4},
+			},
+		},
+		{
+			name: "test parseSelect with IN",
+			args: args{selectSQL: `SELECT * FROM orders WHERE (userId, ts) IN ((1, 1001), (2, 1002)) AND status in ('paid', 'x=1') END`},
+			want: SelectStatement{
+				AttributesToGet: []string{"*"},
+				TableName:       "orders",
+				Conditions: []Condition{
+					Condition{
+						Key:                 "(userId, ts)",
+						Value:               "((1, 1001), (2, 1002))",
+						Operator:            OpIn,
+						NextLogicalOperator: "AND",
+					},
+					Condition{
+						Key:                 "status",
+						Value:               "('paid', 'x=1')",
+						Operator:            OpIn,
+						NextLogicalOperator: "AND",
+					},
+				},
+				Limit: 1,
+			},
+		},
+		{
+			name: "test parseSelect with BETWEEN and keywords in values",
+			args: args{selectSQL: `SELECT * FROM calendar WHERE ts BETWEEN 1 AND 5 AND status=pending AND note = 'where it ends' AND size(tags) > 1 LIMIT 3 END`},
+			want: SelectStatement{
+				AttributesToGet: []string{"*"},
+				TableName:       "calendar",
+				Conditions: []Condition{
+					{Key: "ts", Operator: OpBetween, Value: "1", High: "5", NextLogicalOperator: "AND"},
+					{Key: "status", Operator: "=", Value: "pending", NextLogicalOperator: "AND"},
+					{Key: "note", Operator: "=", Value: "'where it ends'", NextLogicalOperator: "AND"},
+					{Key: "tags", Operator: ">", Value: "1", Function: FunctionSize, NextLogicalOperator: "AND"},
+				},
+				Limit: 3,
+			},
+		},
+		{
+			name: "test parseSelect with ORDER BY",
+			args: args{selectSQL: `SELECT * FROM orders WHERE userId=1 AND note='order by ts' ORDER BY ts DESC, region asc, price LIMIT 10 END`},
+			want: SelectStatement{
+				AttributesToGet: []string{"*"},
+				TableName:       "orders",
+				Conditions: []Condition{
+					{Key: "userId", Operator: "=", Value: "1", NextLogicalOperator: "AND"},
+					{Key: "note", Operator: "=", Value: "'order by ts'", NextLogicalOperator: "AND"},
+				},
+				OrderBy: []OrderBy{{Key: "ts", Desc: true}, {Key: "region"}, {Key: "price"}},
+				Limit:   10,
+			},
+		},
+		{
+			name: "test parseSelect with ORDER BY without WHERE",
+			args: args{selectSQL: "SELECT user_id FROM user ORDER BY `last name` DESC END"},
+			want: SelectStatement{
+				AttributesToGet: []string{"user_id"},
+				TableName:       "user",
+				Conditions:      []Condition{},
+				OrderBy:         []OrderBy{{Key: "`last name`", Desc: true}},
+				Limit:           1,
+			},
+		},
+		{
+			name: "test parseSelect with GROUP BY and HAVING",
+			args: args{selectSQL: `SELECT region, COUNT(*), sum(price) FROM orders WHERE status=pending GROUP BY region HAVING COUNT(*) > 1 AND region != 'group by' ORDER BY SUM(price) DESC END`},
+			want: SelectStatement{
+				AttributesToGet: []string{"region", "COUNT(*)", "sum(price)"},
+				TableName:       "orders",
+				Conditions:      []Condition{{Key: "status", Operator: "=", Value: "pending", NextLogicalOperator: "AND"}},
+				GroupBy:         []string{"region"},
+				Having: []Condition{
+					{Key: "COUNT(*)", Operator: ">", Value: "1", NextLogicalOperator: "AND"},
+					{Key: "region", Operator: "!=", Value: "'group by'", NextLogicalOperator: "AND"},
+				},
+				OrderBy: []OrderBy{{Key: "SUM(price)", Desc: true}},
+				Limit:   -1,
+			},
+		},
+		{
+			name: "test parseSelect with LIMIT and OFFSET",
+			args: args{selectSQL: "SELECT * FROM orders WHERE userId=1 LIMIT 10 OFFSET 20 END"},
+			want: SelectStatement{
+				AttributesToGet: []string{"*"},
+				TableName:       "orders",
+				Conditions:      []Condition{{Key: "userId", Operator: "=", Value: "1", NextLogicalOperator: "AND"}},
+				Limit:           10,
+				Offset:          20,
+			},
+		},
+		{
+			name: "test parseSelect with OFFSET, LIMIT and AFTER",
+			args: args{selectSQL: "SELECT * FROM orders OFFSET 5 LIMIT 2 AFTER 'eyJ1c2VySWQiOnsiTiI6IjEifX0' END"},
+			want: SelectStatement{
+				AttributesToGet: []string{"*"},
+				TableName:       "orders",
+				Conditions:      []Condition{},
+				Limit:           2,
+				Offset:          5,
+				After:           "eyJ1c2VySWQiOnsiTiI6IjEifX0",
+			},
+		},
+		{
+			name: "test parseSelect with an invalid OFFSET and LIMIT in quotes",
+			args: args{selectSQL: "SELECT * FROM orders WHERE note='LIMIT 3' OFFSET x END"},
+			want: SelectStatement{
+				AttributesToGet: []string{"*"},
+				TableName:       "orders",
+				Conditions:      []Condition{{Key: "note", Operator: "=", Value: "'LIMIT 3'", NextLogicalOperator: "AND"}},
+				Limit:           1,
+				Offset:          -1,
+			},
+		},
+		{
+			name: "test parseSelect with DISTINCT and computed columns",
+			args: args{selectSQL: `SELECT DISTINCT status, CASE WHEN price > 10 THEN 'high' ELSE 'low' END AS band, 'from where' FROM orders WHERE userId=1 END`},
+			want: SelectStatement{
+				AttributesToGet: []string{"status", "CASE WHEN price > 10 THEN 'high' ELSE 'low' END AS band", "'from where'"},
+				Distinct:        true,
+				TableName:       "orders",
+				Conditions:      []Condition{{Key: "userId", Operator: "=", Value: "1", NextLogicalOperator: "AND"}},
+				Limit:           1,
+			},
+		},
+		{
+			name: "test parseSelect with an aggregate and LIMIT",
+			args: args{selectSQL: `SELECT count(*) FROM orders GROUP BY region LIMIT 2 END`},
+			want: SelectStatement{
+				AttributesToGet: []string{"count(*)"},
+				TableName:       "orders",
+				Conditions:      []Condition{},
+				GroupBy:         []string{"region"},
+				Limit:           2,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -203,6 +438,137 @@ func TestParseSelect(t *testing.T) {
 	}
 }
 
+func TestParseAggregate(t *testing.T) {
+	tests := []struct {
+		name   string
+		s      string
+		want   Aggregate
+		wantOk bool
+	}{
+		{name: "test ParseAggregate of COUNT(*)", s: "count( * )", want: Aggregate{Function: AggregateCount, Key: "*"}, wantOk: true},
+		{name: "test ParseAggregate of a nested path", s: "Sum(items[0].qty)", want: Aggregate{Function: AggregateSum, Key: "items[0].qty"}, wantOk: true},
+		{name: "test ParseAggregate of an attribute", s: "counter", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk := ParseAggregate(tt.s)
+			if got != tt.want || gotOk != tt.wantOk {
+				t.Errorf("ParseAggregate() = %v, %v, want %v, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestCondition_String(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition Condition
+		want      string
+	}{
+		{name: "test String", condition: Condition{Key: "user_id", Operator: "=", Value: "1"}, want: "user_id = 1"},
+		{name: "test String with ILIKE", condition: Condition{Key: "name", Operator: OpILike, Value: "'ja%'"}, want: "name ILIKE 'ja%'"},
+		{name: "test String with IS NOT NULL", condition: Condition{Key: "email", Operator: OpIsNotNull}, want: "email IS NOT NULL"},
+		{name: "test String with size BETWEEN", condition: Condition{Key: "tags", Operator: OpBetween, Value: "1", High: "3", Function: FunctionSize}, want: "size(tags) BETWEEN 1 AND 3"},
+		{name: "test String with a predicate function", condition: Condition{Key: "name", Operator: OpBeginsWith, Value: "'ab'"}, want: "begins_with(name, 'ab')"},
+		{name: "test String with attribute_exists", condition: Condition{Key: "email", Operator: OpAttributeExists}, want: "attribute_exists(email)"},
+		{name: "test String with IN (SELECT ...)", condition: Condition{Key: "userId", Operator: OpIn, Subquery: "SELECT user_id FROM user"}, want: "userId IN (SELECT user_id FROM user)"},
+		{name: "test String with EXISTS", condition: Condition{Operator: OpExists, Subquery: "SELECT * FROM orders"}, want: "EXISTS (SELECT * FROM orders)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.condition.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_splitConditions(t *testing.T) {
+	tests := []struct {
+		name         string
+		conditionStr string
+		want         []string
+	}{
+		{name: "test splitConditions", conditionStr: "a=1 AND b=2", want: []string{"a=1", "b=2"}},
+		{name: "test splitConditions with BETWEEN", conditionStr: "a BETWEEN 1 and 2 AND b BETWEEN 3 AND 4 AND c=5", want: []string{"a BETWEEN 1 and 2", "b BETWEEN 3 AND 4", "c=5"}},
+		{name: "test splitConditions with AND in quotes", conditionStr: "name = 'Tom AND Jerry' AND contains(tags, ' and ')", want: []string{"name = 'Tom AND Jerry'", "contains(tags, ' and ')"}},
+		{name: "test splitConditions with a word containing and", conditionStr: "brand=sandy AND band=1", want: []string{"brand=sandy", "band=1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitConditions(tt.conditionStr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitConditions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCondition_InList(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		wantKeys  []string
+		wantRows  [][]string
+		wantOk    bool
+	}{
+		{
+			name:      "test InList",
+			condition: "user_id IN (1, 2,3)",
+			wantKeys:  []string{"user_id"},
+			wantRows:  [][]string{{"1"}, {"2"}, {"3"}},
+			wantOk:    true,
+		},
+		{
+			name:      "test InList with tuples",
+			condition: "(userId, ts) IN ((1, 1001), (1, 'a, b'))",
+			wantKeys:  []string{"userId", "ts"},
+			wantRows:  [][]string{{"1", "1001"}, {"1", "'a, b'"}},
+			wantOk:    true,
+		},
+		{name: "test InList with an empty list", condition: "user_id IN ()"},
+		{name: "test InList with a short tuple", condition: "(userId, ts) IN ((1, 1001), (2))"},
+		{name: "test InList without parentheses", condition: "user_id IN 1, 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := switchCondition(tt.condition, "AND")
+			if (c.Operator == OpIn) != tt.wantOk {
+				t.Fatalf("switchCondition() = %+v, want IN %v", c, tt.wantOk)
+			}
+			if !tt.wantOk {
+				return
+			}
+			if keys, rows := c.InList(); !reflect.DeepEqual(keys, tt.wantKeys) || !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("InList() = %v, %v, want %v, %v", keys, rows, tt.wantKeys, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestParseSubquery(t *testing.T) {
+	tests := []struct {
+		name          string
+		subquery      string
+		wantTableName string
+		wantAlias     string
+		wantLimit     int64
+		wantCount     int
+	}{
+		{name: "test ParseSubquery", subquery: "SELECT user_id FROM user WHERE country='NZ'", wantTableName: "user", wantLimit: -1, wantCount: 1},
+		{name: "test ParseSubquery with LIMIT", subquery: "SELECT userId FROM orders WHERE status='pending' LIMIT 5", wantTableName: "orders", wantLimit: 5, wantCount: 1},
+		{name: "test ParseSubquery with an alias", subquery: "SELECT * FROM orders o WHERE o.userId = u.user_id", wantTableName: "orders", wantAlias: "o", wantLimit: -1, wantCount: 1},
+		{name: "test ParseSubquery without FROM", subquery: "SELECT 1", wantLimit: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseSubquery(tt.subquery)
+			if got.TableName != tt.wantTableName || got.TableAlias != tt.wantAlias || got.Limit != tt.wantLimit || len(got.Conditions) != tt.wantCount {
+				t.Errorf("ParseSubquery() = %+v, want table %v alias %v limit %v and %v conditions", got, tt.wantTableName, tt.wantAlias, tt.wantLimit, tt.wantCount)
+			}
+		})
+	}
+}
+
 func TestParseUpdate(t *testing.T) {
 	type args struct {
 		updateSQL string
@@ -217,6 +583,7 @@ func TestParseUpdate(t *testing.T) {
 			args: args{updateSQL: "UPDATE user SET user_name=xinbg WHERE user_id=123 RETRUNING user_name,phone END"},
 			want: UpdateStatement{
 				AttributesToGet: []string{"user_name", "phone"},
+				ReturnValues:    ReturnAllNew,
 				TableName:       "user",
 				Conditions: []Condition{Condition{
 					Key:                 "user_id",
@@ -225,9 +592,64 @@ func TestParseUpdate(t *testing.T) {
 					NextLogicalOperator: "AND",
 				}},
 				UpdateExpressions: []UpdateExpression{UpdateExpression{
-					Key:   "user_name",
-					Value: "xinbg",
+					Action: ActionSet,
+					Key:    "user_name",
+					Value:  "xinbg",
+				}},
+			},
+		},
+		{
+			name: "test ParseUpdate with WHERE ALL",
+			args: args{updateSQL: "UPDATE user SET coins=0 WHERE ALL END"},
+			want: UpdateStatement{
+				AttributesToGet: []string{""},
+				TableName:       "user",
+				Conditions:      []Condition{},
+				UpdateExpressions: []UpdateExpression{UpdateExpression{
+					Action: ActionSet,
+					Key:    "coins",
+					Value:  "0",
+				}},
+				All: true,
+			},
+		},
+		{
+			name: "test ParseUpdate with REMOVE, ADD and DELETE",
+			args: args{updateSQL: "UPDATE user SET coins = coins + 100, tags = list_append(tags, ['vip', 'new']), note='pending, WHERE ever' " +
+				"REMOVE tempField, draft ADD visits 1 DELETE roles <<'admin'>> WHERE user_id=123 END"},
+			want: UpdateStatement{
+				AttributesToGet: []string{""},
+				TableName:       "user",
+				Conditions: []Condition{Condition{
+					Key:                 "user_id",
+					Value:               "123",
+					Operator:            "=",
+					NextLogicalOperator: "AND",
+				}},
+				UpdateExpressions: []UpdateExpression{
+					{Action: ActionSet, Key: "coins", Value: "coins + 100"},
+					{Action: ActionSet, Key: "tags", Value: "list_append(tags, ['vip', 'new'])"},
+					{Action: ActionSet, Key: "note", Value: "'pending, WHERE ever'"},
+					{Action: ActionRemove, Key: "tempField"},
+					{Action: ActionRemove, Key: "draft"},
+					{Action: ActionAdd, Key: "visits", Value: "1"},
+					{Action: ActionDelete, Key: "roles", Value: "<<'admin'>>"},
+				},
+			},
+		},
+		{
+			name: "test ParseUpdate with END in a value",
+			args: args{updateSQL: "UPDATE user SET note=the end WHERE user_id=123 END"},
+			want: UpdateStatement{
+				AttributesToGet: []string{""},
+				TableName:       "user",
+				Conditions: []Condition{Condition{
+					Key:                 "user_id",
+					Value:               "123",
+					Operator:            "=",
+					NextLogicalOperator: "AND",
 				}},
+				UpdateExpressions: []UpdateExpression{{Action: ActionSet, Key: "note", Value: "the end"}},
 			},
 		},
 	}
@@ -240,6 +662,225 @@ func TestParseUpdate(t *testing.T) {
 	}
 }
 
+func Test_parseReturning(t *testing.T) {
+	tests := []struct {
+		name                   string
+		updateSQL              string
+		wantReturnValues       string
+		wantAttributesToGetStr string
+	}{
+		{
+			name:                   "test parseReturning without RETURNING",
+			updateSQL:              "",
+			wantReturnValues:       "",
+			wantAttributesToGetStr: "",
+		},
+		{
+			name:                   "test parseReturning with NONE",
+			updateSQL:              "WHERE user_id=1 RETURNING NONE END",
+			wantReturnValues:       ReturnNone,
+			wantAttributesToGetStr: "",
+		},
+		{
+			name:                   "test parseReturning with OLD *",
+			updateSQL:              "RETURNING OLD * END",
+			wantReturnValues:       ReturnAllOld,
+			wantAttributesToGetStr: "*",
+		},
+		{
+			name:                   "test parseReturning with NEW attributes",
+			updateSQL:              "returning new coins,tier END",
+			wantReturnValues:       ReturnAllNew,
+			wantAttributesToGetStr: "coins,tier",
+		},
+		{
+			name:                   "test parseReturning with UPDATED",
+			updateSQL:              "RETURNING UPDATED END",
+			wantReturnValues:       ReturnUpdatedNew,
+			wantAttributesToGetStr: "",
+		},
+		{
+			name:                   "test parseReturning with UPDATED OLD and the old spelling",
+			updateSQL:              "RETRUNING UPDATED OLD coins END",
+			wantReturnValues:       ReturnUpdatedOld,
+			wantAttributesToGetStr: "coins",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			returnValues, attributesToGetStr := parseReturning(tt.updateSQL)
+			if returnValues != tt.wantReturnValues || attributesToGetStr != tt.wantAttributesToGetStr {
+				t.Errorf("parseReturning() = %q, %q, want %q, %q",
+					returnValues, attributesToGetStr, tt.wantReturnValues, tt.wantAttributesToGetStr)
+			}
+		})
+	}
+}
+
+func TestParseDelete(t *testing.T) {
+	type args struct {
+		deleteSQL string
+	}
+	tests := []struct {
+		name string
+		args args
+		want DeleteStatement
+	}{
+		{
+			name: "test ParseDelete",
+			args: args{deleteSQL: "DELETE FROM user WHERE user_id=123 END"},
+			want: DeleteStatement{
+				TableName: "user",
+				Conditions: []Condition{Condition{
+					Key:                 "user_id",
+					Value:               "123",
+					Operator:            "=",
+					NextLogicalOperator: "AND",
+				}},
+			},
+		},
+		{
+			name: "test ParseDelete without WHERE",
+			args: args{deleteSQL: "DELETE FROM user END"},
+			want: DeleteStatement{
+				TableName:  "user",
+				Conditions: []Condition{},
+			},
+		},
+		{
+			name: "test ParseDelete with WHERE ALL",
+			args: args{deleteSQL: "DELETE FROM user WHERE ALL END"},
+			want: DeleteStatement{
+				TableName:  "user",
+				Conditions: []Condition{},
+				All:        true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseDelete(tt.args.deleteSQL); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDelete() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInsert(t *testing.T) {
+	tests := []struct {
+		name      string
+		insertSQL string
+		want      InsertStatement
+	}{
+		{
+			name:      "test ParseInsert",
+			insertSQL: "INSERT INTO user (user_id, name, tags) VALUES (1, 'a, b', ['x', 'y']), (2,b,[]) END",
+			want: InsertStatement{
+				TableName:  "user",
+				Attributes: []string{"user_id", "name", "tags"},
+				Rows:       [][]string{{"1", "'a, b'", "['x', 'y']"}, {"2", "b", "[]"}},
+			},
+		},
+		{
+			name:      "test ParseInsert without VALUES",
+			insertSQL: "INSERT INTO user END",
+			want:      InsertStatement{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseInsert(tt.insertSQL); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseInsert() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		nextSQL string
+		want    NextStatement
+		wantOk  bool
+	}{
+		{name: "test ParseNext", nextSQL: "NEXT END", want: NextStatement{}, wantOk: true},
+		{name: "test ParseNext with count", nextSQL: "next 20 END", want: NextStatement{Limit: 20}, wantOk: true},
+		{name: "test ParseNext with garbage", nextSQL: "NEXT page END", want: NextStatement{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseNext(tt.nextSQL)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("ParseNext() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestParseUndo(t *testing.T) {
+	tests := []struct {
+		name    string
+		undoSQL string
+		want    UndoStatement
+	}{
+		{name: "test ParseUndo", undoSQL: "UNDO END", want: UndoStatement{Count: 1}},
+		{name: "test ParseUndo with count", undoSQL: "undo 3 END", want: UndoStatement{Count: 3}},
+		{name: "test ParseUndo with garbage", undoSQL: "UNDO all END", want: UndoStatement{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseUndo(tt.undoSQL); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseUndo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBegin(t *testing.T) {
+	tests := []struct {
+		name     string
+		beginSQL string
+		want     BeginStatement
+	}{
+		{name: "test ParseBegin", beginSQL: "BEGIN END", want: BeginStatement{Mode: TransactionReadWrite}},
+		{name: "test ParseBegin READ ONLY", beginSQL: "begin read  only END", want: BeginStatement{Mode: TransactionReadOnly}},
+		{name: "test ParseBegin READ WRITE", beginSQL: "BEGIN READ WRITE END", want: BeginStatement{Mode: TransactionReadWrite}},
+		{name: "test ParseBegin with garbage", beginSQL: "BEGIN WORK END", want: BeginStatement{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseBegin(tt.beginSQL); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBegin() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		checkSQL string
+		want     CheckStatement
+	}{
+		{
+			name:     "test ParseCheck",
+			checkSQL: "CHECK user WHERE user_id=1 AND coins >= 100 END",
+			want: CheckStatement{TableName: "user", Conditions: []Condition{
+				{Key: "user_id", Operator: OpEq, Value: "1", NextLogicalOperator: "AND"},
+				{Key: "coins", Operator: opGtEq, Value: "100", NextLogicalOperator: "AND"},
+			}},
+		},
+		{name: "test ParseCheck without WHERE", checkSQL: "CHECK user END", want: CheckStatement{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseCheck(tt.checkSQL); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCheck() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseDescTable(t *testing.T) {
 	type args struct {
 		descTableSQL string
@@ -263,3 +904,27 @@ func TestParseDescTable(t *testing.T) {
 		})
 	}
 }
+
+func TestIsWriteStatement(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want bool
+	}{
+		{name: "test IsWriteStatement with SELECT", sql: "SELECT * FROM user WHERE status=updated", want: false},
+		{name: "test IsWriteStatement with DESC", sql: "DESC TABLE user", want: false},
+		{name: "test IsWriteStatement with UPDATE", sql: "UPDATE user SET name=xinbg WHERE user_id=1", want: true},
+		{name: "test IsWriteStatement with lower case delete", sql: "delete FROM user WHERE user_id=1", want: true},
+		{name: "test IsWriteStatement with INSERT", sql: "INSERT INTO user VALUES (1)", want: true},
+		{name: "test IsWriteStatement with DDL", sql: "DROP TABLE user", want: true},
+		{name: "test IsWriteStatement with UNDO", sql: "UNDO 2", want: true},
+		{name: "test IsWriteStatement with table named like a keyword", sql: "SELECT * FROM updates", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsWriteStatement(tt.sql); got != tt.want {
+				t.Errorf("IsWriteStatement() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package sqlparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathElement is a step of a document path like address.city or items[0].sku,
+// an attribute or a map key in Name, or a list index in Index when Name is empty
+type PathElement struct {
+	Name  string
+	Index int
+}
+
+// ParsePath parses a document path, a name in backticks is taken as it is, like `first.name` or tags.`a b`[1],
+// two backticks in a quoted name stand for one
+func ParsePath(path string) ([]PathElement, error) {
+	invalid := fmt.Errorf("Invalid path %s, quote names with dots or brackets in backticks like `a.b`", path)
+	elements := []PathElement{}
+	expectName := true
+	for i := 0; i < len(path); {
+		switch {
+		case expectName && path[i] == '`':
+			var name strings.Builder
+			for i++; ; i++ {
+				if i >= len(path) {
+					return nil, invalid
+				}
+				if path[i] == '`' {
+					if i+1 < len(path) && path[i+1] == '`' {
+						i++
+					} else {
+						break
+					}
+				}
+				name.WriteByte(path[i])
+			}
+			i++
+			if name.Len() == 0 {
+				return nil, invalid
+			}
+			elements = append(elements, PathElement{Name: name.String()})
+			expectName = false
+		case expectName:
+			end := strings.IndexAny(path[i:], ".[`")
+			if end == -1 {
+				end = len(path) - i
+			}
+			if end == 0 {
+				return nil, invalid
+			}
+			elements = append(elements, PathElement{Name: path[i : i+end]})
+			i += end
+			expectName = false
+		case path[i] == '.':
+			i++
+			expectName = true
+		case path[i] == '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end == -1 {
+				return nil, invalid
+			}
+			index, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil || index < 0 {
+				return nil, invalid
+			}
+			elements = append(elements, PathElement{Index: index})
+			i += end + 1
+		default:
+			return nil, invalid
+		}
+	}
+	if expectName {
+		return nil, invalid
+	}
+	return elements, nil
+}
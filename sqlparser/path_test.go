@@ -0,0 +1,34 @@
+package sqlparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    []PathElement
+		wantErr bool
+	}{
+		{name: "test ParsePath", path: "coins", want: []PathElement{{Name: "coins"}}},
+		{name: "test ParsePath with a map", path: "address.city", want: []PathElement{{Name: "address"}, {Name: "city"}}},
+		{name: "test ParsePath with a list", path: "items[0].sku", want: []PathElement{{Name: "items"}, {Index: 0}, {Name: "sku"}}},
+		{name: "test ParsePath with nested lists", path: "matrix[1][2]", want: []PathElement{{Name: "matrix"}, {Index: 1}, {Index: 2}}},
+		{name: "test ParsePath with backticks", path: "`first.name`.`a``b`[3]", want: []PathElement{{Name: "first.name"}, {Name: "a`b"}, {Index: 3}}},
+		{name: "test ParsePath with a trailing dot", path: "address.", wantErr: true},
+		{name: "test ParsePath with a bad index", path: "items[x]", wantErr: true},
+		{name: "test ParsePath with an unclosed backtick", path: "`first.name", wantErr: true},
+		{name: "test ParsePath starting with an index", path: "[0]", wantErr: true},
+		{name: "test ParsePath with an empty path", path: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePath(tt.path)
+			if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePath() = %v, %v, want %v, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package sqlparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// FunctionCase is the function of a CASE WHEN operand
+const FunctionCase = "case"
+
+var caseRegexp = regexp.MustCompile(`(?i)\b(CASE|END)\b`)
+var caseKeywordRegexp = regexp.MustCompile(`(?i)\b(WHEN|THEN|ELSE)\b`)
+var caseStmtRegexp = regexp.MustCompile(`(?is)^CASE\s(.*)\sEND$`)
+var aliasRegexp = regexp.MustCompile(`(?i)\s(AS)\s`)
+
+// projectionMask is topLevel of a column which also leaves out everything from a CASE to its END
+func projectionMask(s string) []bool {
+	mask := topLevel(s)
+	depth, start := 0, 0
+	for _, m := range caseRegexp.FindAllStringSubmatchIndex(s, -1) {
+		if !mask[m[2]] {
+			continue
+		}
+		if strings.ToUpper(s[m[2]:m[3]]) == "CASE" {
+			if depth == 0 {
+				start = m[2]
+			}
+			depth++
+		} else if depth > 0 {
+			depth--
+			if depth == 0 {
+				for i := start; i < m[3]; i++ {
+					mask[i] = false
+				}
+			}
+		}
+	}
+	// a CASE without END runs to the end
+	if depth > 0 {
+		for i := start; i < len(s); i++ {
+			mask[i] = false
+		}
+	}
+	return mask
+}
+
+// lastIndexMasked returns the index of the last sep in s whose first byte is in mask, or -1
+func lastIndexMasked(s, sep string, mask []bool) int {
+	for i := len(s) - len(sep); i >= 0; i-- {
+		if mask[i] && strings.HasPrefix(s[i:], sep) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseColumn splits an item of SELECT into what it computes and its alias after AS, empty without AS,
+// an alias in backticks may contain spaces
+func ParseColumn(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	mask := projectionMask(s)
+	matches := aliasRegexp.FindAllStringSubmatchIndex(s, -1)
+	for idx := len(matches) - 1; idx >= 0; idx-- {
+		m := matches[idx]
+		if !mask[m[2]] {
+			continue
+		}
+		alias := strings.TrimSpace(s[m[1]:])
+		if elements, err := ParsePath(alias); err == nil && len(elements) == 1 && elements[0].Name != "" {
+			return strings.TrimSpace(s[:m[0]]), elements[0].Name
+		}
+		break
+	}
+	return s, ""
+}
+
+// ParseProjection parses a computed column of SELECT, a bare word is an attribute,
+// like price * qty, upper(name), substr(name, 1, 3) or CASE WHEN coins > 100 THEN 'rich' ELSE 'poor' END.
+// Operators need spaces around them, * and / bind tighter than + and -.
+func ParseProjection(value string) Operand {
+	value = strings.TrimSpace(value)
+	mask := projectionMask(value)
+	for _, ops := range [][]string{{" + ", " - "}, {" * ", " / "}} {
+		// the last operator is applied last, so a - b - c is (a - b) - c
+		at, op := -1, ""
+		for _, o := range ops {
+			if idx := lastIndexMasked(value, o, mask); idx > at {
+				at, op = idx, o
+			}
+		}
+		if at != -1 {
+			return Operand{
+				Function: strings.TrimSpace(op),
+				Args:     []Operand{ParseProjection(value[:at]), ParseProjection(value[at+len(op):])},
+			}
+		}
+	}
+	if strings.HasPrefix(value, "(") && closingParen(value, 0) == len(value)-1 {
+		return ParseProjection(value[1 : len(value)-1])
+	}
+	if m := caseStmtRegexp.FindStringSubmatch(value); m != nil {
+		return parseCase(m[1])
+	}
+	if m := functionRegexp.FindStringSubmatch(value); m != nil && closingParen(value, len(m[1])) == len(value)-1 {
+		operand := Operand{Function: strings.ToLower(m[1]), Args: []Operand{}}
+		if strings.TrimSpace(m[2]) != "" {
+			for _, arg := range SplitTopLevel(m[2], ",") {
+				operand.Args = append(operand.Args, ParseProjection(arg))
+			}
+		}
+		return operand
+	}
+	return leafOperand(value, true)
+}
+
+// parseCase parses what is between CASE and END, an invalid one gives a CASE without WHEN
+func parseCase(body string) Operand {
+	operand := Operand{Function: FunctionCase, Args: []Operand{}, When: [][]Condition{}}
+	mask := projectionMask(body)
+	keywords := [][]int{}
+	for _, m := range caseKeywordRegexp.FindAllStringSubmatchIndex(body, -1) {
+		if mask[m[2]] {
+			keywords = append(keywords, m)
+		}
+	}
+	invalid := Operand{Function: FunctionCase, Args: []Operand{}, When: [][]Condition{}}
+	for idx := 0; idx < len(keywords); idx++ {
+		keyword := strings.ToUpper(body[keywords[idx][2]:keywords[idx][3]])
+		end := len(body)
+		if idx+1 < len(keywords) {
+			end = keywords[idx+1][0]
+		}
+		text := strings.TrimSpace(body[keywords[idx][1]:end])
+		switch {
+		case keyword == "WHEN" && len(operand.When) == len(operand.Args) && idx+1 < len(keywords) && text != "":
+			conditions := []Condition{}
+			for _, c := range splitConditions(text) {
+				conditions = append(conditions, switchCondition(c, "AND"))
+			}
+			operand.When = append(operand.When, conditions)
+		case keyword == "THEN" && len(operand.When) == len(operand.Args)+1 && text != "":
+			operand.Args = append(operand.Args, ParseProjection(text))
+		case keyword == "ELSE" && len(operand.When) > 0 && len(operand.When) == len(operand.Args) && idx+1 == len(keywords) && text != "":
+			operand.Args = append(operand.Args, ParseProjection(text))
+		default:
+			return invalid
+		}
+	}
+	if len(operand.When) == 0 || len(operand.Args) < len(operand.When) {
+		return invalid
+	}
+	return operand
+}
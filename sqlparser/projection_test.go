@@ -0,0 +1,99 @@
+package sqlparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseColumn(t *testing.T) {
+	tests := []struct {
+		name           string
+		s              string
+		wantExpression string
+		wantAlias      string
+	}{
+		{name: "test ParseColumn without alias", s: "name", wantExpression: "name"},
+		{name: "test ParseColumn with alias", s: "price * qty as total", wantExpression: "price * qty", wantAlias: "total"},
+		{name: "test ParseColumn with an alias in backticks", s: "size(tags) AS `tag count`", wantExpression: "size(tags)", wantAlias: "tag count"},
+		{name: "test ParseColumn with AS in CASE", s: "CASE WHEN note = ' as x' THEN 1 END AS flag", wantExpression: "CASE WHEN note = ' as x' THEN 1 END", wantAlias: "flag"},
+		{name: "test ParseColumn with AS in quotes", s: "'a AS b'", wantExpression: "'a AS b'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, alias := ParseColumn(tt.s)
+			if expression != tt.wantExpression || alias != tt.wantAlias {
+				t.Errorf("ParseColumn() = %q, %q, want %q, %q", expression, alias, tt.wantExpression, tt.wantAlias)
+			}
+		})
+	}
+}
+
+func TestParseProjection(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  Operand
+	}{
+		{name: "test ParseProjection of a path", value: "address.city", want: Operand{Path: "address.city"}},
+		{name: "test ParseProjection of a string", value: "'n/a'", want: Operand{Value: "'n/a'"}},
+		{
+			name:  "test ParseProjection of arithmetic",
+			value: "price * qty - discount - 1",
+			want: Operand{Function: "-", Args: []Operand{
+				{Function: "-", Args: []Operand{
+					{Function: "*", Args: []Operand{{Path: "price"}, {Path: "qty"}}},
+					{Path: "discount"},
+				}},
+				{Value: "1"},
+			}},
+		},
+		{
+			name:  "test ParseProjection with parentheses",
+			value: "(price + 1) * qty",
+			want:  Operand{Function: "*", Args: []Operand{{Function: "+", Args: []Operand{{Path: "price"}, {Value: "1"}}}, {Path: "qty"}}},
+		},
+		{
+			name:  "test ParseProjection of functions",
+			value: "upper(substr(name, 1, 3))",
+			want: Operand{Function: "upper", Args: []Operand{
+				{Function: "substr", Args: []Operand{{Path: "name"}, {Value: "1"}, {Value: "3"}}},
+			}},
+		},
+		{
+			name:  "test ParseProjection of CASE",
+			value: "CASE WHEN coins > 100 AND tier = gold THEN 'rich' WHEN coins > 10 THEN 'ok' ELSE coins * 2 END",
+			want: Operand{
+				Function: FunctionCase,
+				When: [][]Condition{
+					{{Key: "coins", Operator: ">", Value: "100", NextLogicalOperator: "AND"}, {Key: "tier", Operator: "=", Value: "gold", NextLogicalOperator: "AND"}},
+					{{Key: "coins", Operator: ">", Value: "10", NextLogicalOperator: "AND"}},
+				},
+				Args: []Operand{{Value: "'rich'"}, {Value: "'ok'"}, {Function: "*", Args: []Operand{{Path: "coins"}, {Value: "2"}}}},
+			},
+		},
+		{
+			name:  "test ParseProjection of arithmetic on CASE",
+			value: "CASE WHEN a - 1 > 0 THEN a - 1 ELSE 0 END + 1",
+			want: Operand{Function: "+", Args: []Operand{
+				{
+					Function: FunctionCase,
+					When:     [][]Condition{{{Key: "a - 1", Operator: ">", Value: "0", NextLogicalOperator: "AND"}}},
+					Args:     []Operand{{Function: "-", Args: []Operand{{Path: "a"}, {Value: "1"}}}, {Value: "0"}},
+				},
+				{Value: "1"},
+			}},
+		},
+		{
+			name:  "test ParseProjection of CASE without THEN",
+			value: "CASE WHEN a > 1 ELSE 0 END",
+			want:  Operand{Function: FunctionCase, Args: []Operand{}, When: [][]Condition{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseProjection(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseProjection() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
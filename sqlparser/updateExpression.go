@@ -0,0 +1,211 @@
+package sqlparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Actions of an update expression
+const (
+	ActionSet    = "SET"
+	ActionRemove = "REMOVE"
+	ActionAdd    = "ADD"
+	ActionDelete = "DELETE"
+)
+
+var updateTableRegexp = regexp.MustCompile(`(?i)^\s*UPDATE\s+(\S+)`)
+var updateClauseRegexp = regexp.MustCompile(`(?i)\b(SET|REMOVE|ADD|DELETE)\s`)
+
+// END is the one added after every statement, an END in a value is part of it
+var updateEndRegexp = regexp.MustCompile(`(?i)\s(WHERE\b|RETURNING\b|RETRUNING\b|END\s*$)`)
+var functionRegexp = regexp.MustCompile(`(?s)^([A-Za-z_]+)\((.*)\)$`)
+var pathRegexp = regexp.MustCompile(`^[A-Za-z_][\w.\[\]#-]*$`)
+var mantissaRegexp = regexp.MustCompile(`(^|[^\w.])[0-9]*\.?[0-9]+$`)
+
+// Operand is the value of a SET action or a computed column of SELECT: an attribute path, a literal,
+// or a function like +, -, list_append and if_not_exists of other operands
+type Operand struct {
+	// Path is the attribute an operand refers to, like coins in coins + 100
+	Path string
+	// Value is a literal as it's written, like 100, 'vip', ['vip'] or <<'admin'>>
+	Value    string
+	Function string
+	Args     []Operand
+	// When holds the conditions of each WHEN of a CASE, Args holds their THEN values followed by the ELSE value if any
+	When [][]Condition
+}
+
+// topLevel marks the bytes of s which are not inside quotes, backticks, brackets, parentheses or <<sets>>
+func topLevel(s string) []bool {
+	mask := make([]bool, len(s))
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case strings.HasPrefix(s[i:], "<<"):
+			depth++
+			i++
+		case strings.HasPrefix(s[i:], ">>"):
+			depth--
+			i++
+		default:
+			mask[i] = depth == 0
+		}
+	}
+	return mask
+}
+
+// indexTopLevel returns the index of the first sep in s which is not inside quotes or brackets, or -1
+func indexTopLevel(s, sep string) int {
+	mask := topLevel(s)
+	for i := range s {
+		if mask[i] && strings.HasPrefix(s[i:], sep) {
+			return i
+		}
+	}
+	return -1
+}
+
+// SplitTopLevel splits s by sep, ignoring the ones inside quotes, brackets, parentheses or <<sets>>
+func SplitTopLevel(s, sep string) []string {
+	parts := []string{}
+	mask := topLevel(s)
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if mask[i] && strings.HasPrefix(s[i:], sep) {
+			parts = append(parts, s[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// findTopLevel returns the index of submatches of re whose first group is not inside quotes or brackets
+func findTopLevel(re *regexp.Regexp, s string) [][]int {
+	mask := topLevel(s)
+	matches := [][]int{}
+	for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+		if mask[m[2]] {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// splitUpdate returns the table name, the update expression between the table name and WHERE,
+// and the rest of an update statement
+func splitUpdate(updateSQL string) (string, string, string) {
+	tableMatch := updateTableRegexp.FindStringSubmatchIndex(updateSQL)
+	if tableMatch == nil {
+		return "", "", updateSQL
+	}
+	tableName := updateSQL[tableMatch[2]:tableMatch[3]]
+	body := updateSQL[tableMatch[1]:]
+	rest := ""
+	if ends := findTopLevel(updateEndRegexp, body); len(ends) > 0 {
+		body, rest = body[:ends[0][0]], body[ends[0][0]:]
+	}
+	return tableName, strings.TrimSpace(body), rest
+}
+
+// parseUpdateExpressions parses clauses like SET a=1, b=b+1 REMOVE c ADD d 1 DELETE e <<'x'>>
+func parseUpdateExpressions(updateStr string) []UpdateExpression {
+	updateExpressions := []UpdateExpression{}
+	clauses := findTopLevel(updateClauseRegexp, updateStr)
+	for idx, clause := range clauses {
+		action := strings.ToUpper(updateStr[clause[2]:clause[3]])
+		end := len(updateStr)
+		if idx+1 < len(clauses) {
+			end = clauses[idx+1][0]
+		}
+		for _, a := range SplitTopLevel(updateStr[clause[1]:end], ",") {
+			a = strings.TrimSpace(a)
+			u := UpdateExpression{Action: action}
+			switch action {
+			case ActionSet:
+				if eq := indexTopLevel(a, "="); eq != -1 {
+					u.Key, u.Value = strings.TrimSpace(a[:eq]), strings.TrimSpace(a[eq+1:])
+				} else {
+					u.Key = a
+				}
+			case ActionRemove:
+				u.Key = a
+			default:
+				// ADD and DELETE take an attribute and a value separated by space
+				if tokens := strings.Fields(a); len(tokens) > 0 {
+					u.Key = tokens[0]
+					u.Value = strings.TrimSpace(strings.TrimPrefix(a, tokens[0]))
+				}
+			}
+			updateExpressions = append(updateExpressions, u)
+		}
+	}
+	return updateExpressions
+}
+
+// ParseOperand parses the value of a SET action,
+// a bare word is a string unless it's an argument of a function, like coins in coins + 100
+func ParseOperand(value string) Operand {
+	return parseOperand(value, false)
+}
+
+func parseOperand(value string, inFunction bool) Operand {
+	value = strings.TrimSpace(value)
+	// DynamoDB allows a single + or - in a SET action, the left side may be a function like if_not_exists,
+	// a value with more of them is refused by the executor
+	if idx := arithmeticIndex(value); idx != -1 {
+		return Operand{
+			Function: value[idx : idx+1],
+			Args:     []Operand{parseOperand(value[:idx], true), parseOperand(value[idx+1:], true)},
+		}
+	}
+	if m := functionRegexp.FindStringSubmatch(value); m != nil {
+		operand := Operand{Function: strings.ToLower(m[1]), Args: []Operand{}}
+		for _, arg := range SplitTopLevel(m[2], ",") {
+			operand.Args = append(operand.Args, parseOperand(arg, true))
+		}
+		return operand
+	}
+	return leafOperand(value, inFunction)
+}
+
+// arithmeticIndex returns the index of the + or - of a SET value like coins + 100 or coins+100, -1 if there is none,
+// the sign of a number like -5 or 1e-5 is not one
+func arithmeticIndex(value string) int {
+	mask := topLevel(value)
+	for i := 1; i < len(value); i++ {
+		if !mask[i] || (value[i] != '+' && value[i] != '-') {
+			continue
+		}
+		before := strings.TrimSpace(value[:i])
+		if before == "" || strings.HasSuffix(before, "+") || strings.HasSuffix(before, "-") {
+			continue
+		}
+		if (value[i-1] == 'e' || value[i-1] == 'E') && mantissaRegexp.MatchString(value[:i-1]) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// leafOperand parses an attribute or a literal
+func leafOperand(value string, inFunction bool) Operand {
+	// a name in backticks is always an attribute
+	if strings.HasPrefix(value, "`") || (inFunction && pathRegexp.MatchString(value)) {
+		return Operand{Path: value}
+	}
+	return Operand{Value: value}
+}
@@ -0,0 +1,102 @@
+package sqlparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitTopLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		sep  string
+		want []string
+	}{
+		{
+			name: "test SplitTopLevel",
+			s:    "a=1, b=list_append(b, [1, 2]), c='x, y', d=<<'p', 'q'>>",
+			sep:  ",",
+			want: []string{"a=1", " b=list_append(b, [1, 2])", " c='x, y'", " d=<<'p', 'q'>>"},
+		},
+		{
+			name: "test SplitTopLevel without sep",
+			s:    "a",
+			sep:  ",",
+			want: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitTopLevel(tt.s, tt.sep); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitTopLevel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOperand(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  Operand
+	}{
+		{
+			name:  "test ParseOperand with string",
+			value: "gold",
+			want:  Operand{Value: "gold"},
+		},
+		{
+			name:  "test ParseOperand with +",
+			value: "coins + 100",
+			want:  Operand{Function: "+", Args: []Operand{{Path: "coins"}, {Value: "100"}}},
+		},
+		{
+			name:  "test ParseOperand with + without spaces",
+			value: "coins+100",
+			want:  Operand{Function: "+", Args: []Operand{{Path: "coins"}, {Value: "100"}}},
+		},
+		{
+			name:  "test ParseOperand with - and a negative number",
+			value: "coins - -1.5",
+			want:  Operand{Function: "-", Args: []Operand{{Path: "coins"}, {Value: "-1.5"}}},
+		},
+		{
+			name:  "test ParseOperand with exponent",
+			value: "1e-5",
+			want:  Operand{Value: "1e-5"},
+		},
+		{
+			name:  "test ParseOperand with quoted date",
+			value: "'2020-01-01'",
+			want:  Operand{Value: "'2020-01-01'"},
+		},
+		{
+			name:  "test ParseOperand with date",
+			value: "2020-01-01",
+			want: Operand{Function: "-", Args: []Operand{
+				{Value: "2020"},
+				{Function: "-", Args: []Operand{{Value: "01"}, {Value: "01"}}},
+			}},
+		},
+		{
+			name:  "test ParseOperand with if_not_exists and -",
+			value: "if_not_exists(coins, 0) - 5",
+			want: Operand{Function: "-", Args: []Operand{
+				{Function: "if_not_exists", Args: []Operand{{Path: "coins"}, {Value: "0"}}},
+				{Value: "5"},
+			}},
+		},
+		{
+			name:  "test ParseOperand with list_append",
+			value: "LIST_APPEND(tags, ['vip'])",
+			want:  Operand{Function: "list_append", Args: []Operand{{Path: "tags"}, {Value: "['vip']"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseOperand(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseOperand() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
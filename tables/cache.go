@@ -11,18 +11,28 @@ import (
 
 var mutex = sync.Mutex{}
 
+// generation increases every time the cache is cleared,
+// so describe requests sent before clearing won't fill the cache with tables of the old connection
+var generation = 0
+
 // TableInfoCache keys are table name, values are dynamodb DescribeTableOutput
 // TableInfoCache is a cache for table info, reduce request times
 var TableInfoCache = map[string]*dynamodb.DescribeTableOutput{}
 
 // GetTableDesc returns the table info and updates the table info cache
 func GetTableDesc(tableName *string) (*dynamodb.DescribeTableOutput, error) {
-	if TableInfoCache[*tableName] != nil {
-		return TableInfoCache[*tableName], nil
+	mutex.Lock()
+	cached := TableInfoCache[*tableName]
+	requestGeneration := generation
+	mutex.Unlock()
+	if cached != nil {
+		return cached, nil
 	} else {
 		if result, err := db.DynamoDB.DescribeTable(&dynamodb.DescribeTableInput{TableName: tableName}); err == nil {
 			mutex.Lock()
-			TableInfoCache[*tableName] = result
+			if requestGeneration == generation {
+				TableInfoCache[*tableName] = result
+			}
 			mutex.Unlock()
 			return result, nil
 		} else {
@@ -30,3 +40,11 @@ func GetTableDesc(tableName *string) (*dynamodb.DescribeTableOutput, error) {
 		}
 	}
 }
+
+// ClearCache drops all cached table info, used when switching connections
+func ClearCache() {
+	mutex.Lock()
+	TableInfoCache = map[string]*dynamodb.DescribeTableOutput{}
+	generation++
+	mutex.Unlock()
+}
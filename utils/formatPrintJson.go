@@ -9,12 +9,40 @@ import (
 	"github.com/tidwall/pretty"
 )
 
+// Output formats of query results
+const (
+	// OutputPretty prints colorized and indented JSON
+	OutputPretty = "pretty"
+	// OutputJSON prints indented JSON without color, handy when piping to other tools
+	OutputJSON = "json"
+	// OutputCompact prints JSON in a single line
+	OutputCompact = "compact"
+)
+
+// OutputFormats lists all supported output formats
+var OutputFormats = []string{OutputPretty, OutputJSON, OutputCompact}
+
+// OutputFormat is the output format used by FormatPrettyMap and FormatPrettyListOfMap
+var OutputFormat = OutputPretty
+
+func marshalJSON(v interface{}) ([]byte, error) {
+	switch OutputFormat {
+	case OutputJSON:
+		return json.MarshalIndent(v, "", "  ")
+	case OutputCompact:
+		return json.Marshal(v)
+	default:
+		formatedResult, err := json.MarshalIndent(v, "", "  ")
+		return pretty.Color(formatedResult, nil), err
+	}
+}
+
 // FormatPrettyMap format and colorize a map[string]*dynamodb.AttributeValue to JSON string
 func FormatPrettyMap(input map[string]*dynamodb.AttributeValue) string {
 	jsonResult := map[string]interface{}{}
 	if err := dynamodbattribute.UnmarshalMap(input, &jsonResult); err == nil {
-		if formatedResult, err := json.MarshalIndent(&jsonResult, "", "  "); err == nil {
-			return string(pretty.Color(formatedResult, nil))
+		if formatedResult, err := marshalJSON(&jsonResult); err == nil {
+			return string(formatedResult)
 		} else {
 			fmt.Println(err.Error())
 			return ""
@@ -29,11 +57,11 @@ func FormatPrettyMap(input map[string]*dynamodb.AttributeValue) string {
 func FormatPrettyListOfMap(input []map[string]*dynamodb.AttributeValue) string {
 	jsonResult := []map[string]interface{}{}
 	if err := dynamodbattribute.UnmarshalListOfMaps(input, &jsonResult); err == nil {
-		if formatedResult, err := json.MarshalIndent(&jsonResult, "", "  "); err == nil {
+		if formatedResult, err := marshalJSON(&jsonResult); err == nil {
 			if len(jsonResult) == 1 {
-				return fmt.Sprintf("%s\n%d item", string(pretty.Color(formatedResult, nil)), len(jsonResult))
+				return fmt.Sprintf("%s\n%d item", string(formatedResult), len(jsonResult))
 			} else {
-				return fmt.Sprintf("%s\n%d items", string(pretty.Color(formatedResult, nil)), len(jsonResult))
+				return fmt.Sprintf("%s\n%d items", string(formatedResult), len(jsonResult))
 			}
 		} else {
 			fmt.Println(err.Error())
@@ -44,3 +72,17 @@ func FormatPrettyListOfMap(input []map[string]*dynamodb.AttributeValue) string {
 		return ""
 	}
 }
+
+// FormatKey format a primary key to single line JSON string without color, e.g. {"user_id":9527}
+func FormatKey(input map[string]*dynamodb.AttributeValue) string {
+	jsonResult := map[string]interface{}{}
+	if err := dynamodbattribute.UnmarshalMap(input, &jsonResult); err == nil {
+		if formatedResult, err := json.Marshal(&jsonResult); err == nil {
+			return string(formatedResult)
+		} else {
+			return err.Error()
+		}
+	} else {
+		return err.Error()
+	}
+}